@@ -0,0 +1,353 @@
+// Copyright 2019 Max Godfrey
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 		http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package config loads the user's configuration of the file manager from a JSON file.
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+
+	"github.com/maxgodfrey2004/go-file-manager/explorer"
+	"github.com/maxgodfrey2004/go-file-manager/keymap"
+	"github.com/maxgodfrey2004/go-file-manager/lscolors"
+	"github.com/maxgodfrey2004/go-file-manager/textrenderer"
+	"github.com/nsf/termbox-go"
+)
+
+// FileName is the name of the configuration file within the configuration directory.
+const FileName = "config.json"
+
+// The layouts in which the explorer may be displayed.
+const (
+	// LayoutPreview displays the contents of the current directory beside a preview of the
+	// current selected file or directory.
+	LayoutPreview = "preview"
+
+	// LayoutDual displays two directories side by side, each in its own pane.
+	LayoutDual = "dual"
+
+	// LayoutColumns displays the ancestors of the current directory in columns to its left, and a
+	// preview of the current selected file or directory to its right.
+	LayoutColumns = "columns"
+)
+
+// layouts are the names of every layout, in the order in which they are described.
+var layouts = []string{LayoutPreview, LayoutDual, LayoutColumns}
+
+// Config holds the user's configuration of the file manager.
+type Config struct {
+	Options Options  `json:"options"`
+	Openers []Opener `json:"openers"`
+	Theme   Theme    `json:"theme"`
+	Keys    Keys     `json:"keys"`
+	Server  Server   `json:"server"`
+}
+
+// Options holds the configuration of the file manager's behaviour.
+type Options struct {
+	ListAll        bool   `json:"list_all"`        // Whether to list files beginning with a '.'.
+	StartDirectory string `json:"start_directory"` // The directory in which the explorer starts.
+	Editor         string `json:"editor"`          // The command with which files are viewed.
+	KeyPreset      string `json:"key_preset"`      // The preset of key bindings to begin with.
+	ScrollOff      int    `json:"scroll_off"`      // The lines kept between the caret and the edges.
+	Layout         string `json:"layout"`          // The layout in which the explorer starts.
+	ColumnWidths   []int  `json:"column_widths"`   // The relative widths of the columns layout.
+	AutoRefresh    bool   `json:"auto_refresh"`    // Whether to show changes made on disk.
+	FileTemplates  bool   `json:"file_templates"`  // Whether new files begin from templates.
+}
+
+// Opener is a rule describing the command with which files whose names match a pattern are
+// opened, in place of the editor.
+type Opener struct {
+	Pattern string   `json:"pattern"` // A pattern, as understood by filepath.Match.
+	Command []string `json:"command"` // The command's name, followed by its arguments.
+}
+
+// Server holds the configuration of the HTTP server with which the current directory is shared.
+type Server struct {
+	Address  string `json:"address"`  // The address on which to listen, such as "127.0.0.1:8000".
+	Username string `json:"username"` // The user name which must be given, if any.
+	Password string `json:"password"` // The password which must be given along with it.
+	Upload   bool   `json:"upload"`   // Whether files may be uploaded into the directory.
+}
+
+// Keys maps the name of each mode to the key bindings which the user has added to it. Each binding
+// maps a sequence of keys, in the notation understood by keymap.ParseSequence, to the name of an
+// action. Binding a sequence to an empty action removes its default binding.
+type Keys map[string]map[string]string
+
+// Theme names the built-in theme with which the explorer is drawn, along with descriptions of any
+// colours which replace those of the built-in theme, as understood by textrenderer.ParseColor.
+type Theme struct {
+	Name         string `json:"name"`
+	Directory    string `json:"directory"`
+	KeyFunctions string `json:"key_functions"`
+	Error        string `json:"error"`
+	Warning      string `json:"warning"`
+	Caret        string `json:"caret"`
+	Header       string `json:"header"`
+	PreviewBox   string `json:"preview_box"`
+	LSColors     bool   `json:"ls_colors"` // Whether entries are coloured according to $LS_COLORS.
+	Dircolors    string `json:"dircolors"` // A dircolors database used in place of $LS_COLORS.
+}
+
+// Default returns the configuration used when the user has not configured the file manager.
+func Default() Config {
+	return Config{
+		Options: Options{
+			ListAll:        false,
+			StartDirectory: "~",
+			Editor:         explorer.TextEditor,
+			KeyPreset:      "default",
+			ScrollOff:      2,
+			Layout:         LayoutPreview,
+			ColumnWidths:   []int{1, 3, 4},
+			AutoRefresh:    true,
+		},
+		Server: Server{
+			Address: "127.0.0.1:8000",
+		},
+		Theme: Theme{
+			Name: "default",
+		},
+	}
+}
+
+// Dir returns the directory holding the configuration: go-file-manager within $XDG_CONFIG_HOME,
+// or within ~/.config if $XDG_CONFIG_HOME is not set.
+func Dir() (string, error) {
+	if configHome := os.Getenv("XDG_CONFIG_HOME"); configHome != "" {
+		return filepath.Join(configHome, "go-file-manager"), nil
+	}
+	currentUser, err := user.Current()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(currentUser.HomeDir, ".config", "go-file-manager"), nil
+}
+
+// Load reads and validates the configuration file at path. Any setting which is not present in the
+// file takes its default value, and if the file does not exist the default configuration is
+// returned.
+func Load(path string) (Config, error) {
+	config := Default()
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return config, nil
+	} else if err != nil {
+		return config, err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&config); err != nil {
+		return config, fmt.Errorf("%s: %v", path, describeJSONError(data, err))
+	}
+	if err := config.Validate(); err != nil {
+		// Each problem found by Validate is described on its own line.
+		return config, errors.New(path + ": " + strings.Replace(err.Error(), "\n", "\n"+path+": ", -1))
+	}
+	return config, nil
+}
+
+// describeJSONError adds the line and column at which a JSON error occurred to its description.
+func describeJSONError(data []byte, err error) error {
+	var offset int64
+	switch err := err.(type) {
+	case *json.SyntaxError:
+		offset = err.Offset
+	case *json.UnmarshalTypeError:
+		offset = err.Offset
+		if err.Field != "" {
+			return fmt.Errorf("%s: %s must be %s, not %s", position(data, offset), err.Field, err.Type,
+				err.Value)
+		}
+	default:
+		return err
+	}
+	return fmt.Errorf("%s: %v", position(data, offset), err)
+}
+
+// position returns the line and column, both counted from 1, of a byte offset within data.
+func position(data []byte, offset int64) string {
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+	before := data[:offset]
+	line := bytes.Count(before, []byte("\n")) + 1
+	column := len(before) - bytes.LastIndexByte(before, '\n')
+	return fmt.Sprintf("line %d, column %d", line, column)
+}
+
+// Validate checks that every setting of the configuration is valid, returning an error describing
+// each one which is not.
+func (c *Config) Validate() error {
+	var problems []string
+	report := func(setting string, format string, args ...interface{}) {
+		problems = append(problems, setting+": "+fmt.Sprintf(format, args...))
+	}
+
+	if c.Options.StartDirectory == "" {
+		report("options.start_directory", "must not be empty")
+	}
+	if len(strings.Fields(c.Options.Editor)) == 0 {
+		report("options.editor", "must not be empty")
+	}
+	if c.Options.ScrollOff < 0 {
+		report("options.scroll_off", "must not be negative")
+	}
+	if !containsString(layouts, c.Options.Layout) {
+		report("options.layout", "unknown layout %q, the layouts are %s", c.Options.Layout,
+			strings.Join(layouts, ", "))
+	}
+	if len(c.Options.ColumnWidths) < 3 {
+		report("options.column_widths", "must have at least 3 columns")
+	}
+	for _, width := range c.Options.ColumnWidths {
+		if width <= 0 {
+			report("options.column_widths", "must all be positive")
+			break
+		}
+	}
+
+	for i, opener := range c.Openers {
+		setting := fmt.Sprintf("openers[%d]", i)
+		if opener.Pattern == "" {
+			report(setting+".pattern", "must not be empty")
+		} else if _, err := filepath.Match(opener.Pattern, ""); err != nil {
+			report(setting+".pattern", "%q is not a valid pattern", opener.Pattern)
+		}
+		if len(opener.Command) == 0 || opener.Command[0] == "" {
+			report(setting+".command", "must name a command")
+		}
+	}
+
+	for mode, bindings := range c.Keys {
+		for sequence := range bindings {
+			if _, err := keymap.ParseSequence(sequence); err != nil {
+				report(fmt.Sprintf("keys.%s[%q]", mode, sequence), "%v", err)
+			}
+		}
+	}
+
+	if _, _, err := net.SplitHostPort(c.Server.Address); err != nil {
+		report("server.address", "%q is not an address such as \":8000\"", c.Server.Address)
+	}
+	if c.Server.Password != "" && c.Server.Username == "" {
+		report("server.password", "must be given along with server.username")
+	}
+
+	if _, ok := textrenderer.NamedTheme(c.Theme.Name); !ok {
+		report("theme.name", "unknown theme %q, the themes are %s", c.Theme.Name,
+			strings.Join(textrenderer.ThemeNames(), ", "))
+	}
+	for _, color := range c.themeColors(&textrenderer.Theme{}) {
+		if _, err := textrenderer.ParseColor(color.description); err != nil {
+			report(color.setting, "%v", err)
+		}
+	}
+
+	if len(problems) > 0 {
+		return errors.New(strings.Join(problems, "\n"))
+	}
+	return nil
+}
+
+// containsString reports whether a string is one of a list of strings.
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// OpenerFor returns the command with which a file should be opened according to the first opener
+// whose pattern matches its name, or nil if no opener matches it.
+func (c *Config) OpenerFor(fileName string) []string {
+	for _, opener := range c.Openers {
+		if matched, _ := filepath.Match(opener.Pattern, fileName); matched {
+			return opener.Command
+		}
+	}
+	return nil
+}
+
+// themeColor is a colour of the theme which may be configured.
+type themeColor struct {
+	setting     string             // The name of the setting in the configuration file.
+	description string             // The configured description of the colour.
+	attr        *termbox.Attribute // The colour of a textrenderer theme which it replaces.
+}
+
+// themeColors returns each colour of the theme which may be configured, along with the colour of a
+// textrenderer theme which it replaces.
+func (c *Config) themeColors(theme *textrenderer.Theme) []themeColor {
+	return []themeColor{
+		{"theme.directory", c.Theme.Directory, &theme.Directory},
+		{"theme.key_functions", c.Theme.KeyFunctions, &theme.KeyFunctions},
+		{"theme.error", c.Theme.Error, &theme.Error},
+		{"theme.warning", c.Theme.Warning, &theme.Warning},
+		{"theme.caret", c.Theme.Caret, &theme.Caret},
+		{"theme.header", c.Theme.Header, &theme.Header},
+		{"theme.preview_box", c.Theme.PreviewBox, &theme.PreviewBox},
+	}
+}
+
+// TextrendererTheme converts the configured theme into the colours used by the textrenderer: the
+// named built-in theme, with any colours which have been configured replacing its own. The theme
+// must have been validated, and textrenderer.InitColors should have been called so that
+// hexadecimal colours are converted for the current output mode.
+func (c *Config) TextrendererTheme() textrenderer.Theme {
+	theme, _ := textrenderer.NamedTheme(c.Theme.Name)
+	for _, color := range c.themeColors(&theme) {
+		if strings.TrimSpace(color.description) != "" {
+			*color.attr, _ = textrenderer.ParseColor(color.description)
+		}
+	}
+	return theme
+}
+
+// LSColors returns the colours with which entries are coloured by their type and name: those of
+// the configured dircolors database if there is one, or otherwise those of $LS_COLORS. It returns
+// nil if entries should not be coloured in this way. Colours are converted for the output mode
+// when they are used, so this may be called before textrenderer.InitColors.
+func (c *Config) LSColors() (*lscolors.Colors, error) {
+	if !c.Theme.LSColors {
+		return nil, nil
+	}
+	if c.Theme.Dircolors == "" {
+		return lscolors.FromEnvironment()
+	}
+	file, err := os.Open(c.Theme.Dircolors)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	colors, err := lscolors.ParseDircolors(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", c.Theme.Dircolors, err)
+	}
+	return colors, nil
+}
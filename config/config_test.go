@@ -0,0 +1,181 @@
+// Copyright 2019 Max Godfrey
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 		http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/maxgodfrey2004/go-file-manager/textrenderer"
+	"github.com/nsf/termbox-go"
+)
+
+// writeConfig writes a configuration file to a temporary directory, returning its path and a
+// function which removes it.
+func writeConfig(t *testing.T, contents string) (string, func()) {
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, FileName)
+	if err := ioutil.WriteFile(path, []byte(contents), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path, func() { os.RemoveAll(dir) }
+}
+
+func TestLoadMissing(t *testing.T) {
+	config, err := Load(filepath.Join(os.TempDir(), "doesnotexist", FileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(config, Default()) {
+		t.Errorf("Load of a missing file = %+v, want the default configuration", config)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	path, cleanup := writeConfig(t, `{
+	"options": {"list_all": true, "editor": "vim -p"},
+	"openers": [{"pattern": "*.pdf", "command": ["zathura", "{}"]}],
+	"theme": {"directory": "bold #5f87af"},
+	"keys": {"normal": {"j": "down", "q": ""}}
+}`)
+	defer cleanup()
+
+	config, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !config.Options.ListAll || config.Options.Editor != "vim -p" {
+		t.Errorf("options = %+v", config.Options)
+	}
+	if config.Options.StartDirectory != "~" {
+		t.Errorf("start directory = %q, want the default of \"~\"", config.Options.StartDirectory)
+	}
+	if config.Theme.Name != "default" || config.Theme.LSColors {
+		t.Errorf("theme = %+v, want the default theme without LS_COLORS", config.Theme)
+	}
+	if want := (Keys{"normal": {"j": "down", "q": ""}}); !reflect.DeepEqual(config.Keys, want) {
+		t.Errorf("keys = %v, want %v", config.Keys, want)
+	}
+	command := config.OpenerFor("paper.pdf")
+	if !reflect.DeepEqual(command, []string{"zathura", "{}"}) {
+		t.Errorf("OpenerFor(\"paper.pdf\") = %q", command)
+	}
+	if command := config.OpenerFor("paper.txt"); command != nil {
+		t.Errorf("OpenerFor(\"paper.txt\") = %q, want nil", command)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		contents string
+		want     []string
+	}{
+		{"{\n  \"options\": {\"list_all\": tru}\n}", []string{"line 2, column"}},
+		{"{\"options\": {\"list_all\": \"yes\"}}", []string{"options.list_all must be bool, not string"}},
+		{"{\"colour\": {}}", []string{"unknown field \"colour\""}},
+		{"{\"options\": {\"scroll_off\": -1}}", []string{"options.scroll_off: must not be negative"}},
+		{
+			"{\"options\": {\"layout\": \"triple\"}}",
+			[]string{"options.layout: unknown layout \"triple\""},
+		},
+		{
+			"{\"options\": {\"column_widths\": [1, 0, 2]}}",
+			[]string{"options.column_widths: must all be positive"},
+		},
+		{
+			"{\"options\": {\"column_widths\": [1, 1]}}",
+			[]string{"options.column_widths: must have at least 3"},
+		},
+		{"{\"theme\": {\"name\": \"neon\"}}", []string{"theme.name: unknown theme \"neon\""}},
+		{"{\"server\": {\"address\": \"8000\"}}", []string{"server.address: \"8000\" is not an address"}},
+		{"{\"server\": {\"password\": \"pw\"}}", []string{"server.password: must be given along with"}},
+		{
+			`{"theme": {"directory": "bleu", "error": "red green"}, ` +
+				`"openers": [{"pattern": "[", "command": []}]}`,
+			[]string{
+				"theme.directory: unknown colour or attribute \"bleu\"",
+				"theme.error: \"red green\" names more than one colour",
+				"openers[0].pattern: \"[\" is not a valid pattern",
+				"openers[0].command: must name a command",
+			},
+		},
+		{
+			`{"keys": {"normal": {"<Bogus>": "quit", "<C-d": "down"}}}`,
+			[]string{
+				"keys.normal[\"<Bogus>\"]: <Bogus> is not a known key",
+				"keys.normal[\"<C-d\"]: \"<C-d\" is missing a closing '>'",
+			},
+		},
+	}
+	for _, test := range tests {
+		path, cleanup := writeConfig(t, test.contents)
+		_, err := Load(path)
+		cleanup()
+		if err == nil {
+			t.Errorf("Load(%q) did not return an error", test.contents)
+			continue
+		}
+		for _, want := range test.want {
+			if !strings.Contains(err.Error(), path+": ") || !strings.Contains(err.Error(), want) {
+				t.Errorf("Load(%q) returned %q, want it to mention the file and %q", test.contents, err, want)
+			}
+		}
+	}
+}
+
+func TestTextrendererTheme(t *testing.T) {
+	config := Default()
+	if theme := config.TextrendererTheme(); theme != textrenderer.DefaultTheme() {
+		t.Errorf("default configuration has theme %+v, want %+v", theme, textrenderer.DefaultTheme())
+	}
+
+	// Configured colours replace those of the named theme.
+	config.Theme.Name = "monochrome"
+	config.Theme.Caret = "bold red"
+	want, _ := textrenderer.NamedTheme("monochrome")
+	want.Caret = termbox.ColorRed | termbox.AttrBold
+	if theme := config.TextrendererTheme(); theme != want {
+		t.Errorf("configured theme = %+v, want %+v", theme, want)
+	}
+}
+
+func TestLSColors(t *testing.T) {
+	path, cleanup := writeConfig(t, "DIR 01;34\nEXEC 01;32 # executables\n.tar 01;31\n")
+	defer cleanup()
+
+	config := Default()
+	if colors, err := config.LSColors(); colors != nil || err != nil {
+		t.Errorf("LSColors() by default = %v, %v, want nil", colors, err)
+	}
+
+	config.Theme.LSColors = true
+	config.Theme.Dircolors = path
+	colors, err := config.LSColors()
+	if err != nil || colors == nil {
+		t.Fatalf("LSColors() = %v, %v", colors, err)
+	}
+
+	config.Theme.LSColors = false
+	if colors, err := config.LSColors(); colors != nil || err != nil {
+		t.Errorf("LSColors() with ls_colors disabled = %v, %v, want nil", colors, err)
+	}
+}
@@ -0,0 +1,194 @@
+// Copyright 2019 Max Godfrey
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 		http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/maxgodfrey2004/go-file-manager/config"
+	"github.com/maxgodfrey2004/go-file-manager/explorer"
+	"github.com/maxgodfrey2004/go-file-manager/textrenderer"
+)
+
+// templateDir is the directory within the configuration directory which holds the templates with
+// which new files begin.
+const templateDir = "templates"
+
+// promptCreate begins typing a command which creates a file or directory, whose name is typed after
+// it.
+func promptCreate(command string) {
+	openPrompt(CommandMode)
+	input = []rune(command + " ")
+	renderPrompt()
+}
+
+// promptLink begins typing a command which links to the current selected file or directory, with
+// the link defaulting to the same name in the directory of the other pane in dual-pane mode, or
+// otherwise in the current directory.
+func promptLink(command string) {
+	if screen.CurrentSelected() == "" {
+		return
+	}
+	link := strings.TrimSuffix(screen.CurrentSelected(), explorer.PathSep)
+	if screen.DualPane && otherPane.nav.Remote == nav.Remote {
+		link = otherPane.nav.GetPath() + filepath.Base(link)
+	}
+	openPrompt(CommandMode)
+	input = []rune(command + " " + link)
+	renderPrompt()
+}
+
+// createFile creates an empty file at a path which is either absolute or relative to the current
+// directory. If templates are enabled, the file instead holds the contents of the template which
+// fileTemplate finds for it, and it is left empty if the templates cannot be read.
+func createFile(command, p string) error {
+	if p == "" {
+		return fmt.Errorf("%s: no name was given", command)
+	}
+	var contents []byte
+	var template string
+	var templateErr error
+	if userConfig.Options.FileTemplates {
+		contents, template, templateErr = fileTemplate(p)
+	}
+	if err := nav.CreateFile(p, contents); err != nil {
+		return fmt.Errorf("%s %s: %v", command, p, err)
+	}
+	message := "Created " + p
+	if template != "" {
+		message += " from " + template
+	}
+	if err := showCreated(p, message); err != nil {
+		return err
+	}
+	if templateErr != nil {
+		showMessage(textrenderer.Warning, fmt.Sprintf("Created %s without a template: %v", p,
+			templateErr))
+	}
+	return nil
+}
+
+// makeDirectory creates a directory at a path which is either absolute or relative to the current
+// directory, along with any directories leading to it.
+func makeDirectory(command, p string) error {
+	if p == "" {
+		return fmt.Errorf("%s: no name was given", command)
+	}
+	if err := nav.MakeDirectory(p); err != nil {
+		return fmt.Errorf("%s %s: %v", command, p, err)
+	}
+	return showCreated(p, "Created "+p)
+}
+
+// linkEntry creates a symbolic link, or a hard link unless symbolic is set, to the current selected
+// file or directory at a path which is either absolute or relative to the current directory.
+func linkEntry(command, p string, symbolic bool) error {
+	if p == "" {
+		return fmt.Errorf("%s: no name was given", command)
+	}
+	name := screen.CurrentSelected()
+	if name == "" {
+		return fmt.Errorf("%s: %v", command, errNothingSelected)
+	}
+	var err error
+	if symbolic {
+		err = nav.Symlink(name, p)
+	} else {
+		err = nav.Link(name, p)
+	}
+	if err != nil {
+		return fmt.Errorf("%s %s: %v", command, name, err)
+	}
+	return showCreated(p, fmt.Sprintf("Linked %s to %s", p, strings.TrimSuffix(name,
+		explorer.PathSep)))
+}
+
+// showCreated lists the current directory again once something has been created at a path, moving
+// the caret to the entry through which it is reached if it lies within the current directory.
+func showCreated(p, message string) error {
+	if err := listDirectory(); err != nil {
+		return err
+	}
+	if entry, ok := createdEntry(p); ok && !selectName(entry) {
+		selectName(entry + explorer.PathSep)
+	}
+	if err := updateOtherPane(); err != nil {
+		return err
+	}
+	showMessage(textrenderer.Info, message)
+	return nil
+}
+
+// createdEntry returns the name of the entry of the current directory which is, or which leads to,
+// a path which is either absolute or relative to the current directory, reporting whether the path
+// lies within the current directory.
+func createdEntry(p string) (string, bool) {
+	if !filepath.IsAbs(p) {
+		p = nav.GetPath() + p
+	}
+	rel, err := filepath.Rel(nav.GetPath(), p)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+explorer.PathSep) {
+		return "", false
+	}
+	return strings.SplitN(rel, explorer.PathSep, 2)[0], true
+}
+
+// fileTemplate returns the contents of the template with which a new file at a path begins, along
+// with the name of the template. Templates are the files within the templates directory of the
+// configuration directory. A template with the same name as the new file is used if there is one,
+// such as Makefile, and otherwise the first template with the same extension, so that main.go is
+// used for every new file ending in .go. A file for which there is no template begins empty.
+func fileTemplate(p string) ([]byte, string, error) {
+	dir, err := config.Dir()
+	if err != nil {
+		return nil, "", err
+	}
+	dir = filepath.Join(dir, templateDir)
+	templates, err := ioutil.ReadDir(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, "", nil
+	} else if err != nil {
+		return nil, "", err
+	}
+
+	base := filepath.Base(p)
+	chosen := ""
+	for _, template := range templates {
+		if template.IsDir() {
+			continue
+		}
+		if template.Name() == base {
+			chosen = base
+			break
+		}
+		ext := filepath.Ext(base)
+		if chosen == "" && ext != "" && filepath.Ext(template.Name()) == ext {
+			chosen = template.Name()
+		}
+	}
+	if chosen == "" {
+		return nil, "", nil
+	}
+	data, err := ioutil.ReadFile(filepath.Join(dir, chosen))
+	if err != nil {
+		return nil, "", err
+	}
+	return data, chosen, nil
+}
@@ -16,14 +16,18 @@ package explorer
 
 import (
 	"errors"
-	"os"
+	"io/fs"
 	"os/user"
+	"path"
+	"path/filepath"
 	"strings"
+
+	"github.com/maxgodfrey2004/go-file-manager/filesystem"
 )
 
 // DirectoryExists determines whether or not a directory exists
-func DirectoryExists(filePath string) error {
-	fileType, err := os.Stat(filePath)
+func (e *Explorer) DirectoryExists(filePath string) error {
+	fileType, err := e.FS.Stat(e.name(filePath))
 	if err != nil {
 		return err
 	}
@@ -33,15 +37,40 @@ func DirectoryExists(filePath string) error {
 	return nil
 }
 
-// explorer represents a file explorer.
-type explorer struct {
+// Explorer represents a file explorer.
+type Explorer struct {
 	Path        string
 	CurrentUser *user.User
+	Editor      string                // The command with which View opens files.
+	FS          filesystem.FileSystem // The file system which is browsed.
+	Remote      string                // The URL of the host whose file system is browsed, if remote.
+	Sort        SortOrder             // The order in which the contents of directories are listed.
+}
+
+// name returns the name by which the explorer's file system knows the file at an absolute path.
+func (e *Explorer) name(filePath string) string {
+	name := path.Clean("/" + filepath.ToSlash(filePath))
+	if name == "/" {
+		return "."
+	}
+	return name[1:]
+}
+
+// Stat returns information about a file or directory adjacent to the directory which the explorer
+// is currently in.
+func (e *Explorer) Stat(fileName string) (fs.FileInfo, error) {
+	return e.FS.Stat(e.name(e.GetPath() + fileName))
+}
+
+// Lstat returns information about a file or directory adjacent to the directory which the explorer
+// is currently in, describing a symbolic link rather than the file which it refers to.
+func (e *Explorer) Lstat(fileName string) (fs.FileInfo, error) {
+	return e.FS.Lstat(e.name(e.GetPath() + fileName))
 }
 
 // MoveAbsolute will move the explorer to a specified absolute path.
 // The path may begin with either a '~' or a '/'.
-func (e *explorer) MoveAbsolute(path string) error {
+func (e *Explorer) MoveAbsolute(path string) error {
 	// Remove trailing forward slashes from the path
 	if path[len(path)-1] == PathSepChar {
 		path = path[:len(path)-1]
@@ -53,7 +82,7 @@ func (e *explorer) MoveAbsolute(path string) error {
 		path = e.CurrentUser.HomeDir + path[1:]
 	}
 
-	if err := DirectoryExists(path); err != nil {
+	if err := e.DirectoryExists(path); err != nil {
 		return err
 	}
 	e.Path = path
@@ -62,7 +91,7 @@ func (e *explorer) MoveAbsolute(path string) error {
 
 // MoveMultiple will move the explorer through a list of directories separated by '/' characters.
 // Each directory must be adjacent to the directory that the explorer is currently in.
-func (e *explorer) MoveMultiple(directories string) error {
+func (e *Explorer) MoveMultiple(directories string) error {
 	// Remove trailing forward slashes from directories
 	if directories[len(directories)-1] == PathSepChar {
 		directories = directories[:len(directories)-1]
@@ -80,7 +109,7 @@ func (e *explorer) MoveMultiple(directories string) error {
 
 // Move will move the explorer to a given directory relative to the current working directory.
 // The given directory must be adjacent to the directory that the explorer is currently in.
-func (e *explorer) MoveOne(nextDirectory string) error {
+func (e *Explorer) MoveOne(nextDirectory string) error {
 	// Remove trailing forward slashes from nextDirectory
 	if nextDirectory[len(nextDirectory)-1] == PathSepChar {
 		nextDirectory = nextDirectory[:len(nextDirectory)-1]
@@ -99,21 +128,35 @@ func (e *explorer) MoveOne(nextDirectory string) error {
 	}
 
 	nextPath := e.GetPath() + nextDirectory
-	if err := DirectoryExists(nextPath); err != nil {
+	if err := e.DirectoryExists(nextPath); err != nil {
 		return err
 	}
 	e.Path = nextPath
 	return nil
 }
 
+// Location returns the path of the directory which the explorer is in, with an os-specific path
+// separator appended to it, preceded by the URL of the remote host on which it lies.
+func (e *Explorer) Location() string {
+	return e.Remote + e.GetPath()
+}
+
+// Dir returns the name by which the explorer's file system knows the directory which the explorer
+// is in.
+func (e *Explorer) Dir() string {
+	return e.name(e.GetPath())
+}
+
 // Path returns the explorer attribute Path with an os-specific path separator appended to it.
-func (e *explorer) GetPath() string {
+func (e *Explorer) GetPath() string {
 	return e.Path + PathSep
 }
 
-// New returns an explorer type with all member values initialised to their defaults.
-func New() (e explorer) {
+// New returns an Explorer with all member values initialised to their defaults.
+func New() (e Explorer) {
 	e.Path = ""
 	e.CurrentUser, _ = user.Current()
+	e.Editor = TextEditor
+	e.FS = filesystem.OS()
 	return
 }
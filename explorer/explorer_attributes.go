@@ -0,0 +1,124 @@
+// Copyright 2019 Max Godfrey
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 		http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package explorer
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+
+	"github.com/maxgodfrey2004/go-file-manager/filesystem"
+)
+
+// Attributes describes a change to the permissions and ownership of files. Only the bits of a
+// file's mode which are turned on or off are changed, so that the same change may be made to files
+// whose modes differ, as with chmod u+x.
+type Attributes struct {
+	Set   fs.FileMode // The bits of each file's mode which are turned on.
+	Clear fs.FileMode // The bits of each file's mode which are turned off.
+	UID   int         // The user which is to own each file, or -1 to leave it as it is.
+	GID   int         // The group which is to own each file, or -1 to leave it as it is.
+}
+
+// Apply returns the permissions, along with the setuid, setgid and sticky bits, which a file with a
+// mode is given by the change.
+func (a Attributes) Apply(mode fs.FileMode) fs.FileMode {
+	return (mode&filesystem.ModeBits | a.Set&filesystem.ModeBits) &^ a.Clear
+}
+
+// ChangeAttributes changes the permissions and ownership of a file or directory within the
+// directory which the explorer is in, along with everything within a directory if recursive is
+// set. A symbolic link which is changed itself is followed, while symbolic links within a
+// directory are left alone. Every file is changed even if others could not be, and an error is
+// returned for each file which could not be.
+func (e *Explorer) ChangeAttributes(fileName string, attrs Attributes, recursive bool) []error {
+	name := strings.TrimSuffix(fileName, PathSep)
+	if !validName(name) {
+		return []error{fmt.Errorf("%s cannot be changed", fileName)}
+	}
+	src := e.name(e.GetPath() + name)
+	info, err := e.FS.Stat(src)
+	if err != nil {
+		return []error{err}
+	}
+	return e.changeAttributes(src, info, attrs, recursive && info.IsDir())
+}
+
+// changeAttributes changes the permissions and ownership of the file or directory with a name in
+// the explorer's file system, described by info, along with the contents of a directory if
+// recursive is set.
+func (e *Explorer) changeAttributes(name string, info fs.FileInfo, attrs Attributes,
+	recursive bool) []error {
+	var errs []error
+	chowned, err := e.chown(name, info, attrs)
+	if err != nil {
+		errs = append(errs, err)
+	}
+	current, mode := info.Mode()&filesystem.ModeBits, attrs.Apply(info.Mode())
+
+	if recursive {
+		// A directory gains permissions before its contents are changed, and loses them
+		// afterwards, so that its contents may be read and changed in between.
+		if opened := current | mode; opened != current {
+			if err := e.FS.Chmod(name, opened); err != nil {
+				errs = append(errs, err)
+			} else {
+				current = opened
+			}
+		}
+		entries, err := e.FS.ReadDir(name)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		for _, entry := range entries {
+			if entry.Type()&fs.ModeSymlink != 0 {
+				continue
+			}
+			info, err := entry.Info()
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			errs = append(errs, e.changeAttributes(path.Join(name, entry.Name()), info, attrs,
+				entry.IsDir())...)
+		}
+	}
+
+	// Changing the owner of a file may turn off its setuid and setgid bits, so its mode is always
+	// set again afterwards.
+	if mode != current || chowned {
+		if err := e.FS.Chmod(name, mode); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// chown changes the user and group which own a file, described by info, unless they are already
+// those which the change gives it. It reports whether the owner was changed.
+func (e *Explorer) chown(name string, info fs.FileInfo, attrs Attributes) (bool, error) {
+	if attrs.UID < 0 && attrs.GID < 0 {
+		return false, nil
+	}
+	if uid, gid, ok := filesystem.Owner(info); ok && (attrs.UID < 0 || attrs.UID == uid) &&
+		(attrs.GID < 0 || attrs.GID == gid) {
+		return false, nil
+	}
+	if err := e.FS.Chown(name, attrs.UID, attrs.GID); err != nil {
+		return false, err
+	}
+	return true, nil
+}
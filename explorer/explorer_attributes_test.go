@@ -0,0 +1,135 @@
+// Copyright 2019 Max Godfrey
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 		http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package explorer
+
+import (
+	"errors"
+	"io/fs"
+	"testing"
+
+	"github.com/maxgodfrey2004/go-file-manager/filesystem"
+)
+
+func TestApply(t *testing.T) {
+	attrs := Attributes{Set: fs.ModeSticky | 0100, Clear: 0022}
+	if mode := attrs.Apply(fs.ModeDir | 0777); mode != fs.ModeSticky|0755 {
+		t.Errorf("Apply(drwxrwxrwx) = %v, want %v", mode, fs.ModeSticky|0755)
+	}
+}
+
+// checkAttributes checks the mode and owner of a file within the tree made by makeTree.
+func checkAttributes(t *testing.T, e Explorer, name string, mode fs.FileMode, uid int) {
+	t.Helper()
+	info, err := e.FS.Lstat("tree/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := info.Mode() &^ fs.ModeType; got != mode {
+		t.Errorf("%s has mode %v, want %v", name, got, mode)
+	}
+	if got, _, _ := filesystem.Owner(info); got != uid {
+		t.Errorf("%s is owned by %d, want %d", name, got, uid)
+	}
+}
+
+func TestChangeAttributes(t *testing.T) {
+	e := makeTree(t, "dir"+PathSep+"a.txt", "dir"+PathSep+"sub"+PathSep+"b.txt", "c.txt")
+	if err := e.FS.Symlink("../c.txt", "tree/dir/link"); err != nil {
+		t.Fatal(err)
+	}
+
+	attrs := Attributes{Set: 0001, UID: -1, GID: -1}
+	if errs := e.ChangeAttributes("dir"+PathSep, attrs, false); len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	checkAttributes(t, e, "dir", 0755|0001, 0)
+	checkAttributes(t, e, "dir/a.txt", 0644, 0)
+
+	attrs = Attributes{Set: fs.ModeSetgid | 0020, Clear: 0005, UID: 1000, GID: -1}
+	if errs := e.ChangeAttributes("dir"+PathSep, attrs, true); len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	checkAttributes(t, e, "dir", fs.ModeSetgid|0770, 1000)
+	checkAttributes(t, e, "dir/sub", fs.ModeSetgid|0770, 1000)
+	checkAttributes(t, e, "dir/a.txt", fs.ModeSetgid|0660, 1000)
+	checkAttributes(t, e, "dir/sub/b.txt", fs.ModeSetgid|0660, 1000)
+	// The link within the directory is not followed.
+	checkAttributes(t, e, "c.txt", 0644, 0)
+
+	if errs := e.ChangeAttributes("..", attrs, false); len(errs) != 1 {
+		t.Errorf("ChangeAttributes of the parent directory returned %v", errs)
+	}
+	if errs := e.ChangeAttributes("missing", attrs, false); len(errs) != 1 ||
+		!errors.Is(errs[0], fs.ErrNotExist) {
+		t.Errorf("ChangeAttributes of a missing file returned %v", errs)
+	}
+}
+
+// clearingFS is a file system which turns off the setuid and setgid bits of a file when its owner
+// is changed, as Linux does.
+type clearingFS struct {
+	*filesystem.Memory
+}
+
+func (c clearingFS) Chown(name string, uid, gid int) error {
+	if err := c.Memory.Chown(name, uid, gid); err != nil {
+		return err
+	}
+	info, err := c.Memory.Stat(name)
+	if err != nil {
+		return err
+	}
+	return c.Memory.Chmod(name, info.Mode()&filesystem.ModeBits&^(fs.ModeSetuid|fs.ModeSetgid))
+}
+
+func TestChangeAttributesOwnerKeepsMode(t *testing.T) {
+	e := makeTree(t, "a.txt")
+	e.FS = clearingFS{Memory: e.FS.(*filesystem.Memory)}
+	if err := e.FS.Chmod("tree/a.txt", fs.ModeSetuid|0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if errs := e.ChangeAttributes("a.txt", Attributes{UID: 1000, GID: -1}, false); len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	checkAttributes(t, e, "a.txt", fs.ModeSetuid|0755, 1000)
+}
+
+// refusingFS is a file system on which the mode of a single file cannot be changed.
+type refusingFS struct {
+	*filesystem.Memory
+	refused string
+}
+
+func (r refusingFS) Chmod(name string, mode fs.FileMode) error {
+	if name == r.refused {
+		return &fs.PathError{Op: "chmod", Path: name, Err: fs.ErrPermission}
+	}
+	return r.Memory.Chmod(name, mode)
+}
+
+func TestChangeAttributesFailures(t *testing.T) {
+	e := makeTree(t, "dir"+PathSep+"a.txt", "dir"+PathSep+"b.txt", "dir"+PathSep+"c.txt")
+	e.FS = refusingFS{Memory: e.FS.(*filesystem.Memory), refused: "tree/dir/b.txt"}
+
+	errs := e.ChangeAttributes("dir", Attributes{Clear: 0044, UID: -1, GID: -1}, true)
+	if len(errs) != 1 || !errors.Is(errs[0], fs.ErrPermission) {
+		t.Errorf("ChangeAttributes returned %v, want a single permission error", errs)
+	}
+	checkAttributes(t, e, "dir/a.txt", 0600, 0)
+	checkAttributes(t, e, "dir/b.txt", 0644, 0)
+	checkAttributes(t, e, "dir/c.txt", 0600, 0)
+	checkAttributes(t, e, "dir", 0711, 0)
+}
@@ -0,0 +1,151 @@
+// Copyright 2019 Max Godfrey
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 		http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package explorer
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// CreateFile creates a file holding contents at a path which is either absolute or relative to the
+// directory which the explorer is in. The directory which is to hold the file must already exist,
+// and an existing file is never replaced. If the contents cannot all be written, the file is
+// removed.
+func (e *Explorer) CreateFile(filePath string, contents []byte) error {
+	name, err := e.newName(filePath)
+	if err != nil {
+		return err
+	}
+	w, err := e.FS.Create(name, 0644)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(contents)
+	if closeErr := w.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		e.FS.RemoveAll(name)
+	}
+	return err
+}
+
+// MakeDirectory creates a directory at a path which is either absolute or relative to the
+// directory which the explorer is in, along with any directories leading to it which do not exist,
+// as with mkdir -p. It is not an error if the directory already exists.
+func (e *Explorer) MakeDirectory(dirPath string) error {
+	name, err := e.newName(dirPath)
+	if err != nil {
+		return err
+	}
+	elements := strings.Split(name, "/")
+	for i := range elements {
+		dir := path.Join(elements[:i+1]...)
+		info, err := e.FS.Stat(dir)
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+			err = e.FS.Mkdir(dir, 0755)
+		case err == nil && !info.IsDir():
+			err = fmt.Errorf("%s is not a directory", dir)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Symlink creates a symbolic link to a file or directory within the directory which the explorer
+// is in, at a path which is either absolute or relative to the directory. A link made at an
+// absolute path refers to the absolute path of the file, while a link made at a relative path
+// refers to the file relative to the directory holding the link, so that the two may be moved
+// together.
+func (e *Explorer) Symlink(fileName, linkPath string) error {
+	src, err := e.linkSource(fileName)
+	if err != nil {
+		return err
+	}
+	if _, err := e.FS.Lstat(src); err != nil {
+		return err
+	}
+	linkPath = e.expandHome(linkPath)
+	name, err := e.newName(linkPath)
+	if err != nil {
+		return err
+	}
+	target := e.GetPath() + strings.TrimSuffix(fileName, PathSep)
+	if !filepath.IsAbs(linkPath) {
+		target, err = filepath.Rel(filepath.Dir(e.GetPath()+linkPath), target)
+		if err != nil {
+			return err
+		}
+	}
+	return e.FS.Symlink(target, name)
+}
+
+// Link creates a hard link to a file within the directory which the explorer is in, at a path
+// which is either absolute or relative to the directory.
+func (e *Explorer) Link(fileName, linkPath string) error {
+	src, err := e.linkSource(fileName)
+	if err != nil {
+		return err
+	}
+	name, err := e.newName(linkPath)
+	if err != nil {
+		return err
+	}
+	return e.FS.Link(src, name)
+}
+
+// linkSource returns the name in the explorer's file system of a file or directory within the
+// directory which the explorer is in, to which a link is made.
+func (e *Explorer) linkSource(fileName string) (string, error) {
+	name := strings.TrimSuffix(fileName, PathSep)
+	if !validName(name) {
+		return "", fmt.Errorf("%s cannot be linked to", fileName)
+	}
+	return e.name(e.GetPath() + name), nil
+}
+
+// newName returns the name in the explorer's file system of a file which is to be created at a
+// path which is either absolute, begins with '~', or is relative to the directory which the
+// explorer is in.
+func (e *Explorer) newName(filePath string) (string, error) {
+	if filePath == "" {
+		return "", errors.New("no name was given")
+	}
+	filePath = e.expandHome(filePath)
+	if !filepath.IsAbs(filePath) {
+		filePath = e.GetPath() + filePath
+	}
+	name := e.name(filePath)
+	if name == "." {
+		return "", fmt.Errorf("%s cannot be created", filePath)
+	}
+	return name, nil
+}
+
+// expandHome replaces a leading '~' in a path with the home directory of the current user. A file
+// whose name merely begins with '~', such as ~draft.txt, is left as it is.
+func (e *Explorer) expandHome(filePath string) string {
+	if e.CurrentUser != nil && (filePath == "~" || strings.HasPrefix(filePath, "~"+PathSep)) {
+		return e.CurrentUser.HomeDir + filePath[1:]
+	}
+	return filePath
+}
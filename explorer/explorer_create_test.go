@@ -0,0 +1,169 @@
+// Copyright 2019 Max Godfrey
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 		http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package explorer
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"syscall"
+	"testing"
+
+	"github.com/maxgodfrey2004/go-file-manager/filesystem"
+)
+
+func TestCreateFile(t *testing.T) {
+	e := makeTree(t, "a.txt", "dir"+PathSep)
+	if err := e.CreateFile("new.txt", []byte("new")); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.CreateFile("dir"+PathSep+"inner.txt", nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.CreateFile("/tree/abs.txt", nil); err != nil {
+		t.Fatal(err)
+	}
+	checkContents(t, e, map[string]string{"new.txt": "new", "dir/inner.txt": "", "abs.txt": ""})
+
+	if err := e.CreateFile("a.txt", nil); !errors.Is(err, fs.ErrExist) {
+		t.Errorf("CreateFile of an existing file returned %v, want %v", err, fs.ErrExist)
+	}
+	if err := e.CreateFile("missing"+PathSep+"b.txt", nil); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("CreateFile in a missing directory returned %v, want %v", err, fs.ErrNotExist)
+	}
+	if err := e.CreateFile("", nil); err == nil {
+		t.Error("CreateFile without a name did not fail")
+	}
+}
+
+// fullFS is a file system to which nothing can be written, as if its disk were full.
+type fullFS struct {
+	*filesystem.Memory
+}
+
+func (f fullFS) Create(name string, perm fs.FileMode) (io.WriteCloser, error) {
+	w, err := f.Memory.Create(name, perm)
+	if err != nil {
+		return nil, err
+	}
+	return fullWriter{w}, nil
+}
+
+type fullWriter struct {
+	io.WriteCloser
+}
+
+func (fullWriter) Write([]byte) (int, error) { return 0, syscall.ENOSPC }
+
+func TestCreateFileFails(t *testing.T) {
+	e := makeTree(t)
+	e.FS = fullFS{e.FS.(*filesystem.Memory)}
+	if err := e.CreateFile("new.txt", []byte("new")); !errors.Is(err, syscall.ENOSPC) {
+		t.Errorf("CreateFile on a full disk returned %v, want %v", err, syscall.ENOSPC)
+	}
+	if _, err := e.FS.Stat("tree/new.txt"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("a file which could not be written remains: %v", err)
+	}
+}
+
+func TestMakeDirectory(t *testing.T) {
+	e := makeTree(t, "a.txt", "dir"+PathSep)
+	if err := e.MakeDirectory("dir" + PathSep + "x" + PathSep + "y" + PathSep); err != nil {
+		t.Fatal(err)
+	}
+	if info, err := e.FS.Stat("tree/dir/x/y"); err != nil || !info.IsDir() {
+		t.Errorf("Stat of a nested directory = %v, %v", info, err)
+	}
+	if err := e.MakeDirectory("dir"); err != nil {
+		t.Errorf("MakeDirectory of an existing directory returned %v", err)
+	}
+	if err := e.MakeDirectory("a.txt" + PathSep + "sub"); err == nil {
+		t.Error("MakeDirectory made a directory within a file")
+	}
+	if err := e.MakeDirectory("/"); err == nil {
+		t.Error("MakeDirectory of the root directory did not fail")
+	}
+}
+
+func TestSymlink(t *testing.T) {
+	e := makeTree(t, "a.txt", "dir"+PathSep)
+	if err := e.Symlink("a.txt", "dir"+PathSep+"rel"); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Symlink("dir"+PathSep, "/tree/abs"); err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]string{
+		"tree/dir/rel": ".." + PathSep + "a.txt",
+		"tree/abs":     PathSep + "tree" + PathSep + "dir",
+	} {
+		if target, err := e.FS.ReadLink(name); err != nil || target != want {
+			t.Errorf("ReadLink(%q) = %q, %v, want %q", name, target, err, want)
+		}
+	}
+	checkContents(t, e, map[string]string{"dir/rel": "a.txt"})
+
+	if err := e.Symlink("missing", "link"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Symlink to a missing file returned %v, want %v", err, fs.ErrNotExist)
+	}
+	if err := e.Symlink("..", "link"); err == nil {
+		t.Error("Symlink linked to the parent directory")
+	}
+	if err := e.Symlink("a.txt", "dir"+PathSep+"rel"); !errors.Is(err, fs.ErrExist) {
+		t.Errorf("Symlink over an existing file returned %v, want %v", err, fs.ErrExist)
+	}
+}
+
+func TestLink(t *testing.T) {
+	e := makeTree(t, "a.txt", "dir"+PathSep)
+	if err := e.Link("a.txt", "dir"+PathSep+"hard"); err != nil {
+		t.Fatal(err)
+	}
+	checkContents(t, e, map[string]string{"dir/hard": "a.txt"})
+	if info, err := e.FS.Lstat("tree/dir/hard"); err != nil || info.Mode()&fs.ModeSymlink != 0 {
+		t.Errorf("Lstat of a hard link = %v, %v", info, err)
+	}
+	if err := e.Link("dir"+PathSep, "hard"); err == nil {
+		t.Error("Link linked to a directory")
+	}
+}
+
+func TestCreateInHomeDirectory(t *testing.T) {
+	e := makeTree(t, "a.txt")
+	if err := e.MakeDirectory("~" + PathSep + "dir"); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.CreateFile("~"+PathSep+"new.txt", nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Symlink("a.txt", "~"+PathSep+"link"); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.CreateFile("~draft.txt", nil); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"home/user/dir", "home/user/new.txt", "tree/~draft.txt"} {
+		if _, err := e.FS.Stat(name); err != nil {
+			t.Errorf("Stat(%q) = %v", name, err)
+		}
+	}
+	want := PathSep + "tree" + PathSep + "a.txt"
+	if target, err := e.FS.ReadLink("home/user/link"); err != nil || target != want {
+		t.Errorf("ReadLink of a link in the home directory = %q, %v, want %q", target, err, want)
+	}
+	if _, err := e.FS.Stat("tree/~"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("a directory named ~ was created: %v", err)
+	}
+}
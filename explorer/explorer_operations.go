@@ -16,96 +16,190 @@ package explorer
 
 import (
 	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io"
 	"os"
 	"os/exec"
+	"sort"
 	"strings"
+	"time"
 )
 
-// List returns the contents of the directory which the explorer is currently in. Given a bool,
-// if true it will include files and directories prefixed with a '.', otherwise it will not.
-func (e *explorer) List(listAll bool) ([]string, error) {
-	contents := []string{}
-	if e.Path != "" {
-		contents = append(contents, ".."+PathSep)
+// readChunkSize is the number of bytes read from a file at a time by ReadBytes.
+const readChunkSize = 64 * 1024
+
+// SortOrder is an order in which an explorer lists the contents of a directory.
+type SortOrder int
+
+// The orders in which the contents of a directory may be listed. Entries which are equal in size
+// or modification time are listed by name.
+const (
+	SortByName SortOrder = iota // By name, ignoring case.
+	SortBySize                  // By size, with the largest first.
+	SortByTime                  // By modification time, with the most recent first.
+)
+
+// String returns a description of a sort order.
+func (s SortOrder) String() string {
+	switch s {
+	case SortBySize:
+		return "size"
+	case SortByTime:
+		return "modification time"
 	}
+	return "name"
+}
 
-	f, err := os.Open(e.GetPath())
-	if err != nil {
-		return contents, err
+// DirectorySummary describes the immediate contents of a directory.
+type DirectorySummary struct {
+	Entries     int       // The number of files and directories.
+	Files       int       // The number of files.
+	Directories int       // The number of directories.
+	TotalSize   int64     // The combined size of all files, in bytes.
+	Newest      time.Time // The most recent modification time of any file or directory.
+}
+
+// contextReader is a reader which fails with its context's error once the context is cancelled.
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (r contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
 	}
-	fileInfo, err := f.Readdir(0)
-	f.Close()
+	return r.r.Read(p)
+}
+
+// readDir returns information about the contents of a directory, sorted in the explorer's sort
+// order. Given a bool, if true it will include files and directories prefixed with a '.',
+// otherwise it will not.
+func (e *Explorer) readDir(path string, listAll bool) ([]os.FileInfo, error) {
+	return e.readDirContext(context.Background(), path, listAll)
+}
+
+// readDirContext reads a directory in the same manner as readDir, but stops early, returning the
+// context's error, if the context is cancelled.
+func (e *Explorer) readDirContext(ctx context.Context, path string, listAll bool) ([]os.FileInfo,
+	error) {
+	entries, err := e.FS.ReadDir(e.name(path))
 	if err != nil {
-		return contents, err
+		return nil, err
 	}
 
-	if listAll {
-		for _, file := range fileInfo {
-			if file.IsDir() {
-				contents = append(contents, file.Name()+PathSep)
-			} else {
-				contents = append(contents, file.Name())
-			}
+	fileInfo := make([]os.FileInfo, 0, len(entries))
+	for _, entry := range entries {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if !listAll && entry.Name()[0] == '.' {
+			continue
 		}
-	} else {
-		for _, file := range fileInfo {
-			if file.Name()[0] != '.' {
-				if file.IsDir() {
-					contents = append(contents, file.Name()+PathSep)
-				} else {
-					contents = append(contents, file.Name())
-				}
-			}
+		info, err := entry.Info()
+		if err != nil {
+			// The file was removed after the directory was read.
+			continue
 		}
+		fileInfo = append(fileInfo, info)
 	}
-	return contents, nil
+	sortEntries(fileInfo, e.Sort)
+	return fileInfo, nil
+}
+
+// sortEntries sorts information about files and directories in a sort order.
+func sortEntries(fileInfo []os.FileInfo, order SortOrder) {
+	sort.Slice(fileInfo, func(i, j int) bool {
+		a, b := fileInfo[i], fileInfo[j]
+		switch {
+		case order == SortBySize && a.Size() != b.Size():
+			return a.Size() > b.Size()
+		case order == SortByTime && !a.ModTime().Equal(b.ModTime()):
+			return a.ModTime().After(b.ModTime())
+		}
+		x, y := strings.ToLower(a.Name()), strings.ToLower(b.Name())
+		if x == y {
+			return a.Name() < b.Name()
+		}
+		return x < y
+	})
+}
+
+// displayName returns the name of a file, or the name of a directory with an os-specific path
+// separator appended to it.
+func displayName(file os.FileInfo) string {
+	if file.IsDir() {
+		return file.Name() + PathSep
+	}
+	return file.Name()
+}
+
+// List returns the contents of the directory which the explorer is currently in, sorted in the
+// explorer's sort order. Given a bool, if true it will include files and directories prefixed with
+// a '.', otherwise it will not.
+func (e *Explorer) List(listAll bool) ([]string, error) {
+	contents, _, err := e.ListInfo(listAll)
+	return contents, err
+}
+
+// ListInfo returns the contents of the directory which the explorer is currently in as List does,
+// along with information about each of them as it was read from the directory. The information
+// about the parent directory, which is listed first outside of the root, is nil.
+func (e *Explorer) ListInfo(listAll bool) ([]string, []os.FileInfo, error) {
+	contents := []string{}
+	infos := []os.FileInfo{}
+	if e.Path != "" {
+		contents = append(contents, ".."+PathSep)
+		infos = append(infos, nil)
+	}
+
+	fileInfo, err := e.readDir(e.GetPath(), listAll)
+	if err != nil {
+		return contents, infos, err
+	}
+	for _, file := range fileInfo {
+		contents = append(contents, displayName(file))
+		infos = append(infos, file)
+	}
+	return contents, infos, nil
 }
 
 // ListDirectories returns all directories within the current directory in which the explorer is
 // located. Note that this function will return an array of directories exclusively. Files will
 // be ignored. Given a bool, if true it will include directories with a leading '.', otherwise it
 // will not.
-func (e *explorer) ListDirectories(listAll bool) ([]string, error) {
+func (e *Explorer) ListDirectories(listAll bool) ([]string, error) {
 	directories := []string{}
 	if e.Path != "" {
 		directories = append(directories, ".."+PathSep)
 	}
 
-	f, err := os.Open(e.GetPath())
-	if err != nil {
-		return directories, err
-	}
-	fileInfo, err := f.Readdir(0)
-	f.Close()
+	fileInfo, err := e.readDir(e.GetPath(), listAll)
 	if err != nil {
 		return directories, err
 	}
-
-	if listAll {
-		for _, file := range fileInfo {
-			if file.IsDir() {
-				directories = append(directories, file.Name()+PathSep)
-			}
-		}
-	} else {
-		for _, file := range fileInfo {
-			if file.IsDir() && file.Name()[0] != '.' {
-				directories = append(directories, file.Name()+PathSep)
-			}
+	for _, file := range fileInfo {
+		if file.IsDir() {
+			directories = append(directories, displayName(file))
 		}
 	}
 	return directories, nil
 }
 
-// ListN returns the first N contents of the current directory. If the current directory contains
-// fewer than N things, then the contents of the current directory will be returned.
-func (e *explorer) ListN(curSelected string, n int, listAll bool) ([]string, error) {
+// ListN returns the first N contents of the current directory, sorted in the same way as List. If
+// the current directory contains fewer than N things, then the contents of the current directory
+// will be returned. Listing stops early, returning the context's error, if the context is
+// cancelled.
+func (e *Explorer) ListN(ctx context.Context, curSelected string, n int, listAll bool) ([]string,
+	error) {
 	var contents []string
 	if n <= 0 {
 		return contents, nil
 	}
 
-	f, err := os.Open(e.GetPath() + curSelected)
+	fileInfo, err := e.readDirContext(ctx, e.GetPath()+curSelected, listAll)
 	if err != nil {
 		if strings.HasSuffix(err.Error(), "denied") {
 			contents = append(contents, "PERMISSION DENIED")
@@ -113,44 +207,18 @@ func (e *explorer) ListN(curSelected string, n int, listAll bool) ([]string, err
 		}
 		return contents, err
 	}
-	defer f.Close()
-	if listAll {
-		fileInfo, err := f.Readdir(n)
-		if err != nil {
-			if err.Error() == "EOF" {
-				contents = append(contents, "DIRECTORY IS EMPTY")
-				err = nil
-			}
-			return contents, err
-		}
-		for _, file := range fileInfo {
-			if file.IsDir() {
-				contents = append(contents, file.Name()+PathSep)
-			} else {
-				contents = append(contents, file.Name())
-			}
-		}
-		return contents, nil
-	}
-
-	fileInfo, err := f.Readdir(0)
-	if err != nil {
-		return contents, err
-	}
 	for _, file := range fileInfo {
 		if len(contents) == n {
 			break
 		}
-		if file.Name()[0] != '.' {
-			if file.IsDir() {
-				contents = append(contents, file.Name()+PathSep)
-			} else {
-				contents = append(contents, file.Name())
-			}
-		}
+		contents = append(contents, displayName(file))
 	}
 	if len(contents) == 0 {
-		contents = append(contents, "NO CONTENTS TO DISPLAY IN LIST MODE")
+		if listAll {
+			contents = append(contents, "DIRECTORY IS EMPTY")
+		} else {
+			contents = append(contents, "NO CONTENTS TO DISPLAY IN LIST MODE")
+		}
 	}
 
 	return contents, nil
@@ -159,55 +227,129 @@ func (e *explorer) ListN(curSelected string, n int, listAll bool) ([]string, err
 // ListFiles returns all files within the current directory in which the explorer is located. Note
 // that this function will return an array of files exclusively. No directory will be included.
 // Given a bool, if true it will include files prefixed with a '.', otherwise it will not.
-func (e *explorer) ListFiles(listAll bool) ([]string, error) {
+func (e *Explorer) ListFiles(listAll bool) ([]string, error) {
 	var files []string
-	f, err := os.Open(e.GetPath())
+	fileInfo, err := e.readDir(e.GetPath(), listAll)
 	if err != nil {
 		return files, err
 	}
-	fileInfo, err := f.Readdir(0)
-	f.Close()
-	if err != nil {
-		return files, err
+	for _, file := range fileInfo {
+		if !file.IsDir() {
+			files = append(files, file.Name())
+		}
 	}
+	return files, nil
+}
 
-	if listAll {
-		for _, file := range fileInfo {
-			if !file.IsDir() {
-				files = append(files, file.Name())
-			}
+// Summarise returns a summary of the contents of a directory adjacent to the directory which the
+// explorer is currently in. Given a bool, if true it will include files and directories prefixed
+// with a '.', otherwise it will not. Reading stops early, returning the context's error, if the
+// context is cancelled.
+func (e *Explorer) Summarise(ctx context.Context, directory string, listAll bool) (DirectorySummary,
+	error) {
+	var summary DirectorySummary
+	fileInfo, err := e.readDirContext(ctx, e.GetPath()+directory, listAll)
+	if err != nil {
+		return summary, err
+	}
+	for _, file := range fileInfo {
+		summary.Entries++
+		if file.IsDir() {
+			summary.Directories++
+		} else {
+			summary.Files++
+			summary.TotalSize += file.Size()
 		}
-	} else {
-		for _, file := range fileInfo {
-			if !file.IsDir() && file.Name()[0] != '.' {
-				files = append(files, file.Name())
-			}
+		if file.ModTime().After(summary.Newest) {
+			summary.Newest = file.ModTime()
 		}
 	}
-	return files, nil
+	return summary, nil
 }
 
-// ReadN reads the first N lines of a
-func (e *explorer) ReadN(fileName string, n int) ([]string, error) {
+// ReadN reads the first N lines of a file. Reading stops early, returning the context's error, if
+// the context is cancelled.
+func (e *Explorer) ReadN(ctx context.Context, fileName string, n int) ([]string, error) {
 	var contents []string
-	file, err := os.Open(e.GetPath() + fileName)
+	file, err := e.FS.Open(e.name(e.GetPath() + fileName))
 	if err != nil {
 		return contents, err
 	}
+	defer file.Close()
 
-	scanner := bufio.NewScanner(file)
+	scanner := bufio.NewScanner(contextReader{ctx: ctx, r: file})
 	scanner.Split(bufio.ScanLines)
 	for i := 0; i < n && scanner.Scan(); i++ {
 		contents = append(contents, scanner.Text())
 	}
-
-	file.Close()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	return contents, nil
 }
 
-// View will open an os-specific editor in which a file can be viewed (preferably for editing).
-func (e *explorer) View(fileName string) error {
-	cmd := exec.Command(TextEditor, e.GetPath()+fileName)
+// ReadBytes reads at most limit bytes from the start of a file. Reading stops early, returning the
+// context's error, if the context is cancelled.
+func (e *Explorer) ReadBytes(ctx context.Context, fileName string, limit int64) ([]byte, error) {
+	file, err := e.FS.Open(e.name(e.GetPath() + fileName))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var buf bytes.Buffer
+	reader := contextReader{ctx: ctx, r: io.LimitReader(file, limit)}
+	for {
+		if _, err := io.CopyN(&buf, reader, readChunkSize); err == io.EOF {
+			return buf.Bytes(), nil
+		} else if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// View will open the explorer's editor, which is an os-specific editor unless configured otherwise,
+// in which a file can be viewed (preferably for editing).
+func (e *Explorer) View(fileName string) error {
+	return e.OpenWith(strings.Fields(e.Editor), fileName)
+}
+
+// OpenWith runs a command, given as its name followed by its arguments, with which a file can be
+// viewed. Each argument which is exactly "{}" is replaced by the path of the file; if there is no
+// such argument, the path is appended to the arguments instead.
+func (e *Explorer) OpenWith(command []string, fileName string) error {
+	if e.Remote != "" {
+		return errors.New(fileName + " is on a remote host, and cannot be opened")
+	}
+	return openPath(command, e.GetPath()+fileName)
+}
+
+// Edit opens the explorer's editor in the same way as View, with a file on the local file system
+// which is given by its absolute path, rather than one within the directory which the explorer is
+// in.
+func (e *Explorer) Edit(path string) error {
+	return openPath(strings.Fields(e.Editor), path)
+}
+
+// openPath runs a command with which the file at a path can be viewed, as OpenWith does.
+func openPath(command []string, path string) error {
+	if len(command) == 0 {
+		return errors.New("no command with which to open " + path)
+	}
+	args := make([]string, 0, len(command))
+	replaced := false
+	for _, arg := range command[1:] {
+		if arg == "{}" {
+			arg = path
+			replaced = true
+		}
+		args = append(args, arg)
+	}
+	if !replaced {
+		args = append(args, path)
+	}
+
+	cmd := exec.Command(command[0], args...)
 	cmd.Stdin = os.Stdin
 	cmd.Stdout = os.Stdout
 	if err := cmd.Start(); err != nil {
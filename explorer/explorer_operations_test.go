@@ -15,50 +15,178 @@
 package explorer
 
 import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
+
+	"github.com/maxgodfrey2004/go-file-manager/filesystem"
 )
 
 func TestList(t *testing.T) {
-	e := New()
+	e := newTestExplorer(t, "home/user/b.txt", "home/user/A.txt", "home/user/c/d.txt",
+		"home/user/.hidden/")
 	if err := e.MoveAbsolute("~"); err != nil {
 		t.Fatal(err)
 	}
-	all, _ := e.List(false)
-	t.Log(all)
-	all, _ = e.List(true)
-	t.Log(all)
+	tests := []struct {
+		list    func(bool) ([]string, error)
+		listAll bool
+		want    []string
+	}{
+		{e.List, false, []string{".." + PathSep, "A.txt", "b.txt", "c" + PathSep}},
+		{e.List, true, []string{".." + PathSep, ".hidden" + PathSep, "A.txt", "b.txt", "c" + PathSep}},
+		{e.ListDirectories, false, []string{".." + PathSep, "c" + PathSep}},
+		{e.ListDirectories, true, []string{".." + PathSep, ".hidden" + PathSep, "c" + PathSep}},
+		{e.ListFiles, false, []string{"A.txt", "b.txt"}},
+	}
+	for i, test := range tests {
+		contents, err := test.list(test.listAll)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(contents, test.want) {
+			t.Errorf("test %d: listed %q, want %q", i, contents, test.want)
+		}
+	}
 
 	e.Path = "/doesnotexist"
-	all, err := e.List(false)
-	t.Log(all, err)
+	for i, test := range tests {
+		if _, err := test.list(false); err == nil {
+			t.Errorf("test %d: listing a directory which does not exist did not fail", i)
+		}
+	}
 }
 
-func TestListDirectories(t *testing.T) {
-	e := New()
-	if err := e.MoveAbsolute("~"); err != nil {
+// makeTree returns an explorer located in a directory of an in-memory file system containing
+// the given files, whose contents are their own names. Names ending in a path separator are
+// created as directories.
+func makeTree(t *testing.T, names ...string) Explorer {
+	e := newTestExplorer(t, "tree/")
+	m := e.FS.(*filesystem.Memory)
+	for _, name := range names {
+		path := "tree/" + filepath.ToSlash(name)
+		var err error
+		if strings.HasSuffix(path, "/") {
+			err = m.MkdirAll(path[:len(path)-1], 0755)
+		} else {
+			err = m.WriteFile(path, []byte(name), 0644)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := e.MoveAbsolute("/tree"); err != nil {
 		t.Fatal(err)
 	}
-	directories, _ := e.ListDirectories(false)
-	t.Log(directories)
-	directories, _ = e.ListDirectories(true)
-	t.Log(directories)
+	return e
+}
 
-	e.Path = "/doesnotexist"
-	all, err := e.ListDirectories(false)
-	t.Log(all, err)
+func TestListSorted(t *testing.T) {
+	e := makeTree(t, "b.txt", "A.txt", "c"+PathSep, ".hidden")
+
+	contents, err := e.List(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{".." + PathSep, "A.txt", "b.txt", "c" + PathSep}
+	if !reflect.DeepEqual(contents, want) {
+		t.Errorf("List(false) = %q, want %q", contents, want)
+	}
+
+	contents, err = e.ListN(context.Background(), ".", 2, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = []string{".hidden", "A.txt"}
+	if !reflect.DeepEqual(contents, want) {
+		t.Errorf("ListN(\".\", 2, true) = %q, want %q", contents, want)
+	}
 }
 
-func TestListFiles(t *testing.T) {
-	e := New()
-	if err := e.MoveAbsolute("~"); err != nil {
+func TestSummarise(t *testing.T) {
+	e := makeTree(t, "dir"+PathSep, "dir"+PathSep+"abc", "dir"+PathSep+"de",
+		"dir"+PathSep+"sub"+PathSep, "dir"+PathSep+".hidden")
+
+	summary, err := e.Summarise(context.Background(), "dir", false)
+	if err != nil {
 		t.Fatal(err)
 	}
-	files, _ := e.ListFiles(false)
-	t.Log(files)
-	files, _ = e.ListFiles(true)
-	t.Log(files)
+	if summary.Entries != 3 || summary.Files != 2 || summary.Directories != 1 {
+		t.Errorf("got %d entries, %d files and %d directories, want 3, 2 and 1",
+			summary.Entries, summary.Files, summary.Directories)
+	}
+	if summary.TotalSize != int64(len("dir"+PathSep+"abc")+len("dir"+PathSep+"de")) {
+		t.Errorf("got a total size of %d bytes", summary.TotalSize)
+	}
+	if summary.Newest.IsZero() {
+		t.Error("newest modification time was not found")
+	}
 
-	e.Path = "/doesnotexist"
-	all, err := e.ListFiles(false)
-	t.Log(all, err)
+	summary, err = e.Summarise(context.Background(), "dir", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.Entries != 4 {
+		t.Errorf("got %d entries when listing all, want 4", summary.Entries)
+	}
+}
+
+func TestReadBytes(t *testing.T) {
+	e := makeTree(t, "file.txt")
+
+	data, err := e.ReadBytes(context.Background(), "file.txt", 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "file" {
+		t.Errorf("ReadBytes returned %q, want %q", data, "file")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := e.ReadBytes(ctx, "file.txt", 4); err != context.Canceled {
+		t.Errorf("ReadBytes with a cancelled context returned %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestPreviewCancelled(t *testing.T) {
+	e := makeTree(t, "dir"+PathSep, "dir"+PathSep+"file.txt")
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := e.ListN(ctx, "dir", 10, true); err != context.Canceled {
+		t.Errorf("ListN with a cancelled context returned %v, want %v", err, context.Canceled)
+	}
+	if _, err := e.Summarise(ctx, "dir", true); err != context.Canceled {
+		t.Errorf("Summarise with a cancelled context returned %v, want %v", err, context.Canceled)
+	}
+	if _, err := e.ReadN(ctx, "dir"+PathSep+"file.txt", 10); err != context.Canceled {
+		t.Errorf("ReadN with a cancelled context returned %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestListSortOrder(t *testing.T) {
+	// Each file's contents are its name, and each is modified after those before it.
+	e := makeTree(t, "ccc.txt", "a.txt", "b.txt")
+
+	tests := []struct {
+		order SortOrder
+		want  []string
+	}{
+		{SortByName, []string{"a.txt", "b.txt", "ccc.txt"}},
+		{SortBySize, []string{"ccc.txt", "a.txt", "b.txt"}},
+		{SortByTime, []string{"b.txt", "a.txt", "ccc.txt"}},
+	}
+	for _, test := range tests {
+		e.Sort = test.order
+		files, err := e.ListFiles(false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(files, test.want) {
+			t.Errorf("sorted by %s, ListFiles(false) = %q, want %q", test.order, files, test.want)
+		}
+	}
 }
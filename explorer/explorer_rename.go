@@ -0,0 +1,173 @@
+// Copyright 2019 Max Godfrey
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 		http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package explorer
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Rename describes a file or directory within the directory which the explorer is in being given a
+// new name. Both names are relative to the directory, so a file may be moved into one of its
+// subdirectories by renaming it.
+type Rename struct {
+	Old string
+	New string
+}
+
+// RenameAll renames a number of files and directories within the directory which the explorer is
+// in at once, so that names may be swapped between files or passed around a cycle of them. Renames
+// which keep a file's name are ignored. Nothing is renamed unless every new name is valid, no two
+// files would be given the same name, and no file which is not being renamed would be replaced. If
+// a file cannot be renamed, those which have already been renamed are given back their names.
+func (e *Explorer) RenameAll(renames []Rename) error {
+	renames, err := e.checkRenames(renames)
+	if err != nil {
+		return err
+	}
+
+	// Each file is first given a temporary name, so that every new name is free by the time it
+	// is given to a file.
+	reserved := make(map[string]bool)
+	for _, r := range renames {
+		reserved[r.New] = true
+	}
+	var done []Rename
+	temps := make([]string, len(renames))
+	for i, r := range renames {
+		temp, err := e.tempName(r.Old, reserved)
+		if err == nil {
+			err = e.FS.Rename(r.Old, temp)
+		}
+		if err != nil {
+			return e.undoRenames(done, err)
+		}
+		reserved[temp] = true
+		temps[i] = temp
+		done = append(done, Rename{Old: r.Old, New: temp})
+	}
+	for i, r := range renames {
+		if err := e.FS.Rename(temps[i], r.New); err != nil {
+			return e.undoRenames(done, err)
+		}
+		done = append(done, Rename{Old: temps[i], New: r.New})
+	}
+	return nil
+}
+
+// checkRenames checks that a number of files may be renamed at once, returning the renames which
+// change a file's name, with both names converted into those of the explorer's file system.
+func (e *Explorer) checkRenames(renames []Rename) ([]Rename, error) {
+	var checked []Rename
+	// display maps the names of the file system back to those by which they were given.
+	display := make(map[string]string)
+	oldNames := make(map[string]bool)
+	newNames := make(map[string]bool)
+	for _, r := range renames {
+		oldName := strings.TrimSuffix(r.Old, PathSep)
+		newName := strings.TrimSuffix(r.New, PathSep)
+		if oldName == newName {
+			continue
+		}
+		if !validName(oldName) {
+			return nil, fmt.Errorf("%s cannot be renamed", r.Old)
+		}
+		if !validName(newName) {
+			return nil, fmt.Errorf("%s cannot be renamed to %q", oldName, newName)
+		}
+		from, to := e.name(e.GetPath()+oldName), e.name(e.GetPath()+newName)
+		if newNames[to] {
+			return nil, fmt.Errorf("more than one file would be renamed to %s", newName)
+		}
+		if _, err := e.FS.Lstat(from); err != nil {
+			return nil, err
+		}
+		display[from], display[to] = oldName, newName
+		oldNames[from], newNames[to] = true, true
+		checked = append(checked, Rename{Old: from, New: to})
+	}
+
+	for _, r := range checked {
+		for old := range oldNames {
+			switch {
+			case old != r.Old && within(r.Old, old):
+				return nil, fmt.Errorf("%s and %s cannot be renamed at once, as one lies within the other",
+					display[old], display[r.Old])
+			case old != r.New && within(r.New, old):
+				return nil, fmt.Errorf("%s cannot be renamed to %s, as %s is being renamed",
+					display[r.Old], display[r.New], display[old])
+			}
+		}
+		if oldNames[r.New] {
+			continue
+		}
+		if _, err := e.FS.Lstat(r.New); err == nil {
+			return nil, fmt.Errorf("%s cannot be renamed to %s, which already exists", display[r.Old],
+				display[r.New])
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+	}
+	return checked, nil
+}
+
+// validName reports whether a name relative to the directory which the explorer is in names a file
+// within it, rather than the directory itself or a file outside of it.
+func validName(name string) bool {
+	slashed := filepath.ToSlash(name)
+	return fs.ValidPath(slashed) && slashed != "."
+}
+
+// within reports whether the file system name of a file lies within a directory.
+func within(name, dir string) bool {
+	return dir == "." || strings.HasPrefix(name, dir+"/")
+}
+
+// tempName returns a name in the same directory as a file which it may be given while files are
+// being renamed, which belongs to no file and is not reserved.
+func (e *Explorer) tempName(name string, reserved map[string]bool) (string, error) {
+	for i := 0; ; i++ {
+		temp := path.Join(path.Dir(name), fmt.Sprintf(".%s.rename%d", path.Base(name), i))
+		if reserved[temp] {
+			continue
+		}
+		if _, err := e.FS.Lstat(temp); errors.Is(err, fs.ErrNotExist) {
+			return temp, nil
+		} else if err != nil {
+			return "", err
+		}
+	}
+}
+
+// undoRenames gives back the names of files which have been renamed, in the reverse order to which
+// they were renamed, after renaming a file failed with an error. The error is returned, along with
+// a description of any files which could not be given back their names.
+func (e *Explorer) undoRenames(done []Rename, err error) error {
+	var lost []string
+	for i := len(done) - 1; i >= 0; i-- {
+		if undoErr := e.FS.Rename(done[i].New, done[i].Old); undoErr != nil {
+			lost = append(lost, fmt.Sprintf("%s is left at %s", done[i].Old, done[i].New))
+		}
+	}
+	if len(lost) > 0 {
+		return fmt.Errorf("%v, and the files could not all be given back their names: %s", err,
+			strings.Join(lost, ", "))
+	}
+	return err
+}
@@ -0,0 +1,127 @@
+// Copyright 2019 Max Godfrey
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 		http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package explorer
+
+import (
+	"errors"
+	"io/fs"
+	"testing"
+
+	"github.com/maxgodfrey2004/go-file-manager/filesystem"
+)
+
+// checkContents checks that each file within the tree made by makeTree holds the contents given for
+// its name, which is the name which the file was created with.
+func checkContents(t *testing.T, e Explorer, want map[string]string) {
+	t.Helper()
+	for name, contents := range want {
+		data, err := fs.ReadFile(e.FS, "tree/"+name)
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+		} else if string(data) != contents {
+			t.Errorf("%s holds %q, want %q", name, data, contents)
+		}
+	}
+}
+
+func TestRenameAll(t *testing.T) {
+	e := makeTree(t, "a", "b", "c", "d", "dir"+PathSep)
+	renames := []Rename{
+		{Old: "a", New: "b"},
+		{Old: "b", New: "a"},
+		{Old: "c", New: "d"},
+		{Old: "d", New: "dir" + PathSep + "c"},
+		{Old: "dir" + PathSep, New: "dir" + PathSep},
+	}
+	if err := e.RenameAll(renames); err != nil {
+		t.Fatal(err)
+	}
+	checkContents(t, e, map[string]string{"a": "b", "b": "a", "d": "c", "dir/c": "d"})
+	if _, err := e.FS.Stat("tree/c"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("c still exists after being renamed: %v", err)
+	}
+
+	// The names are passed around a cycle.
+	renames = []Rename{{Old: "a", New: "b"}, {Old: "b", New: "d"}, {Old: "d", New: "a"}}
+	if err := e.RenameAll(renames); err != nil {
+		t.Fatal(err)
+	}
+	checkContents(t, e, map[string]string{"a": "c", "b": "b", "d": "a"})
+	entries, _ := e.FS.ReadDir("tree")
+	if len(entries) != 4 {
+		t.Errorf("tree holds %d entries after renaming, want 4", len(entries))
+	}
+}
+
+func TestRenameAllRefused(t *testing.T) {
+	tests := []struct {
+		description string
+		renames     []Rename
+	}{
+		{"duplicate names", []Rename{{Old: "a", New: "x"}, {Old: "b", New: "x"}}},
+		{"an existing file", []Rename{{Old: "a", New: "c"}}},
+		{"a missing file", []Rename{{Old: "missing", New: "x"}, {Old: "a", New: "y"}}},
+		{"an empty name", []Rename{{Old: "a", New: ""}}},
+		{"a name outside the directory", []Rename{{Old: "a", New: "../a"}}},
+		{"an absolute name", []Rename{{Old: "a", New: "/a"}}},
+		{"the parent directory", []Rename{{Old: "..", New: "x"}}},
+		{
+			"a file within a renamed directory",
+			[]Rename{{Old: "dir", New: "x"}, {Old: "dir/c", New: "dir/d"}},
+		},
+		{
+			"a file moved into a renamed directory",
+			[]Rename{{Old: "dir", New: "x"}, {Old: "a", New: "dir/a"}},
+		},
+	}
+	for _, test := range tests {
+		e := makeTree(t, "a", "b", "c", "dir"+PathSep, "dir"+PathSep+"c")
+		if err := e.RenameAll(test.renames); err == nil {
+			t.Errorf("%s: RenameAll(%v) did not return an error", test.description, test.renames)
+		}
+		checkContents(t, e, map[string]string{"a": "a", "b": "b", "c": "c", "dir/c": "dir/c"})
+	}
+}
+
+// failingFS is a file system on which a single rename fails, after a number of them have
+// succeeded.
+type failingFS struct {
+	*filesystem.Memory
+	renames int // The number of renames which succeed before one fails.
+}
+
+func (f *failingFS) Rename(oldname, newname string) error {
+	f.renames--
+	if f.renames == -1 {
+		return &fs.PathError{Op: "rename", Path: oldname, Err: fs.ErrPermission}
+	}
+	return f.Memory.Rename(oldname, newname)
+}
+
+func TestRenameAllRollback(t *testing.T) {
+	for renames := 0; renames < 4; renames++ {
+		e := makeTree(t, "a", "b")
+		fsys := &failingFS{Memory: e.FS.(*filesystem.Memory), renames: renames}
+		e.FS = fsys
+		err := e.RenameAll([]Rename{{Old: "a", New: "b"}, {Old: "b", New: "a"}})
+		if !errors.Is(err, fs.ErrPermission) {
+			t.Errorf("after %d renames: RenameAll returned %v, want a permission error", renames, err)
+		}
+		checkContents(t, e, map[string]string{"a": "a", "b": "b"})
+		if entries, _ := fsys.ReadDir("tree"); len(entries) != 2 {
+			t.Errorf("after %d renames: tree holds %d entries, want 2", renames, len(entries))
+		}
+	}
+}
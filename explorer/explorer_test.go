@@ -15,49 +15,117 @@
 package explorer
 
 import (
+	"os/user"
+	"path/filepath"
 	"testing"
+
+	"github.com/maxgodfrey2004/go-file-manager/filesystem"
 )
 
+// newTestExplorer returns an explorer at the root of an in-memory file system containing the given
+// files, whose contents are their own names. Names ending in a path separator are created as
+// directories. The current user's home directory is /home/user.
+func newTestExplorer(t *testing.T, names ...string) Explorer {
+	m := filesystem.NewMemory()
+	for _, name := range append([]string{"home/user/"}, names...) {
+		name = filepath.ToSlash(name)
+		var err error
+		if name[len(name)-1] == '/' {
+			err = m.MkdirAll(name[:len(name)-1], 0755)
+		} else {
+			err = m.WriteFile(name, []byte(name), 0644)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	e := New()
+	e.FS = m
+	e.CurrentUser = &user.User{HomeDir: "/home/user"}
+	return e
+}
+
 func TestDirectoryExists(t *testing.T) {
-	t.Log(DirectoryExists("/"))              // exists
-	t.Log(DirectoryExists("/doesnotexist/")) // does not exist
-	t.Log(DirectoryExists("/bin"))           // exists
-	t.Log(DirectoryExists("/bin/bash"))      // not a directory
+	e := newTestExplorer(t, "bin/bash")
+	tests := []struct {
+		path   string
+		exists bool
+	}{
+		{"/", true},
+		{"/doesnotexist/", false},
+		{"/bin", true},
+		{"/bin/bash", false},
+	}
+	for _, test := range tests {
+		if err := e.DirectoryExists(test.path); (err == nil) != test.exists {
+			t.Errorf("DirectoryExists(%q) = %v, want exists = %t", test.path, err, test.exists)
+		}
+	}
 }
 
 func TestMoveOne(t *testing.T) {
-	e := New()
-	t.Log("e.Path:", e.Path)
-	err1 := e.MoveOne("bin")
-	t.Log("e.Path:", e.Path, "error:", err1)
-	err2 := e.MoveOne("..")
-	t.Log("e.Path:", e.Path, "error:", err2)
-
-	err3 := e.MoveOne("bin/")
-	t.Log("e.Path:", e.Path, "error:", err3)
-	err4 := e.MoveOne(".")
-	t.Log("e.Path:", e.Path, "error:", err4)
-	err5 := e.MoveOne("doesnotexist")
-	t.Log("e.Path:", e.Path, "error:", err5)
+	e := newTestExplorer(t, "bin/bash")
+	tests := []struct {
+		dir     string
+		path    string
+		wantErr bool
+	}{
+		{"bin", "/bin", false},
+		{"..", "", false},
+		{"..", "", false},
+		{"bin/", "/bin", false},
+		{".", "/bin", false},
+		{"doesnotexist", "/bin", true},
+		{"bash", "/bin", true},
+	}
+	for _, test := range tests {
+		err := e.MoveOne(test.dir)
+		if e.Path != test.path || (err != nil) != test.wantErr {
+			t.Errorf("MoveOne(%q) moved to %q with error %v, want %q", test.dir, e.Path, err,
+				test.path)
+		}
+	}
 }
 
 func TestMoveMultiple(t *testing.T) {
-	e := New()
-	err1 := e.MoveMultiple("../../")
-	t.Log("e.Path:", e.Path, "error:", err1)
-
-	err2 := e.MoveMultiple("bin/../bin/../")
-	t.Log("e.Path:", e.Path, "error:", err2)
-	err3 := e.MoveMultiple("doesnotexist/doesnotexist")
-	t.Log("e.Path:", e.Path, "error:", err3)
+	e := newTestExplorer(t, "bin/", "usr/lib/")
+	tests := []struct {
+		dirs    string
+		path    string
+		wantErr bool
+	}{
+		{"usr/lib/", "/usr/lib", false},
+		{"../../", "", false},
+		{"bin/../bin/../", "", false},
+		{"usr/doesnotexist", "/usr", true},
+	}
+	for _, test := range tests {
+		err := e.MoveMultiple(test.dirs)
+		if e.Path != test.path || (err != nil) != test.wantErr {
+			t.Errorf("MoveMultiple(%q) moved to %q with error %v, want %q", test.dirs, e.Path,
+				err, test.path)
+		}
+	}
 }
 
 func TestMoveAbsolute(t *testing.T) {
-	e := New()
-	err1 := e.MoveAbsolute("/mnt/c/")
-	t.Log("e.Path:", e.Path, "error:", err1)
-	err2 := e.MoveAbsolute("~/bin")
-	t.Log("e.Path:", e.Path, "error:", err2)
-	err3 := e.MoveAbsolute("~")
-	t.Log("e.Path:", e.Path, "error:", err3)
+	e := newTestExplorer(t, "home/user/bin/", "mnt/")
+	tests := []struct {
+		path    string
+		want    string
+		wantErr bool
+	}{
+		{"/mnt/", "/mnt", false},
+		{"~/bin", "/home/user/bin", false},
+		{"~", "/home/user", false},
+		{"/mnt/c", "/home/user", true},
+	}
+	for _, test := range tests {
+		err := e.MoveAbsolute(test.path)
+		if e.Path != test.want || (err != nil) != test.wantErr {
+			t.Errorf("MoveAbsolute(%q) moved to %q with error %v, want %q", test.path, e.Path,
+				err, test.want)
+		}
+	}
 }
@@ -0,0 +1,200 @@
+// Copyright 2019 Max Godfrey
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 		http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package explorer
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"syscall"
+
+	"github.com/maxgodfrey2004/go-file-manager/filesystem"
+)
+
+// Copy copies a file or directory within the directory which the explorer is in into another
+// directory, keeping its name. Directories are copied along with all of their contents, and
+// symbolic links are copied as links rather than as what they point to. An existing file or
+// directory is never replaced.
+func (e *Explorer) Copy(fileName, destDir string) error {
+	return e.CopyTo(fileName, e.FS, destDir)
+}
+
+// CopyTo copies a file or directory in the same way as Copy, into a directory of another file
+// system.
+func (e *Explorer) CopyTo(fileName string, destFS filesystem.FileSystem, destDir string) error {
+	src, dest, err := e.transferPaths(fileName, destFS, destDir)
+	if err != nil {
+		return err
+	}
+	return e.copyAll(destFS, src, dest)
+}
+
+// Move moves a file or directory within the directory which the explorer is in into another
+// directory, keeping its name. If the directory is on another device, the file or directory is
+// copied and then removed. An existing file or directory is never replaced.
+func (e *Explorer) Move(fileName, destDir string) error {
+	return e.MoveTo(fileName, e.FS, destDir)
+}
+
+// MoveTo moves a file or directory in the same way as Move, into a directory of another file
+// system, to which it is copied before being removed.
+func (e *Explorer) MoveTo(fileName string, destFS filesystem.FileSystem, destDir string) error {
+	src, dest, err := e.transferPaths(fileName, destFS, destDir)
+	if err != nil {
+		return err
+	}
+	if sameFileSystem(e.FS, destFS) {
+		err = e.FS.Rename(src, dest)
+		if !errors.Is(err, syscall.EXDEV) {
+			return err
+		}
+	}
+	if err := e.copyAll(destFS, src, dest); err != nil {
+		return err
+	}
+	return e.FS.RemoveAll(src)
+}
+
+// sameFileSystem reports whether two file systems are the same. File systems whose types cannot be
+// compared are never the same.
+func sameFileSystem(a, b filesystem.FileSystem) bool {
+	t := reflect.TypeOf(a)
+	return t == reflect.TypeOf(b) && t.Comparable() && a == b
+}
+
+// Remove removes a file or directory within the directory which the explorer is in, along with all
+// of the contents of a directory. Symbolic links are removed rather than what they point to.
+func (e *Explorer) Remove(fileName string) error {
+	name := strings.TrimSuffix(fileName, PathSep)
+	if name == "" || name == "." || name == ".." {
+		return fmt.Errorf("%s cannot be removed", fileName)
+	}
+	src := e.name(e.GetPath() + name)
+	if _, err := e.FS.Lstat(src); err != nil {
+		return err
+	}
+	return e.FS.RemoveAll(src)
+}
+
+// transferPaths returns the names in the explorer's file system and in destFS from and to which a
+// file or directory is copied or moved, checking that the destination directory exists, that
+// nothing already exists at the destination, and that a directory is not being placed within
+// itself.
+func (e *Explorer) transferPaths(fileName string, destFS filesystem.FileSystem,
+	destDir string) (string, string, error) {
+	name := strings.TrimSuffix(fileName, PathSep)
+	if name == "" || name == "." || name == ".." {
+		return "", "", fmt.Errorf("%s cannot be copied or moved", fileName)
+	}
+	target := Explorer{FS: destFS}
+	if err := target.DirectoryExists(destDir); err != nil {
+		return "", "", err
+	}
+	src := e.name(e.GetPath() + name)
+	dest := path.Join(target.name(destDir), path.Base(src))
+	if _, err := destFS.Lstat(dest); err == nil {
+		return "", "", fmt.Errorf("%s already exists", filepath.Join(destDir, path.Base(src)))
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return "", "", err
+	}
+
+	if src == "." || sameFileSystem(e.FS, destFS) && strings.HasPrefix(dest+"/", src+"/") {
+		return "", "", fmt.Errorf("%s cannot be placed within itself", fileName)
+	}
+	return src, dest, nil
+}
+
+// copyAll copies the file, directory or symbolic link at src to dest in destFS, which must not
+// exist, removing whatever was copied if the copy cannot be completed.
+func (e *Explorer) copyAll(destFS filesystem.FileSystem, src, dest string) error {
+	err := e.copyPath(destFS, src, dest)
+	if err != nil {
+		destFS.RemoveAll(dest)
+	}
+	return err
+}
+
+// copyPath copies the file, directory or symbolic link at src to dest in destFS, which must not
+// exist.
+func (e *Explorer) copyPath(destFS filesystem.FileSystem, src, dest string) error {
+	info, err := e.FS.Lstat(src)
+	if err != nil {
+		return err
+	}
+	switch {
+	case info.Mode()&fs.ModeSymlink != 0:
+		target, err := e.FS.ReadLink(src)
+		if err != nil {
+			return err
+		}
+		return destFS.Symlink(target, dest)
+	case info.IsDir():
+		return e.copyDir(destFS, src, dest, info.Mode())
+	case info.Mode().IsRegular():
+		return e.copyFile(destFS, src, dest, info.Mode())
+	default:
+		// Named pipes, sockets and devices would be read from rather than copied.
+		return fmt.Errorf("%s cannot be copied, as it is not a regular file", path.Base(src))
+	}
+}
+
+// copyDir copies a directory and all of its contents.
+func (e *Explorer) copyDir(destFS filesystem.FileSystem, src, dest string, mode fs.FileMode) error {
+	contents, err := e.FS.ReadDir(src)
+	if err != nil {
+		return err
+	}
+	// The directory is made writable while its contents are copied into it.
+	if err := destFS.Mkdir(dest, mode.Perm()|0700); err != nil {
+		return err
+	}
+	for _, entry := range contents {
+		err := e.copyPath(destFS, path.Join(src, entry.Name()), path.Join(dest, entry.Name()))
+		if err != nil {
+			return err
+		}
+	}
+	// Directories on file systems without permissions, such as object stores, are left as they are.
+	if err := destFS.Chmod(dest, mode.Perm()); err != nil &&
+		!errors.Is(err, filesystem.ErrUnsupported) {
+		return err
+	}
+	return nil
+}
+
+// copyFile copies the contents and permissions of a regular file.
+func (e *Explorer) copyFile(destFS filesystem.FileSystem, src, dest string,
+	mode fs.FileMode) error {
+	in, err := e.FS.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := destFS.Create(dest, mode.Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,174 @@
+// Copyright 2019 Max Godfrey
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 		http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package explorer
+
+import (
+	"errors"
+	"io/fs"
+	"path"
+	"testing"
+
+	"github.com/maxgodfrey2004/go-file-manager/filesystem"
+)
+
+func TestCopy(t *testing.T) {
+	e := makeTree(t, "file.txt", "dir"+PathSep, "dir"+PathSep+"inner.txt", "dest"+PathSep)
+	dest := "/tree/dest"
+
+	if err := e.Copy("file.txt", dest); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Copy("dir"+PathSep, dest); err != nil {
+		t.Fatal(err)
+	}
+	data, err := fs.ReadFile(e.FS, "tree/dest/dir/inner.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "dir"+PathSep+"inner.txt" {
+		t.Errorf("copied file contains %q", data)
+	}
+	if _, err := e.FS.Stat("tree/file.txt"); err != nil {
+		t.Errorf("the original file is missing after copying it: %v", err)
+	}
+
+	if err := e.Copy("file.txt", dest); err == nil {
+		t.Error("Copy replaced a file which already exists")
+	}
+	if err := e.Copy("dir", "/tree/dir"); err == nil {
+		t.Error("Copy placed a directory within itself")
+	}
+	if err := e.Copy("..", dest); err == nil {
+		t.Error("Copy copied the parent directory")
+	}
+	if err := e.Copy("dir"+PathSep+"inner.txt", dest); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.FS.Stat("tree/dest/inner.txt"); err != nil {
+		t.Errorf("a file within a directory was not copied by its name alone: %v", err)
+	}
+}
+
+func TestMove(t *testing.T) {
+	e := makeTree(t, "file.txt", "dest"+PathSep)
+	dest := "/tree/dest"
+
+	if err := e.Move("file.txt", dest); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.FS.Stat("tree/dest/file.txt"); err != nil {
+		t.Errorf("the file was not moved: %v", err)
+	}
+	if _, err := e.FS.Stat("tree/file.txt"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("the file remains after moving it: %v", err)
+	}
+	if err := e.Move("missing.txt", dest); err == nil {
+		t.Error("Move moved a file which does not exist")
+	}
+}
+
+func TestTransferBetweenFileSystems(t *testing.T) {
+	e := makeTree(t, "file.txt", "dir"+PathSep, "dir"+PathSep+"inner.txt")
+	remote := filesystem.NewMemory()
+	if err := remote.MkdirAll("dest", 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := e.CopyTo("dir", remote, "/dest"); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := fs.ReadFile(remote, "dest/dir/inner.txt"); err != nil ||
+		string(data) != "dir"+PathSep+"inner.txt" {
+		t.Errorf("ReadFile of a copied file = %q, %v", data, err)
+	}
+	if err := e.CopyTo("dir", remote, "/dest/dir"); err != nil {
+		t.Errorf("a directory could not be copied into a directory of the same name on another "+
+			"file system: %v", err)
+	}
+
+	if err := e.MoveTo("file.txt", remote, "/dest"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := remote.Stat("dest/file.txt"); err != nil {
+		t.Errorf("the file was not moved: %v", err)
+	}
+	if _, err := e.FS.Stat("tree/file.txt"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("the file remains after moving it: %v", err)
+	}
+	if err := e.MoveTo("dir", remote, "/missing"); err == nil {
+		t.Error("MoveTo moved a directory into a directory which does not exist")
+	}
+}
+
+// pipeFS is a file system on which every file named "pipe" is a named pipe.
+type pipeFS struct {
+	*filesystem.Memory
+}
+
+func (p pipeFS) Lstat(name string) (fs.FileInfo, error) {
+	info, err := p.Memory.Lstat(name)
+	if err != nil || path.Base(name) != "pipe" {
+		return info, err
+	}
+	return pipeInfo{info}, nil
+}
+
+type pipeInfo struct {
+	fs.FileInfo
+}
+
+func (pipeInfo) Mode() fs.FileMode { return fs.ModeNamedPipe | 0644 }
+
+func TestTransferSpecialFiles(t *testing.T) {
+	e := makeTree(t, "pipe", "dir"+PathSep, "dir"+PathSep+"file.txt", "dir"+PathSep+"pipe")
+	e.FS = pipeFS{e.FS.(*filesystem.Memory)}
+	remote := filesystem.NewMemory()
+	if err := remote.MkdirAll("dest", 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := e.CopyTo("pipe", remote, "/dest"); err == nil {
+		t.Error("CopyTo copied a named pipe")
+	}
+	if err := e.MoveTo("dir", remote, "/dest"); err == nil {
+		t.Error("MoveTo moved a directory containing a named pipe to another file system")
+	}
+	if _, err := remote.Stat("dest/dir"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("a partial copy remains after a failed move: %v", err)
+	}
+	if _, err := e.FS.Stat("tree/dir/file.txt"); err != nil {
+		t.Errorf("a file is missing after failing to move its directory: %v", err)
+	}
+}
+
+func TestRemove(t *testing.T) {
+	e := makeTree(t, "file.txt", "dir"+PathSep, "dir"+PathSep+"inner.txt")
+
+	if err := e.Remove("dir" + PathSep); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.FS.Stat("tree/dir/inner.txt"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("the contents of a removed directory remain: %v", err)
+	}
+	if err := e.Remove("missing.txt"); err == nil {
+		t.Error("Remove removed a file which does not exist")
+	}
+	if err := e.Remove(".."); err == nil {
+		t.Error("Remove removed the parent directory")
+	}
+	if _, err := e.FS.Stat("tree/file.txt"); err != nil {
+		t.Errorf("a file which was not removed is missing: %v", err)
+	}
+}
@@ -0,0 +1,95 @@
+// Copyright 2019 Max Godfrey
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 		http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package explorer
+
+import (
+	"os"
+	"strings"
+)
+
+// Tree guides drawn before the entries of expanded directories.
+const (
+	guideBranch   = "├── " // Before an entry which is followed by another in the same directory.
+	guideLast     = "└── " // Before the last entry of a directory.
+	guideContinue = "│   " // Beneath an entry which is followed by another in the same directory.
+	guideBlank    = "    " // Beneath the last entry of a directory.
+)
+
+// ListTree returns the contents of the directory which the explorer is currently in as a tree,
+// along with the tree guide drawn before each of them and information about each of them. The
+// contents are listed as by ListInfo, and the contents of each directory whose path is in expanded
+// follow it, named relative to the current directory. Any error is that of listing the current
+// directory, as expanded directories which cannot be listed are shown as empty.
+func (e *Explorer) ListTree(listAll bool, expanded map[string]bool) ([]string, []string,
+	[]os.FileInfo, error) {
+	dirContents, dirInfos, err := e.ListInfo(listAll)
+	var t tree
+	for i, name := range dirContents {
+		t.append(name, "", dirInfos[i])
+		e.appendChildren(&t, name, "", listAll, expanded)
+	}
+	return t.names, t.guides, t.infos, err
+}
+
+// tree holds the entries of a tree as they are listed by ListTree.
+type tree struct {
+	names  []string
+	guides []string
+	infos  []os.FileInfo
+}
+
+// append adds an entry to the end of a tree.
+func (t *tree) append(name, guide string, info os.FileInfo) {
+	t.names = append(t.names, name)
+	t.guides = append(t.guides, guide)
+	t.infos = append(t.infos, info)
+}
+
+// appendChildren appends the contents of a directory within the current one to a tree, if the
+// directory has been expanded. The contents of any expanded directories within it are appended in
+// turn. The guides of each entry begin with prefix.
+func (e *Explorer) appendChildren(t *tree, dir, prefix string, listAll bool,
+	expanded map[string]bool) {
+	if !IsExpandable(dir) || !expanded[e.EntryPath(dir)] {
+		return
+	}
+	child := *e
+	child.Path = e.EntryPath(dir)
+	children, infos, err := child.ListInfo(listAll)
+	if err != nil || len(children) == 0 {
+		return
+	}
+	// The first entry of each directory other than the root is its parent.
+	children, infos = children[1:], infos[1:]
+	for i, name := range children {
+		guide, beneath := guideBranch, guideContinue
+		if i == len(children)-1 {
+			guide, beneath = guideLast, guideBlank
+		}
+		t.append(dir+name, prefix+guide, infos[i])
+		e.appendChildren(t, dir+name, prefix+beneath, listAll, expanded)
+	}
+}
+
+// IsExpandable reports whether an entry is a directory which may be expanded in a tree.
+func IsExpandable(name string) bool {
+	return name != "" && name[len(name)-1] == PathSepChar && name != ".."+PathSep
+}
+
+// EntryPath returns the path of an entry of the directory which the explorer is currently in,
+// which is how expanded directories are identified by ListTree.
+func (e *Explorer) EntryPath(name string) string {
+	return e.GetPath() + strings.TrimSuffix(name, PathSep)
+}
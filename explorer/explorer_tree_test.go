@@ -0,0 +1,118 @@
+// Copyright 2019 Max Godfrey
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 		http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package explorer
+
+import (
+	"io/fs"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/maxgodfrey2004/go-file-manager/filesystem"
+)
+
+// unreadableFS is a file system on which a single directory cannot be read.
+type unreadableFS struct {
+	*filesystem.Memory
+	unreadable string
+}
+
+func (u unreadableFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	if name == u.unreadable {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrPermission}
+	}
+	return u.Memory.ReadDir(name)
+}
+
+func TestListTree(t *testing.T) {
+	tests := []struct {
+		expanded   []string
+		unreadable string
+		names      []string
+		guides     []string
+	}{
+		{
+			// Nothing is expanded.
+			names:  []string{"../", "a/", "e.txt"},
+			guides: []string{"", "", ""},
+		},
+		{
+			expanded: []string{"a"},
+			names:    []string{"../", "a/", "a/b/", "a/d.txt", "a/z/", "e.txt"},
+			guides:   []string{"", "", "├── ", "├── ", "└── ", ""},
+		},
+		{
+			// Expanded directories are nested, with guides continuing beneath all but the last
+			// entry of each directory.
+			expanded: []string{"a", "a/b", "a/z"},
+			names: []string{"../", "a/", "a/b/", "a/b/c.txt", "a/d.txt", "a/z/", "a/z/y.txt",
+				"e.txt"},
+			guides: []string{"", "", "├── ", "│   └── ", "├── ", "└── ",
+				"    └── ", ""},
+		},
+		{
+			// The contents of a collapsed directory are hidden, even if they were expanded.
+			expanded: []string{"a/b", "a/z"},
+			names:    []string{"../", "a/", "e.txt"},
+			guides:   []string{"", "", ""},
+		},
+		{
+			// A directory which cannot be read is shown as empty.
+			expanded:   []string{"a", "a/b"},
+			unreadable: "tree/a/b",
+			names:      []string{"../", "a/", "a/b/", "a/d.txt", "a/z/", "e.txt"},
+			guides:     []string{"", "", "├── ", "├── ", "└── ", ""},
+		},
+	}
+	for _, test := range tests {
+		e := makeTree(t, "a"+PathSep+"b"+PathSep+"c.txt", "a"+PathSep+"d.txt",
+			"a"+PathSep+"z"+PathSep+"y.txt", "e.txt")
+		e.FS = unreadableFS{Memory: e.FS.(*filesystem.Memory), unreadable: test.unreadable}
+		expanded := make(map[string]bool)
+		for _, dir := range test.expanded {
+			expanded[e.EntryPath(filepath.FromSlash(dir))] = true
+		}
+
+		names, guides, infos, err := e.ListTree(false, expanded)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := range test.names {
+			test.names[i] = filepath.FromSlash(test.names[i])
+		}
+		if !reflect.DeepEqual(names, test.names) || !reflect.DeepEqual(guides, test.guides) {
+			t.Errorf("with %q expanded, ListTree returned %q and %q, want %q and %q",
+				test.expanded, names, guides, test.names, test.guides)
+		}
+		if len(infos) != len(names) {
+			t.Fatalf("with %q expanded, ListTree returned %d names and information about %d",
+				test.expanded, len(names), len(infos))
+		}
+		for i, info := range infos {
+			// There is no information about the parent directory.
+			got, want := "", ""
+			if info != nil {
+				got = info.Name()
+			}
+			if names[i] != ".."+PathSep {
+				want = filepath.Base(names[i])
+			}
+			if got != want {
+				t.Errorf("with %q expanded, ListTree returned information about %q as that of %q",
+					test.expanded, got, names[i])
+			}
+		}
+	}
+}
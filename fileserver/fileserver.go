@@ -0,0 +1,435 @@
+// Copyright 2019 Max Godfrey
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 		http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package fileserver shares a directory over HTTP, so that its files may be browsed and downloaded
+// from a web browser.
+package fileserver
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"io/fs"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/maxgodfrey2004/go-file-manager/filesystem"
+	"github.com/maxgodfrey2004/go-file-manager/preview"
+)
+
+// uploadField is the name of the form field holding the files which are uploaded to a directory.
+const uploadField = "file"
+
+// maxLinks is the number of symbolic links which are followed in finding a file, beyond which the
+// links are taken to form a loop.
+const maxLinks = 40
+
+// Options holds the configuration of a Handler.
+type Options struct {
+	Username string // The user name which must be given, or "" if none is required.
+	Password string // The password which must be given along with the user name.
+	Upload   bool   // Whether files may be uploaded into the shared directories.
+	Hidden   bool   // Whether files whose names begin with a '.' are listed and served.
+
+	// Log is called with a line describing each request once it has been answered, if it is not
+	// nil. It may be called from several goroutines at once.
+	Log func(line string)
+}
+
+// Handler shares a directory of a file system over HTTP. Directories are listed, and files are
+// served along with their content type, supporting requests for ranges of their contents. Symbolic
+// links are followed as long as they lead to something within the shared directory. Files are only
+// ever uploaded if they do not already exist.
+type Handler struct {
+	fsys    filesystem.FileSystem
+	dir     string
+	options Options
+}
+
+// New returns a Handler which shares the directory with a name within a file system.
+func New(fsys filesystem.FileSystem, dir string, options Options) *Handler {
+	return &Handler{fsys: fsys, dir: dir, options: options}
+}
+
+// statusRecorder records the status with which a request was answered.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Write(p []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	return r.ResponseWriter.Write(p)
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	recorder := &statusRecorder{ResponseWriter: w}
+	h.serve(recorder, r)
+	if h.options.Log != nil {
+		host, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			host = r.RemoteAddr
+		}
+		if recorder.status == 0 {
+			recorder.status = http.StatusOK
+		}
+		h.options.Log(fmt.Sprintf("%s %s %s %d", host, r.Method, r.URL.Path, recorder.status))
+	}
+}
+
+// serve answers a request once the user has been authenticated.
+func (h *Handler) serve(w http.ResponseWriter, r *http.Request) {
+	if !h.authenticated(r) {
+		w.Header().Set("WWW-Authenticate", `Basic realm="go-file-manager", charset="UTF-8"`)
+		http.Error(w, "401 unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	urlPath := path.Clean("/" + r.URL.Path)
+	if !h.options.Hidden && strings.Contains(urlPath, "/.") {
+		http.NotFound(w, r)
+		return
+	}
+	name, err := h.resolve(path.Join(h.dir, urlPath[1:]))
+	if err != nil {
+		serveError(w, err)
+		return
+	}
+	info, err := h.fsys.Stat(name)
+	if err != nil {
+		serveError(w, err)
+		return
+	}
+
+	// Directories are always requested with a trailing slash, and files without one, so that
+	// the links of a listing are relative to the directory.
+	canonical := urlPath
+	if info.IsDir() && canonical != "/" {
+		canonical += "/"
+	}
+	if r.URL.Path != canonical {
+		redirect(w, r, canonical)
+		return
+	}
+
+	switch {
+	case r.Method == http.MethodPost && h.options.Upload && info.IsDir():
+		h.upload(w, r, name, canonical)
+	case r.Method != http.MethodGet && r.Method != http.MethodHead:
+		allowed := "GET, HEAD"
+		if h.options.Upload && info.IsDir() {
+			allowed += ", POST"
+		}
+		w.Header().Set("Allow", allowed)
+		http.Error(w, "405 method not allowed", http.StatusMethodNotAllowed)
+	case info.IsDir():
+		h.list(w, r, name, canonical)
+	default:
+		h.serveFile(w, r, name, info)
+	}
+}
+
+// resolve returns the name of a file within the shared directory once every symbolic link along
+// the way to it has been followed. A file which is only reached through a link leading outside of
+// the shared directory, or which is hidden and not served, cannot be resolved.
+func (h *Handler) resolve(name string) (string, error) {
+	root := path.Clean(h.dir)
+	resolved := root
+	pending := strings.Split(relative(root, name), "/")
+	for links := 0; len(pending) > 0; {
+		elem := pending[0]
+		pending = pending[1:]
+		if elem == "" || elem == "." {
+			continue
+		}
+		next := path.Join(resolved, elem)
+		if elem == ".." {
+			resolved = next
+			continue
+		}
+		info, err := h.fsys.Lstat(next)
+		if err != nil {
+			return "", err
+		}
+		if info.Mode()&fs.ModeSymlink == 0 {
+			resolved = next
+			continue
+		}
+
+		if links++; links > maxLinks {
+			return "", &fs.PathError{Op: "open", Path: name, Err: errors.New("too many links")}
+		}
+		target, err := h.fsys.ReadLink(next)
+		if err != nil {
+			return "", err
+		}
+		target = filepath.ToSlash(target)
+		if strings.HasPrefix(target, "/") {
+			// Names within the file system are relative to its root.
+			resolved, target = ".", target[1:]
+		}
+		pending = append(strings.Split(target, "/"), pending...)
+	}
+
+	rel := relative(root, resolved)
+	if rel == ".." || strings.HasPrefix(rel, "../") || strings.HasPrefix(rel, "/") {
+		return "", &fs.PathError{Op: "open", Path: name, Err: fs.ErrPermission}
+	}
+	if !h.options.Hidden && (strings.HasPrefix(rel, ".") && rel != "." ||
+		strings.Contains(rel, "/.")) {
+		return "", &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	return resolved, nil
+}
+
+// relative returns the name of a file relative to a directory, both named within a file system,
+// or a name beginning with ".." or "/" if the file does not lie within the directory.
+func relative(dir, name string) string {
+	switch {
+	case dir == ".":
+		return name
+	case name == dir:
+		return "."
+	case strings.HasPrefix(name, dir+"/"):
+		return name[len(dir)+1:]
+	}
+	return "/" + name
+}
+
+// authenticated reports whether a request gives the user name and password which are required, if
+// there are any.
+func (h *Handler) authenticated(r *http.Request) bool {
+	if h.options.Username == "" && h.options.Password == "" {
+		return true
+	}
+	username, password, ok := r.BasicAuth()
+	// Both are compared, so that the time taken does not reveal which of them was wrong.
+	usernameOK := subtle.ConstantTimeCompare([]byte(username), []byte(h.options.Username)) == 1
+	passwordOK := subtle.ConstantTimeCompare([]byte(password), []byte(h.options.Password)) == 1
+	return ok && usernameOK && passwordOK
+}
+
+// redirect redirects a request to another path, keeping its query.
+func redirect(w http.ResponseWriter, r *http.Request, urlPath string) {
+	location := (&url.URL{Path: urlPath}).String()
+	if r.URL.RawQuery != "" {
+		location += "?" + r.URL.RawQuery
+	}
+	w.Header().Set("Location", location)
+	w.WriteHeader(http.StatusMovedPermanently)
+}
+
+// serveError answers a request with the status which best describes an error. Errors which do not
+// describe a missing or forbidden file are not described, as they may reveal more than the shared
+// directory.
+func serveError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		http.Error(w, "404 page not found", http.StatusNotFound)
+	case errors.Is(err, fs.ErrPermission):
+		http.Error(w, "403 forbidden", http.StatusForbidden)
+	case errors.Is(err, fs.ErrExist):
+		http.Error(w, "409 file already exists", http.StatusConflict)
+	default:
+		http.Error(w, "500 internal server error", http.StatusInternalServerError)
+	}
+}
+
+// serveFile answers a request for the contents of a file. Ranges of the contents may be requested
+// if the file system's files support seeking.
+func (h *Handler) serveFile(w http.ResponseWriter, r *http.Request, name string, info fs.FileInfo) {
+	file, err := h.fsys.Open(name)
+	if err != nil {
+		serveError(w, err)
+		return
+	}
+	defer file.Close()
+
+	if content, ok := file.(io.ReadSeeker); ok {
+		http.ServeContent(w, r, info.Name(), info.ModTime(), content)
+		return
+	}
+	contentType := mime.TypeByExtension(path.Ext(name))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Length", fmt.Sprint(info.Size()))
+	w.Header().Set("Last-Modified", info.ModTime().UTC().Format(http.TimeFormat))
+	if r.Method != http.MethodHead {
+		io.Copy(w, file)
+	}
+}
+
+// listingEntry is an entry of a directory listing.
+type listingEntry struct {
+	Name     string
+	Link     string
+	Size     string
+	Modified string
+}
+
+// listingTemplate is the page on which the contents of a directory are listed.
+var listingTemplate = template.Must(template.New("listing").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>Index of {{.Path}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; }
+td, th { padding: 0.2em 1.5em 0.2em 0; text-align: left; }
+td.size { text-align: right; }
+</style>
+</head>
+<body>
+<h1>Index of {{.Path}}</h1>
+{{- if .Upload}}
+<form method="post" enctype="multipart/form-data">
+<input type="file" name="{{.Field}}" multiple> <input type="submit" value="Upload">
+</form>
+{{- end}}
+<table>
+<tr><th>Name</th><th>Size</th><th>Modified</th></tr>
+{{- if ne .Path "/"}}
+<tr><td><a href="../">../</a></td><td></td><td></td></tr>
+{{- end}}
+{{- range .Entries}}
+<tr><td><a href="{{.Link}}">{{.Name}}</a></td><td class="size">{{.Size}}</td>
+<td>{{.Modified}}</td></tr>
+{{- end}}
+</table>
+</body>
+</html>
+`))
+
+// list answers a request for a directory with a page listing its contents. Directories are listed
+// before files, and symbolic links are listed as what they refer to.
+func (h *Handler) list(w http.ResponseWriter, r *http.Request, name, urlPath string) {
+	dirEntries, err := h.fsys.ReadDir(name)
+	if err != nil {
+		serveError(w, err)
+		return
+	}
+	var dirs, files []listingEntry
+	for _, dirEntry := range dirEntries {
+		entryName := dirEntry.Name()
+		if !h.options.Hidden && strings.HasPrefix(entryName, ".") {
+			continue
+		}
+		target, err := h.resolve(path.Join(name, entryName))
+		if err != nil {
+			// A symbolic link leading outside of the shared directory cannot be served.
+			continue
+		}
+		info, err := h.fsys.Stat(target)
+		if err != nil {
+			// Nor can a broken one.
+			continue
+		}
+		entry := listingEntry{
+			Name:     entryName,
+			Link:     (&url.URL{Path: "./" + entryName}).String(),
+			Modified: info.ModTime().Format("2006-01-02 15:04"),
+		}
+		if info.IsDir() {
+			entry.Name += "/"
+			entry.Link += "/"
+			dirs = append(dirs, entry)
+		} else {
+			entry.Size = preview.FormatSize(info.Size())
+			files = append(files, entry)
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if r.Method == http.MethodHead {
+		return
+	}
+	listingTemplate.Execute(w, struct {
+		Path    string
+		Upload  bool
+		Field   string
+		Entries []listingEntry
+	}{urlPath, h.options.Upload, uploadField, append(dirs, files...)})
+}
+
+// upload creates the files uploaded to a directory through the form of its listing, and then
+// redirects to the listing. If a file already exists, or cannot be created, the files before it
+// are kept but it and those after it are not created.
+func (h *Handler) upload(w http.ResponseWriter, r *http.Request, dir, urlPath string) {
+	reader, err := r.MultipartReader()
+	if err != nil {
+		http.Error(w, "400 bad request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			http.Error(w, "400 bad request: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		if part.FormName() != uploadField || part.FileName() == "" {
+			continue
+		}
+		fileName := part.FileName()
+		if fileName == "." || fileName == ".." || strings.ContainsAny(fileName, "/\\") ||
+			!h.options.Hidden && strings.HasPrefix(fileName, ".") {
+			http.Error(w, "400 bad request: invalid file name", http.StatusBadRequest)
+			return
+		}
+		if err := h.create(path.Join(dir, fileName), part); err != nil {
+			serveError(w, err)
+			return
+		}
+	}
+	w.Header().Set("Location", (&url.URL{Path: urlPath}).String())
+	w.WriteHeader(http.StatusSeeOther)
+}
+
+// create creates a file holding the contents read from a reader. If they cannot all be read and
+// written, the file is removed.
+func (h *Handler) create(name string, contents io.Reader) error {
+	file, err := h.fsys.Create(name, 0644)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(file, contents)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		h.fsys.RemoveAll(name)
+	}
+	return err
+}
@@ -0,0 +1,252 @@
+// Copyright 2019 Max Godfrey
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 		http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fileserver
+
+import (
+	"bytes"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/maxgodfrey2004/go-file-manager/filesystem"
+)
+
+// newTestServer returns a server sharing the directory "share" of an in-memory file system, along
+// with the file system and a function returning the lines which have been logged.
+func newTestServer(t *testing.T, options Options) (*httptest.Server, *filesystem.Memory,
+	func() []string) {
+	fsys := filesystem.NewMemory()
+	fsys.WriteFile("share/readme.txt", []byte("hello, world\n"), 0644)
+	fsys.WriteFile("share/docs/guide.html", []byte("<p>guide</p>"), 0644)
+	fsys.WriteFile("share/.secret", []byte("hidden"), 0600)
+	fsys.WriteFile("private.txt", []byte("private"), 0600)
+	fsys.Symlink("../private.txt", "share/private")
+	fsys.Symlink("docs/../docs/guide.html", "share/guide")
+	fsys.Symlink(".secret", "share/hidden")
+
+	var mu sync.Mutex
+	var lines []string
+	options.Log = func(line string) {
+		mu.Lock()
+		defer mu.Unlock()
+		lines = append(lines, line)
+	}
+	server := httptest.NewServer(New(fsys, "share", options))
+	t.Cleanup(server.Close)
+	logged := func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string{}, lines...)
+	}
+	return server, fsys, logged
+}
+
+// get makes a request without following redirects, returning the response and its body.
+func get(t *testing.T, req *http.Request) (*http.Response, string) {
+	t.Helper()
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp, string(body)
+}
+
+func newRequest(t *testing.T, method, url string, body io.Reader) *http.Request {
+	t.Helper()
+	req, err := http.NewRequest(method, url, body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return req
+}
+
+func TestHandler(t *testing.T) {
+	server, _, logged := newTestServer(t, Options{})
+	tests := []struct {
+		path     string
+		status   int
+		contains string // A string which the body must contain.
+		location string // The location to which the request must be redirected.
+	}{
+		{path: "/", status: http.StatusOK, contains: `<a href="./readme.txt">readme.txt</a>`},
+		{path: "/", status: http.StatusOK, contains: `<a href="./docs/">docs/</a>`},
+		{path: "/readme.txt", status: http.StatusOK, contains: "hello, world"},
+		{path: "/docs/guide.html", status: http.StatusOK, contains: "<p>guide</p>"},
+		{path: "/docs", status: http.StatusMovedPermanently, location: "/docs/"},
+		{path: "/readme.txt/", status: http.StatusMovedPermanently, location: "/readme.txt"},
+		{path: "/docs/../readme.txt", status: http.StatusMovedPermanently, location: "/readme.txt"},
+		{path: "/missing", status: http.StatusNotFound},
+		{path: "/.secret", status: http.StatusNotFound},
+		{path: "/../private.txt", status: http.StatusNotFound},
+		// Symbolic links are only followed within the shared directory.
+		{path: "/private", status: http.StatusForbidden},
+		{path: "/guide", status: http.StatusOK, contains: "guide"},
+		{path: "/hidden", status: http.StatusNotFound},
+	}
+	for _, test := range tests {
+		resp, body := get(t, newRequest(t, http.MethodGet, server.URL+test.path, nil))
+		if resp.StatusCode != test.status {
+			t.Errorf("GET %s: status = %d, want %d", test.path, resp.StatusCode, test.status)
+		}
+		if !strings.Contains(body, test.contains) {
+			t.Errorf("GET %s: body = %q, want it to contain %q", test.path, body, test.contains)
+		}
+		if location := resp.Header.Get("Location"); location != test.location {
+			t.Errorf("GET %s: location = %q, want %q", test.path, location, test.location)
+		}
+	}
+
+	_, body := get(t, newRequest(t, http.MethodGet, server.URL+"/", nil))
+	if strings.Contains(body, ".secret") || strings.Contains(body, "<form") {
+		t.Errorf("listing shows a hidden file or an upload form:\n%s", body)
+	}
+	if lines := logged(); len(lines) != len(tests)+1 || lines[0] != "127.0.0.1 GET / 200" {
+		t.Errorf("logged %q", lines)
+	}
+}
+
+func TestHandlerHidden(t *testing.T) {
+	server, _, _ := newTestServer(t, Options{Hidden: true})
+	_, body := get(t, newRequest(t, http.MethodGet, server.URL+"/", nil))
+	if !strings.Contains(body, ".secret") {
+		t.Errorf("listing does not show a hidden file:\n%s", body)
+	}
+	resp, body := get(t, newRequest(t, http.MethodGet, server.URL+"/.secret", nil))
+	if body != "hidden" {
+		t.Errorf("GET /.secret: status = %d, body = %q", resp.StatusCode, body)
+	}
+}
+
+func TestHandlerRange(t *testing.T) {
+	server, _, _ := newTestServer(t, Options{})
+	req := newRequest(t, http.MethodGet, server.URL+"/readme.txt", nil)
+	req.Header.Set("Range", "bytes=7-11")
+	resp, body := get(t, req)
+	if resp.StatusCode != http.StatusPartialContent || body != "world" {
+		t.Errorf("status = %d, body = %q, want %d and %q", resp.StatusCode, body,
+			http.StatusPartialContent, "world")
+	}
+	if contentType := resp.Header.Get("Content-Type"); !strings.HasPrefix(contentType, "text/plain") {
+		t.Errorf("content type = %q", contentType)
+	}
+}
+
+func TestHandlerAuth(t *testing.T) {
+	server, _, logged := newTestServer(t, Options{Username: "user", Password: "secret"})
+	tests := []struct {
+		username, password string
+		status             int
+	}{
+		{"", "", http.StatusUnauthorized},
+		{"user", "wrong", http.StatusUnauthorized},
+		{"other", "secret", http.StatusUnauthorized},
+		{"user", "secret", http.StatusOK},
+	}
+	for _, test := range tests {
+		req := newRequest(t, http.MethodGet, server.URL+"/readme.txt", nil)
+		if test.username != "" {
+			req.SetBasicAuth(test.username, test.password)
+		}
+		resp, _ := get(t, req)
+		if resp.StatusCode != test.status {
+			t.Errorf("%s:%s: status = %d, want %d", test.username, test.password, resp.StatusCode,
+				test.status)
+		}
+		if resp.StatusCode == http.StatusUnauthorized && resp.Header.Get("WWW-Authenticate") == "" {
+			t.Errorf("%s:%s: no WWW-Authenticate header", test.username, test.password)
+		}
+	}
+	if lines := logged(); len(lines) != 4 || lines[0] != "127.0.0.1 GET /readme.txt 401" {
+		t.Errorf("logged %q", lines)
+	}
+}
+
+// uploadRequest returns a request uploading files to a directory through the form of its listing.
+func uploadRequest(t *testing.T, url string, files map[string]string) *http.Request {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	for name, contents := range files {
+		part, err := writer.CreateFormFile("file", name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		part.Write([]byte(contents))
+	}
+	writer.Close()
+	req := newRequest(t, http.MethodPost, url, &body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	return req
+}
+
+func TestHandlerUpload(t *testing.T) {
+	server, fsys, _ := newTestServer(t, Options{})
+	resp, _ := get(t, uploadRequest(t, server.URL+"/", map[string]string{"new.txt": "new"}))
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("upload while disabled: status = %d, want %d", resp.StatusCode,
+			http.StatusMethodNotAllowed)
+	}
+	if _, err := fsys.Stat("share/new.txt"); err == nil {
+		t.Error("a file was uploaded while uploads were disabled")
+	}
+
+	server, fsys, _ = newTestServer(t, Options{Upload: true})
+	_, body := get(t, newRequest(t, http.MethodGet, server.URL+"/docs/", nil))
+	if !strings.Contains(body, "<form") {
+		t.Errorf("listing has no upload form:\n%s", body)
+	}
+	tests := []struct {
+		name   string
+		status int
+	}{
+		{"new.txt", http.StatusSeeOther},
+		{"guide.html", http.StatusConflict},
+		{"..", http.StatusBadRequest},
+		{".hidden", http.StatusBadRequest},
+	}
+	for _, test := range tests {
+		resp, _ := get(t, uploadRequest(t, server.URL+"/docs/", map[string]string{test.name: "uploaded"}))
+		if resp.StatusCode != test.status {
+			t.Errorf("upload %s: status = %d, want %d", test.name, resp.StatusCode, test.status)
+		}
+	}
+	f, err := fsys.Open("share/docs/new.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := io.ReadAll(f); string(data) != "uploaded" {
+		t.Errorf("uploaded file holds %q, want %q", data, "uploaded")
+	}
+	resp, _ = get(t, uploadRequest(t, server.URL+"/", map[string]string{"readme.txt": "replaced"}))
+	if resp.StatusCode != http.StatusConflict {
+		t.Errorf("upload over an existing file: status = %d, want %d", resp.StatusCode,
+			http.StatusConflict)
+	}
+	f, _ = fsys.Open("share/readme.txt")
+	if data, _ := io.ReadAll(f); string(data) != "hello, world\n" {
+		t.Errorf("existing file was replaced with %q", data)
+	}
+}
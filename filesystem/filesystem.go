@@ -0,0 +1,131 @@
+// Copyright 2019 Max Godfrey
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 		http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package filesystem describes the file systems which the explorer may browse, and provides the
+// local file system, the file systems of remote hosts and object stores, and in-memory and
+// read-only file systems.
+//
+// A FileSystem's read operations are those of io/fs, so names are slash-separated paths which do
+// not begin with a slash, and "." names the root of the file system.
+package filesystem
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"sort"
+)
+
+var (
+	// ErrReadOnly is the error of an operation which would change a read-only file system.
+	ErrReadOnly = errors.New("read-only file system")
+
+	// ErrUnsupported is the error of an operation which a file system does not support.
+	ErrUnsupported = errors.New("operation not supported")
+
+	// errIsDir and errNotDir describe operations which require a file or directory respectively.
+	errIsDir  = errors.New("is a directory")
+	errNotDir = errors.New("not a directory")
+)
+
+// FileSystem is a file system which may be read from and written to.
+type FileSystem interface {
+	fs.ReadDirFS
+	fs.StatFS
+
+	// Lstat returns information about a file, describing a symbolic link rather than the file
+	// which it refers to.
+	Lstat(name string) (fs.FileInfo, error)
+
+	// ReadLink returns the destination of a symbolic link.
+	ReadLink(name string) (string, error)
+
+	// Create creates a file which must not already exist, returning a writer with which its
+	// contents are written. The file is complete once the writer has been closed.
+	Create(name string, perm fs.FileMode) (io.WriteCloser, error)
+
+	// Mkdir creates a directory which must not already exist.
+	Mkdir(name string, perm fs.FileMode) error
+
+	// Symlink creates a symbolic link named newname which refers to oldname.
+	Symlink(oldname, newname string) error
+
+	// Link creates a hard link named newname to the file oldname.
+	Link(oldname, newname string) error
+
+	// Rename renames a file or directory, replacing any file at newname.
+	Rename(oldname, newname string) error
+
+	// RemoveAll removes a file, or a directory along with all of its contents. It is not an
+	// error if there is nothing to remove.
+	RemoveAll(name string) error
+
+	// Chmod changes the permissions of a file or directory, along with its setuid, setgid and
+	// sticky bits.
+	Chmod(name string, mode fs.FileMode) error
+
+	// Chown changes the user and group which own a file or directory. An ID of -1 leaves the user
+	// or group as it is.
+	Chown(name string, uid, gid int) error
+}
+
+// ModeBits are the bits of a file's mode which Chmod changes.
+const ModeBits = fs.ModePerm | fs.ModeSetuid | fs.ModeSetgid | fs.ModeSticky
+
+// Owner returns the IDs of the user and group which own a file, as described by information about
+// it, reporting whether the file system which described it records them.
+func Owner(info fs.FileInfo) (uid, gid int, ok bool) {
+	if ids, ok := info.(interface {
+		Uid() uint32
+		Gid() uint32
+	}); ok {
+		return int(ids.Uid()), int(ids.Gid()), true
+	}
+	return sysOwner(info.Sys())
+}
+
+// sortEntries sorts the entries of a directory by name, as ReadDir returns them.
+func sortEntries(entries []fs.DirEntry) {
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+}
+
+// dirFile is a directory which has been opened, whose entries were read when it was opened.
+type dirFile struct {
+	info    fs.FileInfo
+	entries []fs.DirEntry
+	offset  int
+}
+
+func (d *dirFile) Stat() (fs.FileInfo, error) { return d.info, nil }
+func (d *dirFile) Close() error               { return nil }
+
+func (d *dirFile) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.info.Name(), Err: errIsDir}
+}
+
+func (d *dirFile) ReadDir(n int) ([]fs.DirEntry, error) {
+	remaining := d.entries[d.offset:]
+	if n <= 0 {
+		d.offset = len(d.entries)
+		return remaining, nil
+	}
+	if len(remaining) == 0 {
+		return nil, io.EOF
+	}
+	if n > len(remaining) {
+		n = len(remaining)
+	}
+	d.offset += n
+	return remaining[:n], nil
+}
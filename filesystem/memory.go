@@ -0,0 +1,400 @@
+// Copyright 2019 Max Godfrey
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 		http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filesystem
+
+import (
+	"bytes"
+	"io"
+	"io/fs"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxLinks is the most symbolic links which are followed when resolving a name, beyond which the
+// links are assumed to form a loop.
+const maxLinks = 40
+
+// memoryFile is a file, directory or symbolic link held by a Memory file system.
+type memoryFile struct {
+	data    []byte
+	mode    fs.FileMode
+	modTime time.Time
+	target  string // The destination of a symbolic link.
+	uid     int    // The ID of the user which owns the file.
+	gid     int    // The ID of the group which owns the file.
+}
+
+// Memory is a file system held in memory, which is useful for testing. Symbolic links are
+// followed when they are the last element of a name, but not when they lead to a directory in the
+// middle of one. It is safe for concurrent use.
+type Memory struct {
+	mu    sync.RWMutex
+	files map[string]*memoryFile
+}
+
+// NewMemory returns an empty Memory file system.
+func NewMemory() *Memory {
+	return &Memory{files: map[string]*memoryFile{
+		".": {mode: fs.ModeDir | 0755, modTime: time.Now()},
+	}}
+}
+
+// WriteFile creates a file holding data, along with any directories which contain it, replacing
+// any file which already exists.
+func (m *Memory) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	if err := m.MkdirAll(path.Dir(name), 0755); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if f, ok := m.files[name]; ok && f.mode.IsDir() {
+		return &fs.PathError{Op: "writefile", Path: name, Err: errIsDir}
+	}
+	m.files[name] = &memoryFile{data: append([]byte{}, data...), mode: perm.Perm(),
+		modTime: time.Now()}
+	return nil
+}
+
+// MkdirAll creates a directory along with any directories which contain it. It is not an error if
+// the directory already exists.
+func (m *Memory) MkdirAll(name string, perm fs.FileMode) error {
+	if !fs.ValidPath(name) {
+		return &fs.PathError{Op: "mkdir", Path: name, Err: fs.ErrInvalid}
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, dir := range ancestry(name) {
+		f, ok := m.files[dir]
+		switch {
+		case !ok:
+			m.files[dir] = &memoryFile{mode: fs.ModeDir | perm.Perm(), modTime: time.Now()}
+		case !f.mode.IsDir():
+			return &fs.PathError{Op: "mkdir", Path: dir, Err: errNotDir}
+		}
+	}
+	return nil
+}
+
+// ancestry returns each directory leading to a name, followed by the name itself.
+func ancestry(name string) []string {
+	if name == "." {
+		return []string{"."}
+	}
+	var names []string
+	for i := range name {
+		if name[i] == '/' {
+			names = append(names, name[:i])
+		}
+	}
+	return append(names, name)
+}
+
+// resolve returns the file with a name, following any symbolic links if follow is set, along with
+// the name of the file which was found. The caller must hold the lock.
+func (m *Memory) resolve(op, name string, follow bool) (*memoryFile, string, error) {
+	if !fs.ValidPath(name) {
+		return nil, "", &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	resolved := name
+	for i := 0; i <= maxLinks; i++ {
+		f, ok := m.files[resolved]
+		if !ok {
+			return nil, "", &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+		}
+		if f.mode&fs.ModeSymlink == 0 || !follow {
+			return f, resolved, nil
+		}
+		if path.IsAbs(f.target) {
+			resolved = path.Clean(strings.TrimPrefix(f.target, "/"))
+			if resolved == "" {
+				resolved = "."
+			}
+		} else {
+			resolved = path.Join(path.Dir(resolved), f.target)
+		}
+	}
+	return nil, "", &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+}
+
+// info describes a file with a name.
+func (f *memoryFile) info(name string) fs.FileInfo {
+	return memoryInfo{name: path.Base(name), size: int64(len(f.data)), mode: f.mode,
+		modTime: f.modTime, uid: f.uid, gid: f.gid}
+}
+
+// children returns the entries of a directory, sorted by name. The caller must hold the lock.
+func (m *Memory) children(dir string) []fs.DirEntry {
+	var entries []fs.DirEntry
+	for name, f := range m.files {
+		if name != "." && path.Dir(name) == dir {
+			entries = append(entries, fs.FileInfoToDirEntry(f.info(name)))
+		}
+	}
+	sortEntries(entries)
+	return entries
+}
+
+func (m *Memory) Open(name string) (fs.File, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	f, resolved, err := m.resolve("open", name, true)
+	if err != nil {
+		return nil, err
+	}
+	info := f.info(name)
+	if f.mode.IsDir() {
+		return &dirFile{info: info, entries: m.children(resolved)}, nil
+	}
+	// The contents are copied, so that the file may be read while it is being changed.
+	return &memoryReader{info: info, Reader: bytes.NewReader(append([]byte{}, f.data...))}, nil
+}
+
+func (m *Memory) ReadDir(name string) ([]fs.DirEntry, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	f, resolved, err := m.resolve("readdir", name, true)
+	if err != nil {
+		return nil, err
+	}
+	if !f.mode.IsDir() {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: errNotDir}
+	}
+	return m.children(resolved), nil
+}
+
+func (m *Memory) Stat(name string) (fs.FileInfo, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	f, _, err := m.resolve("stat", name, true)
+	if err != nil {
+		return nil, err
+	}
+	return f.info(name), nil
+}
+
+func (m *Memory) Lstat(name string) (fs.FileInfo, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	f, _, err := m.resolve("lstat", name, false)
+	if err != nil {
+		return nil, err
+	}
+	return f.info(name), nil
+}
+
+func (m *Memory) ReadLink(name string) (string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	f, _, err := m.resolve("readlink", name, false)
+	if err != nil {
+		return "", err
+	}
+	if f.mode&fs.ModeSymlink == 0 {
+		return "", &fs.PathError{Op: "readlink", Path: name, Err: fs.ErrInvalid}
+	}
+	return f.target, nil
+}
+
+// add adds a file which must not already exist to a directory which must exist. The caller must
+// hold the lock.
+func (m *Memory) add(op, name string, f *memoryFile) error {
+	if !fs.ValidPath(name) || name == "." {
+		return &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	if _, ok := m.files[name]; ok {
+		return &fs.PathError{Op: op, Path: name, Err: fs.ErrExist}
+	}
+	parent, ok := m.files[path.Dir(name)]
+	if !ok {
+		return &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+	}
+	if !parent.mode.IsDir() {
+		return &fs.PathError{Op: op, Path: name, Err: errNotDir}
+	}
+	f.modTime = time.Now()
+	m.files[name] = f
+	return nil
+}
+
+func (m *Memory) Create(name string, perm fs.FileMode) (io.WriteCloser, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	f := &memoryFile{mode: perm.Perm()}
+	if err := m.add("create", name, f); err != nil {
+		return nil, err
+	}
+	return &memoryWriter{m: m, f: f}, nil
+}
+
+func (m *Memory) Mkdir(name string, perm fs.FileMode) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.add("mkdir", name, &memoryFile{mode: fs.ModeDir | perm.Perm()})
+}
+
+func (m *Memory) Symlink(oldname, newname string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.add("symlink", newname, &memoryFile{mode: fs.ModeSymlink | 0777, target: oldname})
+}
+
+// Link creates a hard link, so that the file is known by both names and changes through either name
+// are seen through the other.
+func (m *Memory) Link(oldname, newname string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	f, _, err := m.resolve("link", oldname, false)
+	if err != nil {
+		return err
+	}
+	if f.mode.IsDir() {
+		return &fs.PathError{Op: "link", Path: oldname, Err: errIsDir}
+	}
+	// Linking a file does not modify it.
+	modTime := f.modTime
+	if err := m.add("link", newname, f); err != nil {
+		return err
+	}
+	f.modTime = modTime
+	return nil
+}
+
+func (m *Memory) Rename(oldname, newname string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	f, _, err := m.resolve("rename", oldname, false)
+	if err != nil {
+		return err
+	}
+	if !fs.ValidPath(newname) || oldname == "." || newname == "." ||
+		strings.HasPrefix(newname, oldname+"/") {
+		return &fs.PathError{Op: "rename", Path: oldname, Err: fs.ErrInvalid}
+	}
+	if oldname == newname {
+		return nil
+	}
+	if existing, ok := m.files[newname]; ok {
+		if existing.mode.IsDir() != f.mode.IsDir() || len(m.children(newname)) > 0 {
+			return &fs.PathError{Op: "rename", Path: newname, Err: fs.ErrExist}
+		}
+	} else if parent, ok := m.files[path.Dir(newname)]; !ok || !parent.mode.IsDir() {
+		return &fs.PathError{Op: "rename", Path: newname, Err: fs.ErrNotExist}
+	}
+
+	for name, moved := range m.files {
+		if name == oldname || strings.HasPrefix(name, oldname+"/") {
+			delete(m.files, name)
+			m.files[newname+strings.TrimPrefix(name, oldname)] = moved
+		}
+	}
+	return nil
+}
+
+func (m *Memory) RemoveAll(name string) error {
+	if !fs.ValidPath(name) || name == "." {
+		return &fs.PathError{Op: "removeall", Path: name, Err: fs.ErrInvalid}
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for other := range m.files {
+		if other == name || strings.HasPrefix(other, name+"/") {
+			delete(m.files, other)
+		}
+	}
+	return nil
+}
+
+func (m *Memory) Chmod(name string, mode fs.FileMode) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	f, _, err := m.resolve("chmod", name, true)
+	if err != nil {
+		return err
+	}
+	f.mode = f.mode&fs.ModeType | mode&ModeBits
+	return nil
+}
+
+func (m *Memory) Chown(name string, uid, gid int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	f, _, err := m.resolve("chown", name, true)
+	if err != nil {
+		return err
+	}
+	if uid >= 0 {
+		f.uid = uid
+	}
+	if gid >= 0 {
+		f.gid = gid
+	}
+	return nil
+}
+
+// memoryInfo describes a file of a Memory file system.
+type memoryInfo struct {
+	name    string
+	size    int64
+	mode    fs.FileMode
+	modTime time.Time
+	uid     int
+	gid     int
+}
+
+func (i memoryInfo) Name() string       { return i.name }
+func (i memoryInfo) Size() int64        { return i.size }
+func (i memoryInfo) Mode() fs.FileMode  { return i.mode }
+func (i memoryInfo) ModTime() time.Time { return i.modTime }
+func (i memoryInfo) IsDir() bool        { return i.mode.IsDir() }
+func (i memoryInfo) Sys() interface{}   { return nil }
+func (i memoryInfo) Uid() uint32        { return uint32(i.uid) }
+func (i memoryInfo) Gid() uint32        { return uint32(i.gid) }
+
+// memoryReader is a file of a Memory file system which has been opened for reading.
+type memoryReader struct {
+	info fs.FileInfo
+	*bytes.Reader
+}
+
+func (r *memoryReader) Stat() (fs.FileInfo, error) { return r.info, nil }
+func (r *memoryReader) Close() error               { return nil }
+
+// memoryWriter writes the contents of a file of a Memory file system which has been created.
+type memoryWriter struct {
+	m      *Memory
+	f      *memoryFile
+	closed bool
+}
+
+func (w *memoryWriter) Write(p []byte) (int, error) {
+	if w.closed {
+		return 0, fs.ErrClosed
+	}
+	w.m.mu.Lock()
+	defer w.m.mu.Unlock()
+	w.f.data = append(w.f.data, p...)
+	w.f.modTime = time.Now()
+	return len(p), nil
+}
+
+func (w *memoryWriter) Close() error {
+	if w.closed {
+		return fs.ErrClosed
+	}
+	w.closed = true
+	return nil
+}
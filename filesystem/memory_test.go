@@ -0,0 +1,160 @@
+// Copyright 2019 Max Godfrey
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 		http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filesystem
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+)
+
+// newTestMemory returns a Memory file system holding a few files and directories.
+func newTestMemory(t *testing.T) *Memory {
+	m := NewMemory()
+	for name, contents := range map[string]string{
+		"a.txt":           "a",
+		"dir/b.txt":       "bb",
+		"dir/sub/c.txt":   "ccc",
+		"empty/.keep":     "",
+		"dir/sub/d/e.txt": "eeeee",
+	} {
+		if err := m.WriteFile(name, []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := m.Symlink("dir/b.txt", "link"); err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func TestMemoryFS(t *testing.T) {
+	m := newTestMemory(t)
+	err := fstest.TestFS(m, "a.txt", "dir/b.txt", "dir/sub/c.txt", "dir/sub/d/e.txt", "empty/.keep")
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMemoryLinks(t *testing.T) {
+	m := newTestMemory(t)
+	info, err := m.Lstat("link")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode()&fs.ModeSymlink == 0 {
+		t.Errorf("Lstat(\"link\") has mode %v, want a symbolic link", info.Mode())
+	}
+	if info, err = m.Stat("link"); err != nil || info.Size() != 2 {
+		t.Errorf("Stat(\"link\") = %v, %v, want the file it refers to", info, err)
+	}
+	if target, err := m.ReadLink("link"); err != nil || target != "dir/b.txt" {
+		t.Errorf("ReadLink(\"link\") = %q, %v", target, err)
+	}
+
+	if err := m.Symlink("loop", "loop"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Stat("loop"); err == nil {
+		t.Error("Stat of a link to itself did not fail")
+	}
+
+	if err := m.Link("a.txt", "dir/hard"); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Chmod("dir/hard", 0600); err != nil {
+		t.Fatal(err)
+	}
+	if info, err := m.Stat("a.txt"); err != nil || info.Mode() != 0600 || info.Size() != 1 {
+		t.Errorf("Stat(\"a.txt\") = %v, %v after changing its hard link", info, err)
+	}
+	if err := m.Link("dir", "hard"); !errors.Is(err, errIsDir) {
+		t.Errorf("Link of a directory returned %v, want %v", err, errIsDir)
+	}
+	if err := m.Link("a.txt", "dir/b.txt"); !errors.Is(err, fs.ErrExist) {
+		t.Errorf("Link over an existing file returned %v, want %v", err, fs.ErrExist)
+	}
+}
+
+func TestMemoryWrite(t *testing.T) {
+	m := newTestMemory(t)
+
+	w, err := m.Create("dir/new.txt", 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.WriteString(w, "new")
+	w.Close()
+	if data, err := fs.ReadFile(m, "dir/new.txt"); err != nil || string(data) != "new" {
+		t.Errorf("ReadFile of a created file = %q, %v", data, err)
+	}
+	if _, err := m.Create("dir/new.txt", 0600); !errors.Is(err, fs.ErrExist) {
+		t.Errorf("Create of an existing file returned %v, want %v", err, fs.ErrExist)
+	}
+	if _, err := m.Create("missing/new.txt", 0600); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Create in a missing directory returned %v, want %v", err, fs.ErrNotExist)
+	}
+
+	if err := m.Rename("dir", "moved"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Stat("moved/sub/d/e.txt"); err != nil {
+		t.Errorf("the contents of a renamed directory were not moved: %v", err)
+	}
+	if _, err := m.Stat("dir"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("a renamed directory remains: %v", err)
+	}
+	if err := m.Rename("moved", "moved/sub/inside"); err == nil {
+		t.Error("a directory was renamed into itself")
+	}
+
+	if err := m.Chmod("a.txt", 0400); err != nil {
+		t.Fatal(err)
+	}
+	if info, _ := m.Stat("a.txt"); info.Mode() != 0400 {
+		t.Errorf("mode after Chmod = %v, want %v", info.Mode(), fs.FileMode(0400))
+	}
+	if err := m.Chmod("moved", fs.ModeSticky|fs.ModeSetgid|fs.ModeDir|0775); err != nil {
+		t.Fatal(err)
+	}
+	if info, _ := m.Stat("moved"); info.Mode() != fs.ModeDir|fs.ModeSticky|fs.ModeSetgid|0775 {
+		t.Errorf("mode after Chmod = %v, want the sticky and setgid bits", info.Mode())
+	}
+	if err := m.Chown("a.txt", 1000, -1); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Chown("a.txt", -1, 100); err != nil {
+		t.Fatal(err)
+	}
+	info, err := m.Stat("a.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if uid, gid, ok := Owner(info); uid != 1000 || gid != 100 || !ok {
+		t.Errorf("Owner after Chown = %d, %d, %v, want 1000, 100, true", uid, gid, ok)
+	}
+
+	if err := m.RemoveAll("moved"); err != nil {
+		t.Fatal(err)
+	}
+	if entries, _ := m.ReadDir("."); len(entries) != 3 {
+		t.Errorf("%d entries remain after RemoveAll, want 3", len(entries))
+	}
+	if err := m.RemoveAll("missing"); err != nil {
+		t.Errorf("RemoveAll of a missing file returned %v", err)
+	}
+}
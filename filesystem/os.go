@@ -0,0 +1,158 @@
+// Copyright 2019 Max Godfrey
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 		http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filesystem
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// osFS is the local file system.
+type osFS struct{}
+
+// OS returns the local file system, whose root is the root of the operating system's file system.
+// On Windows, each name begins with the volume on which the file lies, such as "C:/Users".
+func OS() FileSystem {
+	return osFS{}
+}
+
+// path returns the path of a file on the local file system.
+func (osFS) path(op, name string) (string, error) {
+	if !fs.ValidPath(name) {
+		return "", &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	if name == "." {
+		return string(filepath.Separator), nil
+	}
+	if native := filepath.FromSlash(name); filepath.VolumeName(native) != "" {
+		return native, nil
+	}
+	return filepath.FromSlash("/" + name), nil
+}
+
+func (o osFS) Open(name string) (fs.File, error) {
+	p, err := o.path("open", name)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(p)
+}
+
+func (o osFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	p, err := o.path("readdir", name)
+	if err != nil {
+		return nil, err
+	}
+	return os.ReadDir(p)
+}
+
+func (o osFS) Stat(name string) (fs.FileInfo, error) {
+	p, err := o.path("stat", name)
+	if err != nil {
+		return nil, err
+	}
+	return os.Stat(p)
+}
+
+func (o osFS) Lstat(name string) (fs.FileInfo, error) {
+	p, err := o.path("lstat", name)
+	if err != nil {
+		return nil, err
+	}
+	return os.Lstat(p)
+}
+
+func (o osFS) ReadLink(name string) (string, error) {
+	p, err := o.path("readlink", name)
+	if err != nil {
+		return "", err
+	}
+	return os.Readlink(p)
+}
+
+func (o osFS) Create(name string, perm fs.FileMode) (io.WriteCloser, error) {
+	p, err := o.path("create", name)
+	if err != nil {
+		return nil, err
+	}
+	return os.OpenFile(p, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+}
+
+func (o osFS) Mkdir(name string, perm fs.FileMode) error {
+	p, err := o.path("mkdir", name)
+	if err != nil {
+		return err
+	}
+	return os.Mkdir(p, perm)
+}
+
+// Symlink creates a symbolic link. Its destination, oldname, is written to the link as it is given.
+func (o osFS) Symlink(oldname, newname string) error {
+	p, err := o.path("symlink", newname)
+	if err != nil {
+		return err
+	}
+	return os.Symlink(oldname, p)
+}
+
+func (o osFS) Link(oldname, newname string) error {
+	oldPath, err := o.path("link", oldname)
+	if err != nil {
+		return err
+	}
+	newPath, err := o.path("link", newname)
+	if err != nil {
+		return err
+	}
+	return os.Link(oldPath, newPath)
+}
+
+func (o osFS) Rename(oldname, newname string) error {
+	oldPath, err := o.path("rename", oldname)
+	if err != nil {
+		return err
+	}
+	newPath, err := o.path("rename", newname)
+	if err != nil {
+		return err
+	}
+	return os.Rename(oldPath, newPath)
+}
+
+func (o osFS) RemoveAll(name string) error {
+	p, err := o.path("removeall", name)
+	if err != nil {
+		return err
+	}
+	return os.RemoveAll(p)
+}
+
+func (o osFS) Chmod(name string, mode fs.FileMode) error {
+	p, err := o.path("chmod", name)
+	if err != nil {
+		return err
+	}
+	return os.Chmod(p, mode)
+}
+
+func (o osFS) Chown(name string, uid, gid int) error {
+	p, err := o.path("chown", name)
+	if err != nil {
+		return err
+	}
+	return os.Chown(p, uid, gid)
+}
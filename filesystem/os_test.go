@@ -0,0 +1,77 @@
+// Copyright 2019 Max Godfrey
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 		http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filesystem
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestOS(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "a.txt"), []byte("a"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	name := strings.TrimPrefix(filepath.ToSlash(dir), "/")
+	o := OS()
+
+	w, err := o.Create(name+"/b.txt", 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.WriteString(w, "b")
+	w.Close()
+	if err := o.Mkdir(name+"/sub", 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := o.Symlink("a.txt", name+"/link"); err != nil {
+		t.Fatal(err)
+	}
+	if info, err := o.Lstat(name + "/link"); err != nil || info.Mode()&fs.ModeSymlink == 0 {
+		t.Errorf("Lstat of a link = %v, %v", info, err)
+	}
+	if err := o.Link(name+"/a.txt", name+"/sub/hard"); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := fs.ReadFile(o, name+"/sub/hard"); err != nil || string(data) != "a" {
+		t.Errorf("ReadFile of a hard link = %q, %v", data, err)
+	}
+
+	sub, err := fs.Sub(o, name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := fstest.TestFS(sub, "a.txt", "b.txt", "sub"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := o.Rename(name+"/b.txt", name+"/sub/b.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if err := o.RemoveAll(name + "/sub"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "sub")); !os.IsNotExist(err) {
+		t.Errorf("a removed directory remains: %v", err)
+	}
+	if _, err := o.Open("../escape"); err == nil {
+		t.Error("Open of an invalid name did not fail")
+	}
+}
@@ -0,0 +1,46 @@
+// Copyright 2019 Max Godfrey
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 		http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !windows
+// +build !windows
+
+package filesystem
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestOSOwner(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "a.txt"), []byte("a"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	name := strings.TrimPrefix(filepath.ToSlash(dir), "/") + "/a.txt"
+	o := OS()
+
+	// Any user may give their own files to themselves.
+	if err := o.Chown(name, os.Getuid(), os.Getgid()); err != nil {
+		t.Fatal(err)
+	}
+	info, err := o.Stat(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if uid, gid, ok := Owner(info); uid != os.Getuid() || gid != os.Getgid() || !ok {
+		t.Errorf("Owner = %d, %d, %v, want %d, %d, true", uid, gid, ok, os.Getuid(), os.Getgid())
+	}
+}
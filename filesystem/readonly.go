@@ -0,0 +1,99 @@
+// Copyright 2019 Max Godfrey
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 		http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filesystem
+
+import (
+	"io"
+	"io/fs"
+)
+
+// readOnly is a file system which may only be read.
+type readOnly struct {
+	fsys fs.FS
+}
+
+// ReadOnly returns a FileSystem which reads from fsys, and refuses every operation which would
+// change it with ErrReadOnly. If fsys has Lstat and ReadLink methods, as a FileSystem does, they
+// are used to describe symbolic links; otherwise links are described as the files they refer to.
+func ReadOnly(fsys fs.FS) FileSystem {
+	return readOnly{fsys}
+}
+
+// refuse returns the error of an operation which would change the file system.
+func refuse(op, name string) error {
+	return &fs.PathError{Op: op, Path: name, Err: ErrReadOnly}
+}
+
+func (r readOnly) Open(name string) (fs.File, error) {
+	return r.fsys.Open(name)
+}
+
+func (r readOnly) ReadDir(name string) ([]fs.DirEntry, error) {
+	return fs.ReadDir(r.fsys, name)
+}
+
+func (r readOnly) Stat(name string) (fs.FileInfo, error) {
+	return fs.Stat(r.fsys, name)
+}
+
+func (r readOnly) Lstat(name string) (fs.FileInfo, error) {
+	if lstat, ok := r.fsys.(interface {
+		Lstat(name string) (fs.FileInfo, error)
+	}); ok {
+		return lstat.Lstat(name)
+	}
+	return fs.Stat(r.fsys, name)
+}
+
+func (r readOnly) ReadLink(name string) (string, error) {
+	if readLink, ok := r.fsys.(interface {
+		ReadLink(name string) (string, error)
+	}); ok {
+		return readLink.ReadLink(name)
+	}
+	return "", &fs.PathError{Op: "readlink", Path: name, Err: ErrUnsupported}
+}
+
+func (r readOnly) Create(name string, perm fs.FileMode) (io.WriteCloser, error) {
+	return nil, refuse("create", name)
+}
+
+func (r readOnly) Mkdir(name string, perm fs.FileMode) error {
+	return refuse("mkdir", name)
+}
+
+func (r readOnly) Symlink(oldname, newname string) error {
+	return refuse("symlink", newname)
+}
+
+func (r readOnly) Link(oldname, newname string) error {
+	return refuse("link", newname)
+}
+
+func (r readOnly) Rename(oldname, newname string) error {
+	return refuse("rename", oldname)
+}
+
+func (r readOnly) RemoveAll(name string) error {
+	return refuse("removeall", name)
+}
+
+func (r readOnly) Chmod(name string, mode fs.FileMode) error {
+	return refuse("chmod", name)
+}
+
+func (r readOnly) Chown(name string, uid, gid int) error {
+	return refuse("chown", name)
+}
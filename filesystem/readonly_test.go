@@ -0,0 +1,66 @@
+// Copyright 2019 Max Godfrey
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 		http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filesystem
+
+import (
+	"errors"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+)
+
+func TestReadOnly(t *testing.T) {
+	r := ReadOnly(newTestMemory(t))
+	if err := fstest.TestFS(r, "a.txt", "dir/b.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if info, err := r.Lstat("link"); err != nil || info.Mode()&fs.ModeSymlink == 0 {
+		t.Errorf("Lstat(\"link\") = %v, %v, want a symbolic link", info, err)
+	}
+
+	writes := map[string]error{
+		"Create":    func() error { _, err := r.Create("new.txt", 0644); return err }(),
+		"Mkdir":     r.Mkdir("new", 0755),
+		"Symlink":   r.Symlink("a.txt", "new"),
+		"Link":      r.Link("a.txt", "new"),
+		"Rename":    r.Rename("a.txt", "b.txt"),
+		"RemoveAll": r.RemoveAll("a.txt"),
+		"Chmod":     r.Chmod("a.txt", 0600),
+		"Chown":     r.Chown("a.txt", 0, 0),
+	}
+	for op, err := range writes {
+		if !errors.Is(err, ErrReadOnly) {
+			t.Errorf("%s returned %v, want %v", op, err, ErrReadOnly)
+		}
+	}
+	if _, err := r.Stat("a.txt"); err != nil {
+		t.Errorf("a.txt was changed: %v", err)
+	}
+}
+
+// openFS hides every method of a file system other than Open.
+type openFS struct{ fsys fs.FS }
+
+func (o openFS) Open(name string) (fs.File, error) { return o.fsys.Open(name) }
+
+func TestReadOnlyOpenFS(t *testing.T) {
+	r := ReadOnly(openFS{fstest.MapFS{"file": {Data: []byte("data")}}})
+	if info, err := r.Lstat("file"); err != nil || info.Size() != 4 {
+		t.Errorf("Lstat of a file system without links = %v, %v", info, err)
+	}
+	if _, err := r.ReadLink("file"); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("ReadLink returned %v, want %v", err, ErrUnsupported)
+	}
+}
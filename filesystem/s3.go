@@ -0,0 +1,704 @@
+// Copyright 2019 Max Godfrey
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 		http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filesystem
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// s3ChunkSize is the size of the parts in which objects are uploaded and downloaded, so that
+	// each request finishes within s3Timeout. Objects no larger than this are uploaded at once.
+	s3ChunkSize = 5 << 20
+
+	// s3Timeout is how long each request to an object store may take with the default client.
+	s3Timeout = 2 * time.Minute
+)
+
+// S3Config describes how to reach a bucket of an S3-compatible object store.
+type S3Config struct {
+	Endpoint     string       // The URL of the store, such as "https://s3.us-east-1.amazonaws.com".
+	Region       string       // The region in which the bucket lies, such as "us-east-1".
+	Bucket       string       // The name of the bucket.
+	AccessKey    string       // The access key ID. Requests are not signed without one.
+	SecretKey    string       // The secret access key.
+	SessionToken string       // The session token of temporary credentials, if any.
+	Client       *http.Client // The client which sends requests, or nil for one with a timeout.
+}
+
+// S3 is a bucket of an S3-compatible object store, such as Amazon S3 or MinIO. Objects whose keys
+// contain slashes are presented as files within directories, so that the object "a/b/c.txt" is the
+// file c.txt within the directory b within the directory a. A directory exists while any object
+// lies within it, or while there is an empty object whose key is the directory's name followed by
+// a slash, which is how Mkdir creates directories. Objects have no permissions or symbolic links.
+type S3 struct {
+	config    S3Config
+	endpoint  *url.URL
+	now       func() time.Time // The time at which requests are signed.
+	chunkSize int64            // The size of each part of an object which is sent or received.
+}
+
+// NewS3 returns the file system of a bucket of an S3-compatible object store. The bucket is
+// addressed by path, as in "https://host/bucket/key", which every such store supports.
+func NewS3(config S3Config) (*S3, error) {
+	endpoint, err := url.Parse(config.Endpoint)
+	if err != nil {
+		return nil, err
+	}
+	if endpoint.Scheme == "" || endpoint.Host == "" {
+		return nil, fmt.Errorf("%s is not the URL of an object store", config.Endpoint)
+	}
+	if config.Bucket == "" {
+		return nil, errors.New("no bucket was given")
+	}
+	if config.Region == "" {
+		config.Region = "us-east-1"
+	}
+	if config.Client == nil {
+		config.Client = &http.Client{Timeout: s3Timeout}
+	}
+	return &S3{config: config, endpoint: endpoint, now: time.Now, chunkSize: s3ChunkSize}, nil
+}
+
+// s3Error is an error returned by an object store.
+type s3Error struct {
+	Code    string
+	Message string
+	status  int
+}
+
+func (e *s3Error) Error() string {
+	switch {
+	case e.Message != "":
+		return e.Message
+	case e.Code != "":
+		return e.Code
+	}
+	return fmt.Sprintf("%d %s", e.status, http.StatusText(e.status))
+}
+
+func (e *s3Error) Is(target error) bool {
+	switch target {
+	case fs.ErrNotExist:
+		return e.status == http.StatusNotFound
+	case fs.ErrPermission:
+		return e.status == http.StatusForbidden
+	}
+	return false
+}
+
+// do sends a request concerning an object, or the bucket if key is empty, and returns the response
+// if it succeeded. The caller must close the body of the response.
+func (s *S3) do(method, key string, query url.Values, header http.Header,
+	body []byte) (*http.Response, error) {
+	u := *s.endpoint
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/" + s.config.Bucket + "/" + key
+	u.RawPath = s3Escape(u.Path, false)
+	u.RawQuery = canonicalQuery(query)
+	req, err := http.NewRequest(method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+
+	if s.config.AccessKey != "" {
+		hash := sha256.Sum256(body)
+		if s.config.SessionToken != "" {
+			req.Header.Set("X-Amz-Security-Token", s.config.SessionToken)
+		}
+		signV4(req, hex.EncodeToString(hash[:]), s.config.AccessKey, s.config.SecretKey,
+			s.config.Region, s.now())
+	}
+	resp, err := s.config.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 != 2 {
+		defer resp.Body.Close()
+		s3Err := &s3Error{status: resp.StatusCode}
+		// The responses to HEAD requests have no body describing the error.
+		xml.NewDecoder(io.LimitReader(resp.Body, 64*1024)).Decode(s3Err)
+		return nil, s3Err
+	}
+	return resp, nil
+}
+
+// s3Info describes an object, or a directory of objects.
+type s3Info struct {
+	name    string
+	size    int64
+	modTime time.Time
+	dir     bool
+}
+
+func (i s3Info) Name() string       { return i.name }
+func (i s3Info) Size() int64        { return i.size }
+func (i s3Info) ModTime() time.Time { return i.modTime }
+func (i s3Info) IsDir() bool        { return i.dir }
+func (i s3Info) Sys() interface{}   { return nil }
+
+func (i s3Info) Mode() fs.FileMode {
+	if i.dir {
+		return fs.ModeDir | 0755
+	}
+	return 0644
+}
+
+// s3Listing is the response to a request to list the objects in a bucket.
+type s3Listing struct {
+	IsTruncated           bool
+	NextContinuationToken string
+	Contents              []struct {
+		Key          string
+		Size         int64
+		LastModified time.Time
+	}
+	CommonPrefixes []struct {
+		Prefix string
+	}
+}
+
+// list returns the objects whose keys begin with a prefix, along with the prefixes up to the next
+// slash of any others if delimit is set. At most max objects and prefixes are listed in each
+// request for a page of results, or as many as the store allows if max is zero, and only the first
+// page is listed if first is set.
+func (s *S3) list(prefix string, delimit bool, max int, first bool) ([]fs.FileInfo, []string,
+	error) {
+	query := url.Values{"list-type": {"2"}, "prefix": {prefix}}
+	if delimit {
+		query.Set("delimiter", "/")
+	}
+	if max > 0 {
+		query.Set("max-keys", strconv.Itoa(max))
+	}
+
+	var objects []fs.FileInfo
+	var prefixes []string
+	for {
+		resp, err := s.do("GET", "", query, nil, nil)
+		if err != nil {
+			return nil, nil, err
+		}
+		var listing s3Listing
+		err = xml.NewDecoder(resp.Body).Decode(&listing)
+		resp.Body.Close()
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, object := range listing.Contents {
+			objects = append(objects, s3Info{
+				name:    object.Key,
+				size:    object.Size,
+				modTime: object.LastModified,
+			})
+		}
+		for _, p := range listing.CommonPrefixes {
+			prefixes = append(prefixes, p.Prefix)
+		}
+		if first || !listing.IsTruncated || listing.NextContinuationToken == "" {
+			return objects, prefixes, nil
+		}
+		query.Set("continuation-token", listing.NextContinuationToken)
+	}
+}
+
+// prefix returns the prefix of the keys of the objects within a directory.
+func (s *S3) prefix(op, name string) (string, error) {
+	if !fs.ValidPath(name) {
+		return "", &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	if name == "." {
+		return "", nil
+	}
+	return name + "/", nil
+}
+
+// head returns information about the object with a key.
+func (s *S3) head(key string) (fs.FileInfo, error) {
+	resp, err := s.do("HEAD", key, nil, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+	modTime, _ := http.ParseTime(resp.Header.Get("Last-Modified"))
+	return s3Info{name: path.Base(key), size: resp.ContentLength, modTime: modTime}, nil
+}
+
+func (s *S3) Stat(name string) (fs.FileInfo, error) {
+	prefix, err := s.prefix("stat", name)
+	if err != nil {
+		return nil, err
+	}
+	if name == "." {
+		// The bucket is listed to check that it may be read.
+		if _, _, err := s.list("", false, 1, true); err != nil {
+			return nil, &fs.PathError{Op: "stat", Path: name, Err: err}
+		}
+		return s3Info{name: ".", dir: true}, nil
+	}
+	info, err := s.head(name)
+	if err == nil {
+		return info, nil
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: err}
+	}
+
+	objects, _, err := s.list(prefix, false, 1, true)
+	if err != nil {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: err}
+	}
+	if len(objects) == 0 {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrNotExist}
+	}
+	return s3Info{name: path.Base(name), dir: true}, nil
+}
+
+// Lstat is the same as Stat, as objects cannot be symbolic links.
+func (s *S3) Lstat(name string) (fs.FileInfo, error) {
+	return s.Stat(name)
+}
+
+func (s *S3) ReadDir(name string) ([]fs.DirEntry, error) {
+	prefix, err := s.prefix("readdir", name)
+	if err != nil {
+		return nil, err
+	}
+	objects, prefixes, err := s.list(prefix, true, 0, false)
+	if err != nil {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: err}
+	}
+
+	var entries []fs.DirEntry
+	marker := false
+	for _, p := range prefixes {
+		info := s3Info{name: path.Base(strings.TrimPrefix(p, prefix)), dir: true}
+		entries = append(entries, fs.FileInfoToDirEntry(info))
+	}
+	for _, object := range objects {
+		info := object.(s3Info)
+		if info.name == prefix {
+			// The empty object which marks the directory.
+			marker = true
+			continue
+		}
+		info.name = strings.TrimPrefix(info.name, prefix)
+		entries = append(entries, fs.FileInfoToDirEntry(info))
+	}
+	if len(entries) == 0 && !marker && name != "." {
+		if _, err := s.Stat(name); err != nil {
+			return nil, err
+		}
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: errNotDir}
+	}
+	sortEntries(entries)
+	return entries, nil
+}
+
+func (s *S3) Open(name string) (fs.File, error) {
+	info, err := s.Stat(name)
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: errors.Unwrap(err)}
+	}
+	if info.IsDir() {
+		entries, err := s.ReadDir(name)
+		if err != nil {
+			return nil, err
+		}
+		return &dirFile{info: info, entries: entries}, nil
+	}
+	return &s3File{s: s, key: name, info: info}, nil
+}
+
+// s3File is an object which has been opened for reading. Its contents are read with ranged
+// requests of at most one chunk each, beginning at the offset of each read, so that only as much of
+// the object as is read is downloaded.
+type s3File struct {
+	s      *S3
+	key    string
+	info   fs.FileInfo
+	offset int64
+	body   io.ReadCloser // The contents of the object from offset to end, once requested.
+	end    int64         // The offset at which the body ends.
+}
+
+func (f *s3File) Stat() (fs.FileInfo, error) { return f.info, nil }
+
+func (f *s3File) Read(p []byte) (int, error) {
+	if f.offset >= f.info.Size() {
+		return 0, io.EOF
+	}
+	if f.body == nil {
+		f.end = f.offset + f.s.chunkSize
+		if f.end > f.info.Size() {
+			f.end = f.info.Size()
+		}
+		header := http.Header{"Range": {fmt.Sprintf("bytes=%d-%d", f.offset, f.end-1)}}
+		resp, err := f.s.do("GET", f.key, nil, header, nil)
+		if err != nil {
+			return 0, &fs.PathError{Op: "read", Path: f.key, Err: err}
+		}
+		f.body = resp.Body
+	}
+	n, err := f.body.Read(p)
+	f.offset += int64(n)
+	switch {
+	case f.offset == f.end:
+		// The next chunk is requested by the next read.
+		f.body.Close()
+		f.body = nil
+		err = nil
+	case err == io.EOF:
+		err = io.ErrUnexpectedEOF
+	}
+	return n, err
+}
+
+// ReadAt reads part of the object with a single ranged request.
+func (f *s3File) ReadAt(p []byte, off int64) (int, error) {
+	if off >= f.info.Size() {
+		return 0, io.EOF
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+	header := http.Header{"Range": {fmt.Sprintf("bytes=%d-%d", off, off+int64(len(p))-1)}}
+	resp, err := f.s.do("GET", f.key, nil, header, nil)
+	if err != nil {
+		return 0, &fs.PathError{Op: "read", Path: f.key, Err: err}
+	}
+	defer resp.Body.Close()
+	n, err := io.ReadFull(resp.Body, p)
+	if err == io.ErrUnexpectedEOF && off+int64(n) == f.info.Size() {
+		err = io.EOF
+	}
+	return n, err
+}
+
+func (f *s3File) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekCurrent:
+		offset += f.offset
+	case io.SeekEnd:
+		offset += f.info.Size()
+	}
+	if offset < 0 {
+		return 0, &fs.PathError{Op: "seek", Path: f.key, Err: fs.ErrInvalid}
+	}
+	if offset != f.offset && f.body != nil {
+		f.body.Close()
+		f.body = nil
+	}
+	f.offset = offset
+	return offset, nil
+}
+
+func (f *s3File) Close() error {
+	if f.body != nil {
+		return f.body.Close()
+	}
+	return nil
+}
+
+// exists returns an error if a file or directory already exists with a name.
+func (s *S3) exists(op, name string) error {
+	if _, err := s.Stat(name); err == nil {
+		return &fs.PathError{Op: op, Path: name, Err: fs.ErrExist}
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// Create creates an object. Objects of up to one chunk are held in memory until the writer is
+// closed, when they are uploaded, while larger objects are uploaded in parts as they are written.
+func (s *S3) Create(name string, perm fs.FileMode) (io.WriteCloser, error) {
+	if _, err := s.prefix("create", name); err != nil || name == "." {
+		return nil, &fs.PathError{Op: "create", Path: name, Err: fs.ErrInvalid}
+	}
+	if err := s.exists("create", name); err != nil {
+		return nil, err
+	}
+	return &s3Writer{s: s, key: name}, nil
+}
+
+// s3Writer uploads the contents of an object. The contents are collected until there is a chunk of
+// them, and each chunk is then uploaded as a part of a multipart upload, which is completed when
+// the writer is closed.
+type s3Writer struct {
+	s        *S3
+	key      string
+	buf      bytes.Buffer
+	uploadID string   // The ID of the multipart upload, once it has begun.
+	etags    []string // The ETag of each part which has been uploaded.
+	err      error    // The error which ended the upload, if any.
+	closed   bool
+}
+
+// s3Part is a part of an object uploaded by a multipart upload.
+type s3Part struct {
+	PartNumber int
+	ETag       string
+}
+
+func (w *s3Writer) Write(p []byte) (int, error) {
+	if w.closed {
+		return 0, fs.ErrClosed
+	}
+	if w.err != nil {
+		return 0, w.err
+	}
+	w.buf.Write(p)
+	for int64(w.buf.Len()) >= w.s.chunkSize {
+		if err := w.uploadPart(w.buf.Next(int(w.s.chunkSize))); err != nil {
+			w.abort(err)
+			return 0, w.err
+		}
+	}
+	return len(p), nil
+}
+
+// uploadPart uploads the next part of the object, beginning the multipart upload if it is the
+// first.
+func (w *s3Writer) uploadPart(part []byte) error {
+	if w.uploadID == "" {
+		resp, err := w.s.do("POST", w.key, url.Values{"uploads": {""}}, nil, nil)
+		if err != nil {
+			return err
+		}
+		var result struct{ UploadId string }
+		err = xml.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+		if err != nil {
+			return err
+		}
+		if result.UploadId == "" {
+			return errors.New("the object store did not begin the upload")
+		}
+		w.uploadID = result.UploadId
+	}
+
+	query := url.Values{"partNumber": {strconv.Itoa(len(w.etags) + 1)}, "uploadId": {w.uploadID}}
+	resp, err := w.s.do("PUT", w.key, query, nil, part)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	w.etags = append(w.etags, resp.Header.Get("ETag"))
+	return nil
+}
+
+// complete completes the multipart upload, joining the parts which were uploaded into the object.
+func (w *s3Writer) complete() error {
+	parts := make([]s3Part, len(w.etags))
+	for i, etag := range w.etags {
+		parts[i] = s3Part{PartNumber: i + 1, ETag: etag}
+	}
+	body, err := xml.Marshal(struct {
+		XMLName xml.Name `xml:"CompleteMultipartUpload"`
+		Parts   []s3Part `xml:"Part"`
+	}{Parts: parts})
+	if err != nil {
+		return err
+	}
+	resp, err := w.s.do("POST", w.key, url.Values{"uploadId": {w.uploadID}}, nil, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	// The upload may fail after the object store has begun its response, which then describes
+	// the error.
+	var result struct {
+		XMLName xml.Name
+		Code    string
+		Message string
+	}
+	xml.NewDecoder(io.LimitReader(resp.Body, 64*1024)).Decode(&result)
+	if result.XMLName.Local == "Error" {
+		return &s3Error{Code: result.Code, Message: result.Message, status: resp.StatusCode}
+	}
+	return nil
+}
+
+// abort ends the upload with an error, abandoning any parts which were uploaded.
+func (w *s3Writer) abort(err error) {
+	w.err = &fs.PathError{Op: "write", Path: w.key, Err: err}
+	if w.uploadID == "" {
+		return
+	}
+	resp, err := w.s.do("DELETE", w.key, url.Values{"uploadId": {w.uploadID}}, nil, nil)
+	if err == nil {
+		resp.Body.Close()
+	}
+}
+
+func (w *s3Writer) Close() error {
+	if w.closed {
+		return fs.ErrClosed
+	}
+	w.closed = true
+	if w.err != nil {
+		return w.err
+	}
+	if w.uploadID == "" {
+		resp, err := w.s.do("PUT", w.key, nil, nil, w.buf.Bytes())
+		if err != nil {
+			return &fs.PathError{Op: "write", Path: w.key, Err: err}
+		}
+		return resp.Body.Close()
+	}
+
+	var err error
+	if w.buf.Len() > 0 {
+		// Only the last part may be smaller than a chunk.
+		err = w.uploadPart(w.buf.Bytes())
+	}
+	if err == nil {
+		err = w.complete()
+	}
+	if err != nil {
+		w.abort(err)
+		return w.err
+	}
+	return nil
+}
+
+// Mkdir creates a directory by uploading an empty object whose key is its name followed by a slash.
+func (s *S3) Mkdir(name string, perm fs.FileMode) error {
+	prefix, err := s.prefix("mkdir", name)
+	if err != nil || name == "." {
+		return &fs.PathError{Op: "mkdir", Path: name, Err: fs.ErrInvalid}
+	}
+	if err := s.exists("mkdir", name); err != nil {
+		return err
+	}
+	resp, err := s.do("PUT", prefix, nil, nil, nil)
+	if err != nil {
+		return &fs.PathError{Op: "mkdir", Path: name, Err: err}
+	}
+	return resp.Body.Close()
+}
+
+// Symlink returns ErrUnsupported, as objects cannot be symbolic links.
+func (s *S3) Symlink(oldname, newname string) error {
+	return &fs.PathError{Op: "symlink", Path: newname, Err: ErrUnsupported}
+}
+
+// Link returns ErrUnsupported, as objects cannot share their contents.
+func (s *S3) Link(oldname, newname string) error {
+	return &fs.PathError{Op: "link", Path: newname, Err: ErrUnsupported}
+}
+
+// ReadLink returns ErrUnsupported, as objects cannot be symbolic links.
+func (s *S3) ReadLink(name string) (string, error) {
+	return "", &fs.PathError{Op: "readlink", Path: name, Err: ErrUnsupported}
+}
+
+// keys returns the keys of the object with a name, or of every object within the directory with
+// the name.
+func (s *S3) keys(op, name string) ([]string, error) {
+	prefix, err := s.prefix(op, name)
+	if err != nil {
+		return nil, err
+	}
+	info, err := s.Stat(name)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return []string{name}, nil
+	}
+	objects, _, err := s.list(prefix, false, 0, false)
+	if err != nil {
+		return nil, &fs.PathError{Op: op, Path: name, Err: err}
+	}
+	keys := make([]string, len(objects))
+	for i, object := range objects {
+		keys[i] = object.Name()
+	}
+	return keys, nil
+}
+
+// Rename renames an object, or every object within a directory, by copying each object to its new
+// key and then deleting it.
+func (s *S3) Rename(oldname, newname string) error {
+	if _, err := s.prefix("rename", newname); err != nil || oldname == "." || newname == "." ||
+		strings.HasPrefix(newname+"/", oldname+"/") {
+		return &fs.PathError{Op: "rename", Path: oldname, Err: fs.ErrInvalid}
+	}
+	keys, err := s.keys("rename", oldname)
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		newKey := newname + strings.TrimPrefix(key, oldname)
+		source := http.Header{"X-Amz-Copy-Source": {s3Escape("/"+s.config.Bucket+"/"+key, false)}}
+		resp, err := s.do("PUT", newKey, nil, source, nil)
+		if err != nil {
+			return &fs.PathError{Op: "rename", Path: key, Err: err}
+		}
+		resp.Body.Close()
+		if resp, err = s.do("DELETE", key, nil, nil, nil); err != nil {
+			return &fs.PathError{Op: "rename", Path: key, Err: err}
+		}
+		resp.Body.Close()
+	}
+	return nil
+}
+
+// RemoveAll deletes an object, or every object within a directory.
+func (s *S3) RemoveAll(name string) error {
+	if name == "." {
+		return &fs.PathError{Op: "removeall", Path: name, Err: fs.ErrInvalid}
+	}
+	keys, err := s.keys("removeall", name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		resp, err := s.do("DELETE", key, nil, nil, nil)
+		if err != nil {
+			return &fs.PathError{Op: "removeall", Path: key, Err: err}
+		}
+		resp.Body.Close()
+	}
+	return nil
+}
+
+// Chmod returns ErrUnsupported, as objects have no permissions.
+func (s *S3) Chmod(name string, mode fs.FileMode) error {
+	return &fs.PathError{Op: "chmod", Path: name, Err: ErrUnsupported}
+}
+
+// Chown returns ErrUnsupported, as objects have no owners.
+func (s *S3) Chown(name string, uid, gid int) error {
+	return &fs.PathError{Op: "chown", Path: name, Err: ErrUnsupported}
+}
@@ -0,0 +1,414 @@
+// Copyright 2019 Max Godfrey
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 		http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filesystem
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+// The credentials and bucket of the fake object store.
+const (
+	fakeAccessKey = "AKIDEXAMPLE"
+	fakeSecretKey = "secret"
+	fakeBucket    = "bucket"
+	fakeRegion    = "eu-west-1"
+)
+
+// fakeS3 is an in-process stand-in for an S3-compatible object store holding a single bucket. It
+// checks the signature of every request, and lists at most pageSize objects at a time so that
+// listings span several pages.
+type fakeS3 struct {
+	pageSize int
+
+	mu      sync.Mutex
+	objects map[string][]byte
+	modTime time.Time
+	ranges  []string            // The Range header of each request for an object.
+	uploads map[string][][]byte // The parts uploaded by each multipart upload, by its ID.
+	started int                 // The number of multipart uploads which have begun.
+	refuse  bool                // Whether parts of multipart uploads are refused.
+}
+
+// fail replies to a request with an error in the form in which S3 describes it.
+func fail(w http.ResponseWriter, status int, code string) {
+	w.WriteHeader(status)
+	xml.NewEncoder(w).Encode(s3Error{Code: code, Message: code})
+}
+
+// authorized reports whether a request was signed with the fake store's credentials.
+func authorized(r *http.Request, body []byte) bool {
+	auth := strings.TrimPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 ")
+	fields := make(map[string]string)
+	for _, field := range strings.Split(auth, ", ") {
+		if i := strings.Index(field, "="); i >= 0 {
+			fields[field[:i]] = field[i+1:]
+		}
+	}
+	hash := sha256.Sum256(body)
+	signedHeaders := strings.Split(fields["SignedHeaders"], ";")
+	return fields["Credential"] == fakeAccessKey+"/"+credentialScope(r, fakeRegion) &&
+		r.Header.Get("X-Amz-Content-Sha256") == hex.EncodeToString(hash[:]) &&
+		fields["Signature"] == signatureV4(r, signedHeaders, fakeSecretKey, fakeRegion)
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := ioutil.ReadAll(r.Body)
+	if !authorized(r, body) {
+		fail(w, http.StatusForbidden, "SignatureDoesNotMatch")
+		return
+	}
+	parts := strings.SplitN(strings.TrimPrefix(r.URL.Path, "/"), "/", 2)
+	if parts[0] != fakeBucket || len(parts) < 2 {
+		fail(w, http.StatusNotFound, "NoSuchBucket")
+		return
+	}
+	key := parts[1]
+	query := r.URL.Query()
+	_, uploads := query["uploads"]
+	id := query.Get("uploadId")
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch {
+	case uploads || id != "":
+		f.multipart(w, r, key, body)
+	case key == "" && r.Method == "GET":
+		f.list(w, r)
+	case r.Method == "GET" || r.Method == "HEAD":
+		data, ok := f.objects[key]
+		if !ok {
+			fail(w, http.StatusNotFound, "NoSuchKey")
+			return
+		}
+		if r.Method == "GET" {
+			f.ranges = append(f.ranges, r.Header.Get("Range"))
+		}
+		http.ServeContent(w, r, key, f.modTime, bytes.NewReader(data))
+	case r.Method == "PUT":
+		if source := r.Header.Get("X-Amz-Copy-Source"); source != "" {
+			source, _ = url.PathUnescape(source)
+			data, ok := f.objects[strings.TrimPrefix(source, "/"+fakeBucket+"/")]
+			if !ok {
+				fail(w, http.StatusNotFound, "NoSuchKey")
+				return
+			}
+			body = data
+		}
+		f.objects[key] = body
+	case r.Method == "DELETE":
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		fail(w, http.StatusMethodNotAllowed, "MethodNotAllowed")
+	}
+}
+
+// multipart begins, adds a part to, completes or aborts a multipart upload.
+func (f *fakeS3) multipart(w http.ResponseWriter, r *http.Request, key string, body []byte) {
+	query := r.URL.Query()
+	id := query.Get("uploadId")
+	if _, ok := f.uploads[id]; !ok && id != "" {
+		fail(w, http.StatusNotFound, "NoSuchUpload")
+		return
+	}
+	switch r.Method {
+	case "POST":
+		if id == "" {
+			f.started++
+			id = strconv.Itoa(f.started)
+			f.uploads[id] = nil
+			xml.NewEncoder(w).Encode(struct {
+				XMLName  xml.Name `xml:"InitiateMultipartUploadResult"`
+				UploadId string
+			}{UploadId: id})
+			return
+		}
+		var complete struct{ Part []s3Part }
+		if err := xml.Unmarshal(body, &complete); err != nil {
+			fail(w, http.StatusBadRequest, "MalformedXML")
+			return
+		}
+		var data []byte
+		for i, part := range complete.Part {
+			if part.PartNumber != i+1 || part.ETag != strconv.Quote(strconv.Itoa(i+1)) ||
+				i >= len(f.uploads[id]) {
+				fail(w, http.StatusBadRequest, "InvalidPart")
+				return
+			}
+			data = append(data, f.uploads[id][i]...)
+		}
+		f.objects[key] = data
+		delete(f.uploads, id)
+	case "PUT":
+		n, err := strconv.Atoi(query.Get("partNumber"))
+		if f.refuse {
+			fail(w, http.StatusInternalServerError, "InternalError")
+			return
+		}
+		if err != nil || n != len(f.uploads[id])+1 {
+			fail(w, http.StatusBadRequest, "InvalidPart")
+			return
+		}
+		f.uploads[id] = append(f.uploads[id], body)
+		w.Header().Set("ETag", strconv.Quote(strconv.Itoa(n)))
+	case "DELETE":
+		delete(f.uploads, id)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// list replies with a page of the objects in the bucket whose keys begin with the requested prefix.
+func (f *fakeS3) list(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	prefix, delimiter := query.Get("prefix"), query.Get("delimiter")
+	max, err := strconv.Atoi(query.Get("max-keys"))
+	if err != nil || max > f.pageSize {
+		max = f.pageSize
+	}
+
+	var keys []string
+	for key := range f.objects {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var listing s3Listing
+	seen := make(map[string]bool)
+	for _, key := range keys {
+		if !strings.HasPrefix(key, prefix) || key <= query.Get("continuation-token") {
+			continue
+		}
+		if len(listing.Contents)+len(listing.CommonPrefixes) == max {
+			listing.IsTruncated = true
+			break
+		}
+		listing.NextContinuationToken = key
+		rest := strings.TrimPrefix(key, prefix)
+		if i := strings.Index(rest, delimiter); delimiter != "" && i >= 0 {
+			p := prefix + rest[:i+1]
+			if !seen[p] {
+				seen[p] = true
+				listing.CommonPrefixes = append(listing.CommonPrefixes, struct{ Prefix string }{p})
+			}
+			// The next page begins after every key with the prefix.
+			listing.NextContinuationToken = p + "~"
+			continue
+		}
+		listing.Contents = append(listing.Contents, struct {
+			Key          string
+			Size         int64
+			LastModified time.Time
+		}{key, int64(len(f.objects[key])), f.modTime})
+	}
+	xml.NewEncoder(w).Encode(struct {
+		XMLName xml.Name `xml:"ListBucketResult"`
+		s3Listing
+	}{s3Listing: listing})
+}
+
+// newTestS3 returns the file system of a fake object store holding a few objects.
+func newTestS3(t *testing.T) (*S3, *fakeS3) {
+	fake := &fakeS3{
+		pageSize: 2,
+		objects: map[string][]byte{
+			"a.txt":           []byte("a"),
+			"dir/b.txt":       []byte("bb"),
+			"dir/sub/c.txt":   []byte("ccc"),
+			"dir/sub/d/e.txt": []byte("eeeee"),
+			"empty/":          nil,
+		},
+		modTime: time.Date(2020, time.January, 2, 3, 4, 5, 0, time.UTC),
+		uploads: make(map[string][][]byte),
+	}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	s, err := NewS3(S3Config{
+		Endpoint:  server.URL,
+		Region:    fakeRegion,
+		Bucket:    fakeBucket,
+		AccessKey: fakeAccessKey,
+		SecretKey: fakeSecretKey,
+		Client:    server.Client(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s, fake
+}
+
+func TestS3(t *testing.T) {
+	s, _ := newTestS3(t)
+	err := fstest.TestFS(s, "a.txt", "dir/b.txt", "dir/sub/c.txt", "dir/sub/d/e.txt", "empty")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Stat("missing"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Stat of a missing object returned %v, want %v", err, fs.ErrNotExist)
+	}
+	if _, err := s.ReadDir("a.txt"); err == nil {
+		t.Error("ReadDir of an object did not fail")
+	}
+
+	s.config.SecretKey = "incorrect"
+	if _, err := s.Stat("a.txt"); !errors.Is(err, fs.ErrPermission) {
+		t.Errorf("Stat with incorrect credentials returned %v, want %v", err, fs.ErrPermission)
+	}
+}
+
+func TestS3RangedReads(t *testing.T) {
+	s, fake := newTestS3(t)
+	s.chunkSize = 6
+	fake.objects["large"] = bytes.Repeat([]byte("0123456789"), 1000)
+
+	f, err := s.Open("large")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	buf := make([]byte, 8)
+	if _, err := io.ReadFull(f, buf); err != nil || string(buf) != "01234567" {
+		t.Errorf("Read = %q, %v", buf, err)
+	}
+	buf = buf[:4]
+	if _, err := f.(io.ReaderAt).ReadAt(buf, 9996); err != nil || string(buf) != "6789" {
+		t.Errorf("ReadAt = %q, %v", buf, err)
+	}
+	want := []string{"bytes=0-5", "bytes=6-11", "bytes=9996-9999"}
+	if strings.Join(fake.ranges, " ") != strings.Join(want, " ") {
+		t.Errorf("the object was requested with ranges %q, want %q", fake.ranges, want)
+	}
+}
+
+func TestS3Write(t *testing.T) {
+	s, fake := newTestS3(t)
+
+	w, err := s.Create("dir/new file.txt", 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.WriteString(w, "new")
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if string(fake.objects["dir/new file.txt"]) != "new" {
+		t.Errorf("the uploaded object holds %q", fake.objects["dir/new file.txt"])
+	}
+	if _, err := s.Create("a.txt", 0644); !errors.Is(err, fs.ErrExist) {
+		t.Errorf("Create of an existing object returned %v, want %v", err, fs.ErrExist)
+	}
+	if err := s.Mkdir("made", 0755); err != nil {
+		t.Fatal(err)
+	}
+	if info, err := s.Stat("made"); err != nil || !info.IsDir() {
+		t.Errorf("Stat of a new directory = %v, %v", info, err)
+	}
+
+	if err := s.Rename("dir", "moved"); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := fs.ReadFile(s, "moved/sub/d/e.txt"); err != nil || string(data) != "eeeee" {
+		t.Errorf("ReadFile of an object in a renamed directory = %q, %v", data, err)
+	}
+	if _, err := s.Stat("dir"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("a renamed directory remains: %v", err)
+	}
+
+	if err := s.RemoveAll("moved"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.RemoveAll("a.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.RemoveAll("missing"); err != nil {
+		t.Errorf("RemoveAll of a missing object returned %v", err)
+	}
+	entries, err := s.ReadDir(".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[0].Name() != "empty" || entries[1].Name() != "made" {
+		t.Errorf("ReadDir(\".\") = %v after removing objects, want empty and made", entries)
+	}
+	if err := s.Symlink("a.txt", "link"); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("Symlink returned %v, want %v", err, ErrUnsupported)
+	}
+	if err := s.Link("a.txt", "hard"); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("Link returned %v, want %v", err, ErrUnsupported)
+	}
+	if err := s.Chmod("a.txt", 0644); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("Chmod returned %v, want %v", err, ErrUnsupported)
+	}
+	if err := s.Chown("a.txt", 0, 0); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("Chown returned %v, want %v", err, ErrUnsupported)
+	}
+}
+
+func TestS3MultipartWrite(t *testing.T) {
+	s, fake := newTestS3(t)
+	s.chunkSize = 4
+
+	w, err := s.Create("large", 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, piece := range []string{"012", "3456789", "ab"} {
+		if _, err := io.WriteString(w, piece); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(fake.uploads) != 1 {
+		t.Error("an object larger than a chunk was not uploaded as it was written")
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if string(fake.objects["large"]) != "0123456789ab" {
+		t.Errorf("the uploaded object holds %q", fake.objects["large"])
+	}
+
+	// An upload which fails is abandoned.
+	w, err = s.Create("failed", 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.WriteString(w, "0123")
+	fake.refuse = true
+	if _, err := io.WriteString(w, "4567"); err == nil {
+		t.Error("Write of a part which was refused did not fail")
+	}
+	if err := w.Close(); err == nil {
+		t.Error("Close of a failed upload did not fail")
+	}
+	if _, ok := fake.objects["failed"]; ok || len(fake.uploads) != 0 {
+		t.Errorf("a failed upload was not abandoned, leaving %d uploads", len(fake.uploads))
+	}
+}
@@ -0,0 +1,338 @@
+// Copyright 2019 Max Godfrey
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 		http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filesystem
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+)
+
+// SFTP is the file system of a remote host, reached over SFTP. Its root is the root of the remote
+// host's file system.
+type SFTP struct {
+	client *sftp.Client
+	conn   *ssh.Client   // The connection over which the client was started, if it was dialled.
+	lost   chan struct{} // Closed once the connection to the remote host has been lost.
+}
+
+// NewSFTP returns the file system of an SFTP client. Closing the file system closes the client.
+func NewSFTP(client *sftp.Client) *SFTP {
+	return newSFTP(client, nil)
+}
+
+// newSFTP returns the file system of an SFTP client started over a connection, which is nil if the
+// client was not dialled.
+func newSFTP(client *sftp.Client, conn *ssh.Client) *SFTP {
+	s := &SFTP{client: client, conn: conn, lost: make(chan struct{})}
+	go func() {
+		client.Wait()
+		close(s.lost)
+	}()
+	return s
+}
+
+// DialSFTP connects to an SSH server at an address given as "host:port", and starts an SFTP
+// client over the connection.
+func DialSFTP(addr string, config *ssh.ClientConfig) (*SFTP, error) {
+	conn, err := ssh.Dial("tcp", addr, config)
+	if err != nil {
+		return nil, err
+	}
+	client, err := sftp.NewClient(conn)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return newSFTP(client, conn), nil
+}
+
+// Lost reports whether the connection to the remote host has been lost or closed, after which
+// every operation fails.
+func (s *SFTP) Lost() bool {
+	select {
+	case <-s.lost:
+		return true
+	default:
+		return false
+	}
+}
+
+// Close closes the SFTP client, along with the connection over which it was started.
+func (s *SFTP) Close() error {
+	err := s.client.Close()
+	if s.conn != nil {
+		if connErr := s.conn.Close(); err == nil {
+			err = connErr
+		}
+	}
+	return err
+}
+
+// Getwd returns the name of the directory in which the SFTP server started, which is usually the
+// home directory of the user who connected.
+func (s *SFTP) Getwd() (string, error) {
+	dir, err := s.client.Getwd()
+	if err != nil {
+		return "", err
+	}
+	if dir = path.Clean(dir); dir == "/" {
+		return ".", nil
+	}
+	return dir[1:], nil
+}
+
+// path returns the path of a file on the remote host.
+func (s *SFTP) path(op, name string) (string, error) {
+	if !fs.ValidPath(name) {
+		return "", &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	if name == "." {
+		return "/", nil
+	}
+	return "/" + name, nil
+}
+
+// wrap describes the error of an operation on a file, which the SFTP client reports without
+// naming the file.
+func wrap(op, name string, err error) error {
+	if err == nil {
+		return nil
+	}
+	var pathErr *fs.PathError
+	if errors.As(err, &pathErr) {
+		return err
+	}
+	return &fs.PathError{Op: op, Path: name, Err: err}
+}
+
+func (s *SFTP) Open(name string) (fs.File, error) {
+	p, err := s.path("open", name)
+	if err != nil {
+		return nil, err
+	}
+	info, err := s.client.Stat(p)
+	if err != nil {
+		return nil, wrap("open", name, err)
+	}
+	if info.IsDir() {
+		entries, err := s.ReadDir(name)
+		if err != nil {
+			return nil, err
+		}
+		return &dirFile{info: withOwner(info), entries: entries}, nil
+	}
+	f, err := s.client.Open(p)
+	return f, wrap("open", name, err)
+}
+
+func (s *SFTP) ReadDir(name string) ([]fs.DirEntry, error) {
+	p, err := s.path("readdir", name)
+	if err != nil {
+		return nil, err
+	}
+	contents, err := s.client.ReadDir(p)
+	if err != nil {
+		return nil, wrap("readdir", name, err)
+	}
+	entries := make([]fs.DirEntry, len(contents))
+	for i, info := range contents {
+		entries[i] = fs.FileInfoToDirEntry(withOwner(info))
+	}
+	sortEntries(entries)
+	return entries, nil
+}
+
+func (s *SFTP) Stat(name string) (fs.FileInfo, error) {
+	p, err := s.path("stat", name)
+	if err != nil {
+		return nil, err
+	}
+	info, err := s.client.Stat(p)
+	if err != nil {
+		return nil, wrap("stat", name, err)
+	}
+	return withOwner(info), nil
+}
+
+func (s *SFTP) Lstat(name string) (fs.FileInfo, error) {
+	p, err := s.path("lstat", name)
+	if err != nil {
+		return nil, err
+	}
+	info, err := s.client.Lstat(p)
+	if err != nil {
+		return nil, wrap("lstat", name, err)
+	}
+	return withOwner(info), nil
+}
+
+func (s *SFTP) ReadLink(name string) (string, error) {
+	p, err := s.path("readlink", name)
+	if err != nil {
+		return "", err
+	}
+	target, err := s.client.ReadLink(p)
+	return target, wrap("readlink", name, err)
+}
+
+func (s *SFTP) Create(name string, perm fs.FileMode) (io.WriteCloser, error) {
+	p, err := s.path("create", name)
+	if err != nil {
+		return nil, err
+	}
+	f, err := s.client.OpenFile(p, os.O_WRONLY|os.O_CREATE|os.O_EXCL)
+	if err != nil {
+		return nil, wrap("create", name, err)
+	}
+	if err := f.Chmod(perm); err != nil {
+		f.Close()
+		return nil, wrap("create", name, err)
+	}
+	return f, nil
+}
+
+func (s *SFTP) Mkdir(name string, perm fs.FileMode) error {
+	p, err := s.path("mkdir", name)
+	if err != nil {
+		return err
+	}
+	if err := s.client.Mkdir(p); err != nil {
+		return wrap("mkdir", name, err)
+	}
+	return wrap("mkdir", name, s.client.Chmod(p, perm))
+}
+
+// Symlink creates a symbolic link. Its destination, oldname, is written to the link as it is given.
+func (s *SFTP) Symlink(oldname, newname string) error {
+	p, err := s.path("symlink", newname)
+	if err != nil {
+		return err
+	}
+	return wrap("symlink", newname, s.client.Symlink(oldname, p))
+}
+
+// Link creates a hard link, which requires the server to support the hardlink extension.
+func (s *SFTP) Link(oldname, newname string) error {
+	oldPath, err := s.path("link", oldname)
+	if err != nil {
+		return err
+	}
+	newPath, err := s.path("link", newname)
+	if err != nil {
+		return err
+	}
+	return wrap("link", newname, s.client.Link(oldPath, newPath))
+}
+
+// Rename renames a file or directory. Servers without the posix-rename extension cannot replace a
+// file at newname.
+func (s *SFTP) Rename(oldname, newname string) error {
+	oldPath, err := s.path("rename", oldname)
+	if err != nil {
+		return err
+	}
+	newPath, err := s.path("rename", newname)
+	if err != nil {
+		return err
+	}
+	if _, ok := s.client.HasExtension("posix-rename@openssh.com"); ok {
+		return wrap("rename", oldname, s.client.PosixRename(oldPath, newPath))
+	}
+	return wrap("rename", oldname, s.client.Rename(oldPath, newPath))
+}
+
+// RemoveAll removes a file or directory. Symbolic links are removed rather than what they refer to.
+func (s *SFTP) RemoveAll(name string) error {
+	p, err := s.path("removeall", name)
+	if err != nil {
+		return err
+	}
+	info, err := s.client.Lstat(p)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return wrap("removeall", name, err)
+	}
+	if !info.IsDir() {
+		return wrap("removeall", name, s.client.Remove(p))
+	}
+
+	entries, err := s.ReadDir(name)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if err := s.RemoveAll(path.Join(name, entry.Name())); err != nil {
+			return err
+		}
+	}
+	return wrap("removeall", name, s.client.RemoveDirectory(p))
+}
+
+func (s *SFTP) Chmod(name string, mode fs.FileMode) error {
+	p, err := s.path("chmod", name)
+	if err != nil {
+		return err
+	}
+	return wrap("chmod", name, s.client.Chmod(p, mode))
+}
+
+// Chown changes the owner of a file or directory. As SFTP always sets both the user and the group,
+// an ID of -1 is replaced by the file's current one.
+func (s *SFTP) Chown(name string, uid, gid int) error {
+	p, err := s.path("chown", name)
+	if err != nil {
+		return err
+	}
+	if uid < 0 || gid < 0 {
+		info, err := s.client.Stat(p)
+		if err != nil {
+			return wrap("chown", name, err)
+		}
+		currentUID, currentGID, _ := Owner(withOwner(info))
+		if uid < 0 {
+			uid = currentUID
+		}
+		if gid < 0 {
+			gid = currentGID
+		}
+	}
+	return wrap("chown", name, s.client.Chown(p, uid, gid))
+}
+
+// sftpInfo is information about a file on an SFTP server, through which Owner finds the user and
+// group which own the file.
+type sftpInfo struct {
+	fs.FileInfo
+	stat *sftp.FileStat
+}
+
+func (i sftpInfo) Uid() uint32 { return i.stat.UID }
+func (i sftpInfo) Gid() uint32 { return i.stat.GID }
+
+// withOwner returns information about a file on an SFTP server which records the user and group
+// which own the file, if the server described them.
+func withOwner(info fs.FileInfo) fs.FileInfo {
+	if stat, ok := info.Sys().(*sftp.FileStat); ok {
+		return sftpInfo{FileInfo: info, stat: stat}
+	}
+	return info
+}
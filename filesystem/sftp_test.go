@@ -0,0 +1,368 @@
+// Copyright 2019 Max Godfrey
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 		http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filesystem
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"io"
+	"io/fs"
+	"net"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+)
+
+// memoryHandlers serve a Memory file system over SFTP, standing in for the file system of a remote
+// host.
+type memoryHandlers struct {
+	m *Memory
+}
+
+// memoryName returns the name in a Memory file system of an absolute path requested over SFTP.
+func memoryName(p string) string {
+	if name := strings.TrimPrefix(path.Clean("/"+p), "/"); name != "" {
+		return name
+	}
+	return "."
+}
+
+func (h memoryHandlers) Fileread(r *sftp.Request) (io.ReaderAt, error) {
+	data, err := fs.ReadFile(h.m, memoryName(r.Filepath))
+	if err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(data), nil
+}
+
+func (h memoryHandlers) Filewrite(r *sftp.Request) (io.WriterAt, error) {
+	name := memoryName(r.Filepath)
+	if w, err := h.m.Create(name, 0644); err == nil {
+		w.Close()
+	} else if r.Pflags().Excl || !errors.Is(err, fs.ErrExist) {
+		return nil, err
+	}
+	return &memoryWriterAt{m: h.m, name: name}, nil
+}
+
+func (h memoryHandlers) Filecmd(r *sftp.Request) error {
+	name := memoryName(r.Filepath)
+	switch r.Method {
+	case "Setstat":
+		if r.AttrFlags().UidGid {
+			if err := h.m.Chown(name, int(r.Attributes().UID), int(r.Attributes().GID)); err != nil {
+				return err
+			}
+		}
+		if r.AttrFlags().Permissions {
+			return h.m.Chmod(name, r.Attributes().FileMode()&ModeBits)
+		}
+		return nil
+	case "Rename":
+		if _, err := h.m.Lstat(memoryName(r.Target)); err == nil {
+			return os.ErrExist
+		}
+		return h.m.Rename(name, memoryName(r.Target))
+	case "Rmdir", "Remove":
+		if _, err := h.m.Lstat(name); err != nil {
+			return err
+		}
+		return h.m.RemoveAll(name)
+	case "Mkdir":
+		return h.m.Mkdir(name, 0755)
+	case "Symlink":
+		return h.m.Symlink(r.Filepath, memoryName(r.Target))
+	case "Link":
+		return h.m.Link(memoryName(r.Filepath), memoryName(r.Target))
+	}
+	return errors.New("unsupported")
+}
+
+func (h memoryHandlers) PosixRename(r *sftp.Request) error {
+	return h.m.Rename(memoryName(r.Filepath), memoryName(r.Target))
+}
+
+func (h memoryHandlers) Filelist(r *sftp.Request) (sftp.ListerAt, error) {
+	name := memoryName(r.Filepath)
+	switch r.Method {
+	case "List":
+		entries, err := h.m.ReadDir(name)
+		if err != nil {
+			return nil, err
+		}
+		infos := make(listerAt, len(entries))
+		for i, entry := range entries {
+			if infos[i], err = entry.Info(); err != nil {
+				return nil, err
+			}
+		}
+		return infos, nil
+	case "Stat":
+		info, err := h.m.Stat(name)
+		return listerAt{info}, err
+	}
+	return nil, errors.New("unsupported")
+}
+
+func (h memoryHandlers) Lstat(r *sftp.Request) (sftp.ListerAt, error) {
+	info, err := h.m.Lstat(memoryName(r.Filepath))
+	return listerAt{info}, err
+}
+
+func (h memoryHandlers) Readlink(p string) (string, error) {
+	return h.m.ReadLink(memoryName(p))
+}
+
+// listerAt lists information about files in reply to an SFTP request.
+type listerAt []os.FileInfo
+
+func (l listerAt) ListAt(infos []os.FileInfo, offset int64) (int, error) {
+	if offset >= int64(len(l)) {
+		return 0, io.EOF
+	}
+	n := copy(infos, l[offset:])
+	if n < len(infos) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// memoryWriterAt collects the contents of a file written over SFTP, which may arrive out of order,
+// and writes them to the file once it is closed.
+type memoryWriterAt struct {
+	m    *Memory
+	name string
+
+	mu   sync.Mutex
+	data []byte
+}
+
+func (w *memoryWriterAt) WriteAt(p []byte, off int64) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if end := int(off) + len(p); end > len(w.data) {
+		w.data = append(w.data, make([]byte, end-len(w.data))...)
+	}
+	copy(w.data[off:], p)
+	return len(p), nil
+}
+
+func (w *memoryWriterAt) Close() error {
+	info, err := w.m.Stat(w.name)
+	if err != nil {
+		return err
+	}
+	return w.m.WriteFile(w.name, w.data, info.Mode())
+}
+
+// serveSFTP starts an SSH server on the loopback interface which serves an in-memory file system
+// over SFTP to the user "user" with the password "password", and returns its address along with
+// the configuration with which a client connects to it.
+func serveSFTP(t *testing.T) (string, *ssh.ClientConfig) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := ssh.NewSignerFromKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := &ssh.ServerConfig{
+		PasswordCallback: func(c ssh.ConnMetadata, password []byte) (*ssh.Permissions, error) {
+			if c.User() == "user" && string(password) == "password" {
+				return nil, nil
+			}
+			return nil, errors.New("incorrect password")
+		},
+	}
+	config.AddHostKey(signer)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	h := memoryHandlers{NewMemory()}
+	handlers := sftp.Handlers{FileGet: h, FilePut: h, FileCmd: h, FileList: h}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serveSSH(conn, config, handlers)
+		}
+	}()
+
+	return listener.Addr().String(), &ssh.ClientConfig{
+		User:            "user",
+		Auth:            []ssh.AuthMethod{ssh.Password("password")},
+		HostKeyCallback: ssh.FixedHostKey(signer.PublicKey()),
+	}
+}
+
+// serveSSH serves the SFTP subsystem over an SSH connection.
+func serveSSH(conn net.Conn, config *ssh.ServerConfig, handlers sftp.Handlers) {
+	_, channels, requests, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		conn.Close()
+		return
+	}
+	go ssh.DiscardRequests(requests)
+	for newChannel := range channels {
+		if newChannel.ChannelType() != "session" {
+			newChannel.Reject(ssh.UnknownChannelType, "only sessions are supported")
+			continue
+		}
+		channel, requests, err := newChannel.Accept()
+		if err != nil {
+			continue
+		}
+		go func() {
+			for req := range requests {
+				ok := req.Type == "subsystem" && string(req.Payload[4:]) == "sftp"
+				req.Reply(ok, nil)
+				if ok {
+					server := sftp.NewRequestServer(channel, handlers)
+					go func() {
+						server.Serve()
+						server.Close()
+					}()
+				}
+			}
+		}()
+	}
+}
+
+// newTestSFTP returns the file system of an in-process SFTP server, holding a few files and
+// directories.
+func newTestSFTP(t *testing.T) *SFTP {
+	addr, config := serveSFTP(t)
+	s, err := DialSFTP(addr, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Close() })
+
+	for _, dir := range []string{"dir", "dir/sub"} {
+		if err := s.Mkdir(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, name := range []string{"a.txt", "dir/b.txt", "dir/sub/c.txt"} {
+		w, err := s.Create(name, 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.WriteString(w, name)
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return s
+}
+
+func TestSFTP(t *testing.T) {
+	s := newTestSFTP(t)
+	if err := fstest.TestFS(s, "a.txt", "dir/b.txt", "dir/sub/c.txt"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.Symlink("a.txt", "link"); err != nil {
+		t.Fatal(err)
+	}
+	if info, err := s.Lstat("link"); err != nil || info.Mode()&fs.ModeSymlink == 0 {
+		t.Errorf("Lstat(\"link\") = %v, %v, want a symbolic link", info, err)
+	}
+	if target, err := s.ReadLink("link"); err != nil || target != "a.txt" {
+		t.Errorf("ReadLink(\"link\") = %q, %v", target, err)
+	}
+	if err := s.Link("a.txt", "dir/hard"); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := fs.ReadFile(s, "dir/hard"); err != nil || string(data) != "a.txt" {
+		t.Errorf("ReadFile of a hard link = %q, %v", data, err)
+	}
+
+	if err := s.Chmod("a.txt", fs.ModeSetuid|0750); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Chown("a.txt", 1000, -1); err != nil {
+		t.Fatal(err)
+	}
+	info, err := s.Stat("a.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode() != fs.ModeSetuid|0750 {
+		t.Errorf("mode after Chmod = %v, want %v", info.Mode(), fs.ModeSetuid|0750)
+	}
+	if uid, gid, ok := Owner(info); uid != 1000 || gid != 0 || !ok {
+		t.Errorf("Owner after Chown = %d, %d, %v, want 1000, 0, true", uid, gid, ok)
+	}
+
+	if _, err := s.Create("a.txt", 0644); err == nil {
+		t.Error("Create replaced an existing file")
+	}
+	if err := s.Rename("dir", "moved"); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := fs.ReadFile(s, "moved/sub/c.txt"); err != nil || string(data) != "dir/sub/c.txt" {
+		t.Errorf("ReadFile of a file in a renamed directory = %q, %v", data, err)
+	}
+	if err := s.RemoveAll("moved"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Stat("moved"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("a removed directory remains: %v", err)
+	}
+	if err := s.RemoveAll("moved"); err != nil {
+		t.Errorf("RemoveAll of a missing file returned %v", err)
+	}
+}
+
+func TestSFTPLost(t *testing.T) {
+	s := newTestSFTP(t)
+	if s.Lost() {
+		t.Fatal("Lost reported that a new connection was lost")
+	}
+	s.conn.Close()
+	select {
+	case <-s.lost:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the connection was closed, but Lost does not report it")
+	}
+	if !s.Lost() {
+		t.Error("Lost reported that a closed connection was not lost")
+	}
+}
+
+func TestDialSFTP(t *testing.T) {
+	addr, config := serveSFTP(t)
+	config.Auth = []ssh.AuthMethod{ssh.Password("incorrect")}
+	if s, err := DialSFTP(addr, config); err == nil {
+		s.Close()
+		t.Error("DialSFTP connected with an incorrect password")
+	}
+}
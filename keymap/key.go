@@ -0,0 +1,225 @@
+// Copyright 2019 Max Godfrey
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 		http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package keymap
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/nsf/termbox-go"
+)
+
+// Key is a single key pressed on the keyboard, possibly along with the alt key. Either Ch is set
+// to the character which was typed, or Key is set to a termbox key code.
+type Key struct {
+	Key termbox.Key
+	Ch  rune
+	Alt bool
+}
+
+// specialKeys maps the names of keys which are written in angle brackets, such as <Enter>, to
+// their termbox key codes. Names are matched regardless of case.
+var specialKeys = map[string]termbox.Key{
+	"up":        termbox.KeyArrowUp,
+	"down":      termbox.KeyArrowDown,
+	"left":      termbox.KeyArrowLeft,
+	"right":     termbox.KeyArrowRight,
+	"enter":     termbox.KeyEnter,
+	"cr":        termbox.KeyEnter,
+	"esc":       termbox.KeyEsc,
+	"tab":       termbox.KeyTab,
+	"backspace": termbox.KeyBackspace2,
+	"bs":        termbox.KeyBackspace2,
+	"delete":    termbox.KeyDelete,
+	"del":       termbox.KeyDelete,
+	"insert":    termbox.KeyInsert,
+	"home":      termbox.KeyHome,
+	"end":       termbox.KeyEnd,
+	"pgup":      termbox.KeyPgup,
+	"pageup":    termbox.KeyPgup,
+	"pgdn":      termbox.KeyPgdn,
+	"pagedown":  termbox.KeyPgdn,
+	"f1":        termbox.KeyF1,
+	"f2":        termbox.KeyF2,
+	"f3":        termbox.KeyF3,
+	"f4":        termbox.KeyF4,
+	"f5":        termbox.KeyF5,
+	"f6":        termbox.KeyF6,
+	"f7":        termbox.KeyF7,
+	"f8":        termbox.KeyF8,
+	"f9":        termbox.KeyF9,
+	"f10":       termbox.KeyF10,
+	"f11":       termbox.KeyF11,
+	"f12":       termbox.KeyF12,
+}
+
+// keyNames maps termbox key codes to the names with which they are written by Key.String.
+var keyNames = map[termbox.Key]string{
+	termbox.KeyArrowUp:    "Up",
+	termbox.KeyArrowDown:  "Down",
+	termbox.KeyArrowLeft:  "Left",
+	termbox.KeyArrowRight: "Right",
+	termbox.KeyEnter:      "Enter",
+	termbox.KeyEsc:        "Esc",
+	termbox.KeyTab:        "Tab",
+	termbox.KeyBackspace2: "BS",
+	termbox.KeyDelete:     "Del",
+	termbox.KeyInsert:     "Insert",
+	termbox.KeyHome:       "Home",
+	termbox.KeyEnd:        "End",
+	termbox.KeyPgup:       "PgUp",
+	termbox.KeyPgdn:       "PgDn",
+	termbox.KeyF1:         "F1",
+	termbox.KeyF2:         "F2",
+	termbox.KeyF3:         "F3",
+	termbox.KeyF4:         "F4",
+	termbox.KeyF5:         "F5",
+	termbox.KeyF6:         "F6",
+	termbox.KeyF7:         "F7",
+	termbox.KeyF8:         "F8",
+	termbox.KeyF9:         "F9",
+	termbox.KeyF10:        "F10",
+	termbox.KeyF11:        "F11",
+	termbox.KeyF12:        "F12",
+}
+
+// FromEvent returns the Key pressed in a termbox key event. The space bar is reported by termbox
+// as either a key code or a character, so it is normalised to a character here.
+func FromEvent(ev termbox.Event) Key {
+	key := Key{Key: ev.Key, Ch: ev.Ch, Alt: ev.Mod&termbox.ModAlt != 0}
+	switch {
+	case key.Ch != 0:
+		key.Key = 0
+	case key.Key == termbox.KeySpace:
+		key.Key, key.Ch = 0, ' '
+	}
+	return key
+}
+
+// String returns the notation for a key understood by ParseSequence, such as "q", "<C-d>" or
+// "<PgDn>".
+func (k Key) String() string {
+	var name string
+	switch {
+	case k.Ch == '<':
+		name = "lt"
+	case k.Ch == ' ':
+		name = "Space"
+	case k.Ch != 0:
+		if !k.Alt {
+			return string(k.Ch)
+		}
+		name = string(k.Ch)
+	case keyNames[k.Key] != "":
+		name = keyNames[k.Key]
+	case k.Key >= termbox.KeyCtrlA && k.Key <= termbox.KeyCtrlZ:
+		name = "C-" + string(rune('a'+k.Key-termbox.KeyCtrlA))
+	default:
+		name = fmt.Sprintf("0x%X", uint16(k.Key))
+	}
+	if k.Alt {
+		name = "A-" + name
+	}
+	return "<" + name + ">"
+}
+
+// ParseSequence parses a sequence of keys written in the notation used by vim: ordinary characters
+// stand for themselves, while other keys are written in angle brackets, such as <Enter>, <PgDn>,
+// <Space> and <lt> (for the '<' character). Within angle brackets, a key may be prefixed with C-
+// to hold control, or A- or M- to hold alt, as in <C-d> or <A-x>.
+func ParseSequence(sequence string) ([]Key, error) {
+	var keys []Key
+	for len(sequence) > 0 {
+		if sequence[0] == '<' {
+			end := strings.IndexByte(sequence, '>')
+			if end < 0 {
+				return nil, fmt.Errorf("%q is missing a closing '>'", sequence)
+			}
+			key, err := parseSpecial(sequence[1:end])
+			if err != nil {
+				return nil, err
+			}
+			keys = append(keys, key)
+			sequence = sequence[end+1:]
+			continue
+		}
+
+		ch, size := utf8.DecodeRuneInString(sequence)
+		keys = append(keys, Key{Ch: ch})
+		sequence = sequence[size:]
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("a key sequence must contain at least one key")
+	}
+	return keys, nil
+}
+
+// parseSpecial parses the name of a key written within angle brackets.
+func parseSpecial(name string) (Key, error) {
+	var key Key
+	ctrl := false
+	rest := name
+	for len(rest) > 2 && rest[1] == '-' {
+		switch strings.ToLower(rest[:1]) {
+		case "c":
+			ctrl = true
+		case "a", "m":
+			key.Alt = true
+		default:
+			return key, fmt.Errorf("<%s> has an unknown modifier %q", name, rest[:1])
+		}
+		rest = rest[2:]
+	}
+
+	if code, ok := specialKeys[strings.ToLower(rest)]; ok {
+		if ctrl {
+			return key, fmt.Errorf("<%s> cannot be combined with control", name)
+		}
+		key.Key = code
+		return key, nil
+	}
+	switch strings.ToLower(rest) {
+	case "lt":
+		key.Ch = '<'
+	case "space":
+		key.Ch = ' '
+	default:
+		ch, size := utf8.DecodeRuneInString(rest)
+		if size != len(rest) || size == 0 {
+			return key, fmt.Errorf("<%s> is not a known key", name)
+		}
+		key.Ch = ch
+	}
+
+	if ctrl {
+		lower := key.Ch | 0x20
+		if lower < 'a' || lower > 'z' {
+			return key, fmt.Errorf("<%s> cannot be combined with control", name)
+		}
+		key.Key = termbox.KeyCtrlA + termbox.Key(lower-'a')
+		key.Ch = 0
+	}
+	return key, nil
+}
+
+// FormatSequence returns the notation for a sequence of keys understood by ParseSequence.
+func FormatSequence(keys []Key) string {
+	var b strings.Builder
+	for _, key := range keys {
+		b.WriteString(key.String())
+	}
+	return b.String()
+}
@@ -0,0 +1,102 @@
+// Copyright 2019 Max Godfrey
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 		http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package keymap
+
+import (
+	"testing"
+
+	"github.com/nsf/termbox-go"
+)
+
+func TestParseSequence(t *testing.T) {
+	tests := []struct {
+		sequence string
+		want     []Key
+	}{
+		{"q", []Key{{Ch: 'q'}}},
+		{"gg", []Key{{Ch: 'g'}, {Ch: 'g'}}},
+		{"<Enter>", []Key{{Key: termbox.KeyEnter}}},
+		{"<pgdn>", []Key{{Key: termbox.KeyPgdn}}},
+		{"<C-d>", []Key{{Key: termbox.KeyCtrlD}}},
+		{"<C-D>", []Key{{Key: termbox.KeyCtrlD}}},
+		{"<A-x>", []Key{{Ch: 'x', Alt: true}}},
+		{"<M-Up>", []Key{{Key: termbox.KeyArrowUp, Alt: true}}},
+		{"<lt>a", []Key{{Ch: '<'}, {Ch: 'a'}}},
+		{"<Space>é", []Key{{Ch: ' '}, {Ch: 'é'}}},
+	}
+	for _, test := range tests {
+		keys, err := ParseSequence(test.sequence)
+		if err != nil {
+			t.Errorf("ParseSequence(%q) returned %v", test.sequence, err)
+			continue
+		}
+		if len(keys) != len(test.want) {
+			t.Errorf("ParseSequence(%q) = %v, want %v", test.sequence, keys, test.want)
+			continue
+		}
+		for i := range keys {
+			if keys[i] != test.want[i] {
+				t.Errorf("ParseSequence(%q) = %v, want %v", test.sequence, keys, test.want)
+				break
+			}
+		}
+	}
+}
+
+func TestParseSequenceErrors(t *testing.T) {
+	for _, sequence := range []string{"", "<Enter", "<Bogus>", "<C-Enter>", "<C-1>", "<X-a>"} {
+		if keys, err := ParseSequence(sequence); err == nil {
+			t.Errorf("ParseSequence(%q) = %v, want an error", sequence, keys)
+		}
+	}
+}
+
+func TestFormatSequence(t *testing.T) {
+	// Every sequence is written in a normalised form, which parses back to the same keys.
+	tests := map[string]string{
+		"q":           "q",
+		"<cr>":        "<Enter>",
+		"<C-D>":       "<C-d>",
+		"<M-x>":       "<A-x>",
+		"<lt><space>": "<lt><Space>",
+		"<backspace>": "<BS>",
+	}
+	for sequence, want := range tests {
+		keys, err := ParseSequence(sequence)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := FormatSequence(keys); got != want {
+			t.Errorf("FormatSequence(ParseSequence(%q)) = %q, want %q", sequence, got, want)
+		}
+	}
+}
+
+func TestFromEvent(t *testing.T) {
+	tests := []struct {
+		ev   termbox.Event
+		want Key
+	}{
+		{termbox.Event{Ch: 'q'}, Key{Ch: 'q'}},
+		{termbox.Event{Key: termbox.KeySpace}, Key{Ch: ' '}},
+		{termbox.Event{Key: termbox.KeyArrowUp}, Key{Key: termbox.KeyArrowUp}},
+		{termbox.Event{Ch: 'x', Mod: termbox.ModAlt}, Key{Ch: 'x', Alt: true}},
+	}
+	for _, test := range tests {
+		if got := FromEvent(test.ev); got != test.want {
+			t.Errorf("FromEvent(%+v) = %+v, want %+v", test.ev, got, test.want)
+		}
+	}
+}
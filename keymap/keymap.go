@@ -0,0 +1,217 @@
+// Copyright 2019 Max Godfrey
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 		http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package keymap maps sequences of keys pressed by the user to the actions which they perform.
+// Each mode of the application has its own set of bindings.
+package keymap
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Status describes the outcome of feeding a key to a Keymap.
+type Status int
+
+const (
+	// NoMatch means that the keys pressed so far are not bound to any action in the mode.
+	NoMatch Status = iota
+
+	// Pending means that the keys pressed so far begin at least one bound sequence, so more keys
+	// are required to determine the action.
+	Pending
+
+	// Matched means that the keys pressed so far are bound to an action.
+	Matched
+)
+
+// Binding associates a sequence of keys with an action in a mode.
+type Binding struct {
+	Mode     string // The mode in which the binding is active, such as "normal".
+	Sequence string // The keys, in the notation understood by ParseSequence.
+	Action   string // The name of the action performed when the keys are pressed.
+}
+
+// node is a node of the trie of bound sequences within a mode. A node either has an action, or
+// children reached by pressing further keys.
+type node struct {
+	action   string
+	children map[Key]*node
+}
+
+// Keymap holds the bindings of each mode, along with the keys pressed so far towards a sequence.
+type Keymap struct {
+	modes    map[string]*node
+	bindings []Binding // Every binding, in the order in which they were bound.
+	pending  []Key
+}
+
+// New returns a Keymap without any bindings.
+func New() *Keymap {
+	return &Keymap{modes: make(map[string]*node)}
+}
+
+// Bind binds a sequence of keys to an action in a mode, replacing any binding of exactly the same
+// sequence. An error is returned if the sequence cannot be parsed, or if it begins (or is the
+// beginning of) another sequence bound in the mode, as it would then be ambiguous which action
+// should be performed.
+func (k *Keymap) Bind(mode, sequence, action string) error {
+	keys, err := ParseSequence(sequence)
+	if err != nil {
+		return err
+	}
+	if action == "" {
+		return fmt.Errorf("%s cannot be bound to an empty action", sequence)
+	}
+
+	root, ok := k.modes[mode]
+	if !ok {
+		root = &node{children: make(map[Key]*node)}
+		k.modes[mode] = root
+	}
+	current := root
+	for i, key := range keys {
+		if current.action != "" {
+			return fmt.Errorf("%s conflicts with %s, which is bound to %s in %s mode", sequence,
+				FormatSequence(keys[:i]), current.action, mode)
+		}
+		next, ok := current.children[key]
+		if !ok {
+			next = &node{children: make(map[Key]*node)}
+			current.children[key] = next
+		}
+		current = next
+	}
+	if len(current.children) > 0 {
+		return fmt.Errorf("%s conflicts with longer sequences which begin with it in %s mode", sequence,
+			mode)
+	}
+
+	normalised := FormatSequence(keys)
+	k.removeBinding(mode, normalised)
+	current.action = action
+	k.bindings = append(k.bindings, Binding{Mode: mode, Sequence: normalised, Action: action})
+	return nil
+}
+
+// Unbind removes the binding of a sequence of keys in a mode, if there is one.
+func (k *Keymap) Unbind(mode, sequence string) error {
+	keys, err := ParseSequence(sequence)
+	if err != nil {
+		return err
+	}
+
+	// Every node along the path to the binding is recorded, so that nodes which are left without
+	// children can be pruned.
+	path := []*node{k.modes[mode]}
+	for _, key := range keys {
+		current := path[len(path)-1]
+		if current == nil {
+			return nil
+		}
+		path = append(path, current.children[key])
+	}
+	if path[len(path)-1] == nil || path[len(path)-1].action == "" {
+		return nil
+	}
+
+	path[len(path)-1].action = ""
+	for i := len(keys) - 1; i >= 0; i-- {
+		if child := path[i+1]; child.action == "" && len(child.children) == 0 {
+			delete(path[i].children, keys[i])
+		}
+	}
+	k.removeBinding(mode, FormatSequence(keys))
+	return nil
+}
+
+// removeBinding removes a sequence from the list of bindings in a mode.
+func (k *Keymap) removeBinding(mode, sequence string) {
+	for i, binding := range k.bindings {
+		if binding.Mode == mode && binding.Sequence == sequence {
+			k.bindings = append(k.bindings[:i], k.bindings[i+1:]...)
+			return
+		}
+	}
+}
+
+// Feed records a key pressed while the application is in a mode. If the keys pressed so far make
+// up a bound sequence, its action is returned along with Matched. If they begin a bound sequence,
+// Pending is returned and the keys are remembered. Otherwise NoMatch is returned, and the keys are
+// forgotten.
+func (k *Keymap) Feed(mode string, key Key) (string, Status) {
+	k.pending = append(k.pending, key)
+	current := k.modes[mode]
+	for _, pressed := range k.pending {
+		if current == nil {
+			break
+		}
+		current = current.children[pressed]
+	}
+
+	switch {
+	case current == nil:
+		k.pending = nil
+		return "", NoMatch
+	case current.action != "":
+		k.pending = nil
+		return current.action, Matched
+	default:
+		return "", Pending
+	}
+}
+
+// Starts reports whether any sequence bound in a mode begins with a key.
+func (k *Keymap) Starts(mode string, key Key) bool {
+	root := k.modes[mode]
+	return root != nil && root.children[key] != nil
+}
+
+// Pending returns the keys pressed so far towards a sequence, in the notation understood by
+// ParseSequence.
+func (k *Keymap) Pending() string {
+	return FormatSequence(k.pending)
+}
+
+// Reset forgets any keys pressed so far towards a sequence.
+func (k *Keymap) Reset() {
+	k.pending = nil
+}
+
+// Bindings returns every binding in a mode, in the order in which they were bound.
+func (k *Keymap) Bindings(mode string) []Binding {
+	var bindings []Binding
+	for _, binding := range k.bindings {
+		if binding.Mode == mode {
+			bindings = append(bindings, binding)
+		}
+	}
+	return bindings
+}
+
+// Describe returns a description of the sequences bound to an action in a mode along with a label
+// for the action, in the form "[Q|q: Quit]". If no sequences are bound to the action, an empty
+// string is returned.
+func (k *Keymap) Describe(mode, action, label string) string {
+	var sequences []string
+	for _, binding := range k.Bindings(mode) {
+		if binding.Action == action {
+			sequences = append(sequences, binding.Sequence)
+		}
+	}
+	if len(sequences) == 0 {
+		return ""
+	}
+	return "[" + strings.Join(sequences, "|") + ": " + label + "]"
+}
@@ -0,0 +1,154 @@
+// Copyright 2019 Max Godfrey
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 		http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package keymap
+
+import (
+	"testing"
+)
+
+// feed feeds each key of a sequence to a keymap, returning the result of the last.
+func feed(t *testing.T, k *Keymap, mode, sequence string) (string, Status) {
+	keys, err := ParseSequence(sequence)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var action string
+	var status Status
+	for _, key := range keys {
+		action, status = k.Feed(mode, key)
+	}
+	return action, status
+}
+
+func TestFeed(t *testing.T) {
+	k := New()
+	for sequence, action := range map[string]string{"q": "quit", "gg": "top", "<C-d>": "page-down"} {
+		if err := k.Bind("normal", sequence, action); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		sequence string
+		action   string
+		status   Status
+	}{
+		{"q", "quit", Matched},
+		{"<C-d>", "page-down", Matched},
+		{"g", "", Pending},
+		{"g", "top", Matched},
+		{"x", "", NoMatch},
+		{"gx", "", NoMatch},
+		{"gg", "top", Matched},
+	}
+	for _, test := range tests {
+		action, status := feed(t, k, "normal", test.sequence)
+		if action != test.action || status != test.status {
+			t.Errorf("after %q, Feed = (%q, %v), want (%q, %v)", test.sequence, action, status,
+				test.action, test.status)
+		}
+	}
+
+	if action, status := feed(t, k, "search", "q"); status != NoMatch {
+		t.Errorf("Feed in an unbound mode = (%q, %v), want NoMatch", action, status)
+	}
+}
+
+func TestPendingAndReset(t *testing.T) {
+	k := New()
+	if err := k.Bind("normal", "gg", "top"); err != nil {
+		t.Fatal(err)
+	}
+	feed(t, k, "normal", "g")
+	if pending := k.Pending(); pending != "g" {
+		t.Errorf("Pending() = %q, want \"g\"", pending)
+	}
+	k.Reset()
+	if pending := k.Pending(); pending != "" {
+		t.Errorf("Pending() after Reset = %q, want \"\"", pending)
+	}
+	if _, status := feed(t, k, "normal", "g"); status != Pending {
+		t.Errorf("Feed after Reset = %v, want Pending", status)
+	}
+}
+
+func TestStarts(t *testing.T) {
+	k := New()
+	if err := k.Bind("normal", "gg", "top"); err != nil {
+		t.Fatal(err)
+	}
+	if !k.Starts("normal", Key{Ch: 'g'}) {
+		t.Error("Starts(\"normal\", g) = false, want true")
+	}
+	if k.Starts("normal", Key{Ch: '5'}) || k.Starts("search", Key{Ch: 'g'}) {
+		t.Error("Starts reported a key which begins no sequence")
+	}
+}
+
+func TestBindConflicts(t *testing.T) {
+	k := New()
+	if err := k.Bind("normal", "gg", "top"); err != nil {
+		t.Fatal(err)
+	}
+	if err := k.Bind("normal", "g", "go"); err == nil {
+		t.Error("binding a prefix of a bound sequence did not return an error")
+	}
+	if err := k.Bind("normal", "ggx", "go"); err == nil {
+		t.Error("binding a sequence beginning with a bound sequence did not return an error")
+	}
+	if err := k.Bind("other", "g", "go"); err != nil {
+		t.Errorf("binding in another mode returned %v", err)
+	}
+	if err := k.Bind("normal", "gg", "bottom"); err != nil {
+		t.Errorf("rebinding an identical sequence returned %v", err)
+	}
+	if action, _ := feed(t, k, "normal", "gg"); action != "bottom" {
+		t.Errorf("rebound sequence performs %q, want \"bottom\"", action)
+	}
+	if bindings := k.Bindings("normal"); len(bindings) != 1 {
+		t.Errorf("Bindings(\"normal\") = %v, want a single binding", bindings)
+	}
+}
+
+func TestUnbind(t *testing.T) {
+	k := New()
+	k.Bind("normal", "gg", "top")
+	if err := k.Unbind("normal", "gg"); err != nil {
+		t.Fatal(err)
+	}
+	if _, status := feed(t, k, "normal", "g"); status != NoMatch {
+		t.Errorf("Feed of an unbound prefix = %v, want NoMatch", status)
+	}
+	// Once unbound, a prefix of the sequence no longer conflicts.
+	if err := k.Bind("normal", "g", "go"); err != nil {
+		t.Errorf("binding a prefix of an unbound sequence returned %v", err)
+	}
+	if err := k.Unbind("normal", "zz"); err != nil {
+		t.Errorf("unbinding a sequence which is not bound returned %v", err)
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	k := New()
+	k.Bind("normal", "Q", "quit")
+	k.Bind("normal", "q", "quit")
+	k.Bind("normal", "<C-c>", "quit")
+	if got, want := k.Describe("normal", "quit", "Quit"), "[Q|q|<C-c>: Quit]"; got != want {
+		t.Errorf("Describe = %q, want %q", got, want)
+	}
+	if got := k.Describe("normal", "top", "Top"); got != "" {
+		t.Errorf("Describe of an unbound action = %q, want \"\"", got)
+	}
+}
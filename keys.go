@@ -0,0 +1,367 @@
+// Copyright 2019 Max Godfrey
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 		http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"sort"
+	"unicode"
+
+	"github.com/maxgodfrey2004/go-file-manager/config"
+	"github.com/maxgodfrey2004/go-file-manager/keymap"
+)
+
+// The modes which the application may be in.
+const (
+	// NormalMode is the mode in which the user navigates the file system.
+	NormalMode = "normal"
+
+	// SearchMode is the mode in which the user types a search for a file or directory.
+	SearchMode = "search"
+
+	// CommandMode is the mode in which the user types a command.
+	CommandMode = "command"
+
+	// PropertiesMode is the mode in which the user changes the permissions and ownership of files
+	// in the properties dialog.
+	PropertiesMode = "properties"
+)
+
+// action is something which the user may bind a sequence of keys to.
+type action struct {
+	event     KeyEvent // The event which the action causes.
+	direction int      // The direction in which the action moves, for events which move.
+	label     string   // The label of the action in the key functions, if it is listed there.
+	prompt    bool     // Whether the action edits a prompt, rather than being used in normal mode.
+	dialog    bool     // Whether the action is only used in the properties dialog.
+}
+
+// actions maps the name of each action to the action itself.
+var actions = map[string]action{
+	"up":               {event: Reselect, direction: Up},
+	"down":             {event: Reselect, direction: Down},
+	"page-up":          {event: Page, direction: Up},
+	"page-down":        {event: Page, direction: Down},
+	"half-page-up":     {event: HalfPage, direction: Up},
+	"half-page-down":   {event: HalfPage, direction: Down},
+	"top":              {event: Jump, direction: Up},
+	"bottom":           {event: Jump, direction: Down},
+	"select":           {event: Select},
+	"parent":           {event: Parent},
+	"search":           {event: Search, label: "Search"},
+	"search-next":      {event: SearchNext, direction: Down},
+	"search-previous":  {event: SearchNext, direction: Up},
+	"command":          {event: Command, label: "Command"},
+	"toggle-list-all":  {event: ToggleListAll, label: "List"},
+	"tab-new":          {event: NewTab},
+	"tab-close":        {event: CloseTab},
+	"tab-next":         {event: SwitchTab, direction: Down},
+	"tab-previous":     {event: SwitchTab, direction: Up},
+	"toggle-dual-pane": {event: ToggleDualPane},
+	"switch-pane":      {event: SwitchPane},
+	"toggle-tree":      {event: ToggleTree},
+	"toggle-expand":    {event: ToggleExpand},
+	"cycle-sort":       {event: CycleSort},
+	"copy":             {event: CopyEntry, label: "Copy"},
+	"move":             {event: MoveEntry, label: "Move"},
+	"delete":           {event: DeleteEntry, label: "Delete"},
+	"mark":             {event: ToggleMark},
+	"unmark-all":       {event: UnmarkAll},
+	"bulk-rename":      {event: BulkRename},
+	"pattern-rename":   {event: PatternRename},
+	"new-file":         {event: NewFile},
+	"new-directory":    {event: NewDirectory, label: "Mkdir"},
+	"new-symlink":      {event: NewSymlink},
+	"new-hardlink":     {event: NewHardlink},
+	"properties":       {event: OpenProperties},
+	"quit":             {event: Quit, label: "Quit"},
+	"confirm":          {event: Confirm, prompt: true},
+	"cancel":           {event: Cancel, prompt: true},
+	"backspace":        {event: DeleteChar, prompt: true},
+	"field-up":         {event: MoveField, direction: Up, dialog: true},
+	"field-down":       {event: MoveField, direction: Down, dialog: true},
+	"field-left":       {event: MoveColumn, direction: Up, dialog: true},
+	"field-right":      {event: MoveColumn, direction: Down, dialog: true},
+	"toggle-field":     {event: ToggleField, dialog: true},
+}
+
+// keyFunctionActions are the actions listed in the key functions at the bottom of the screen, in
+// the order in which they are listed.
+var keyFunctionActions = []string{"quit", "toggle-list-all", "search", "command", "copy", "move",
+	"new-directory", "delete"}
+
+// promptBindings are the bindings with which a prompt is edited in search and command mode, and
+// with which the properties dialog is used. They belong to every preset.
+var promptBindings = []keymap.Binding{
+	{Mode: SearchMode, Sequence: "<Enter>", Action: "confirm"},
+	{Mode: SearchMode, Sequence: "<Esc>", Action: "cancel"},
+	{Mode: SearchMode, Sequence: "<C-c>", Action: "cancel"},
+	{Mode: SearchMode, Sequence: "<BS>", Action: "backspace"},
+	{Mode: CommandMode, Sequence: "<Enter>", Action: "confirm"},
+	{Mode: CommandMode, Sequence: "<Esc>", Action: "cancel"},
+	{Mode: CommandMode, Sequence: "<C-c>", Action: "cancel"},
+	{Mode: CommandMode, Sequence: "<BS>", Action: "backspace"},
+	{Mode: PropertiesMode, Sequence: "<Up>", Action: "field-up"},
+	{Mode: PropertiesMode, Sequence: "<Down>", Action: "field-down"},
+	{Mode: PropertiesMode, Sequence: "<Tab>", Action: "field-down"},
+	{Mode: PropertiesMode, Sequence: "<Left>", Action: "field-left"},
+	{Mode: PropertiesMode, Sequence: "<Right>", Action: "field-right"},
+	{Mode: PropertiesMode, Sequence: "<Space>", Action: "toggle-field"},
+	{Mode: PropertiesMode, Sequence: "<Enter>", Action: "confirm"},
+	{Mode: PropertiesMode, Sequence: "<Esc>", Action: "cancel"},
+	{Mode: PropertiesMode, Sequence: "<C-c>", Action: "cancel"},
+	{Mode: PropertiesMode, Sequence: "<BS>", Action: "backspace"},
+}
+
+// defaultBindings are the bindings of the default preset.
+var defaultBindings = []keymap.Binding{
+	{Mode: NormalMode, Sequence: "<Up>", Action: "up"},
+	{Mode: NormalMode, Sequence: "<Down>", Action: "down"},
+	{Mode: NormalMode, Sequence: "<PgUp>", Action: "page-up"},
+	{Mode: NormalMode, Sequence: "<PgDn>", Action: "page-down"},
+	{Mode: NormalMode, Sequence: "<Home>", Action: "top"},
+	{Mode: NormalMode, Sequence: "<End>", Action: "bottom"},
+	{Mode: NormalMode, Sequence: "<Right>", Action: "select"},
+	{Mode: NormalMode, Sequence: "<Enter>", Action: "select"},
+	{Mode: NormalMode, Sequence: "Q", Action: "quit"},
+	{Mode: NormalMode, Sequence: "q", Action: "quit"},
+	{Mode: NormalMode, Sequence: "<C-c>", Action: "quit"},
+	{Mode: NormalMode, Sequence: "A", Action: "toggle-list-all"},
+	{Mode: NormalMode, Sequence: "a", Action: "toggle-list-all"},
+	{Mode: NormalMode, Sequence: "<C-t>", Action: "tab-new"},
+	{Mode: NormalMode, Sequence: "<C-w>", Action: "tab-close"},
+	{Mode: NormalMode, Sequence: "<C-n>", Action: "tab-next"},
+	{Mode: NormalMode, Sequence: "<C-p>", Action: "tab-previous"},
+	{Mode: NormalMode, Sequence: "<Tab>", Action: "switch-pane"},
+	{Mode: NormalMode, Sequence: "<F5>", Action: "copy"},
+	{Mode: NormalMode, Sequence: "<F6>", Action: "move"},
+	{Mode: NormalMode, Sequence: "<F8>", Action: "delete"},
+	{Mode: NormalMode, Sequence: "<F9>", Action: "toggle-dual-pane"},
+	{Mode: NormalMode, Sequence: "t", Action: "toggle-tree"},
+	{Mode: NormalMode, Sequence: "<Space>", Action: "toggle-expand"},
+	{Mode: NormalMode, Sequence: "s", Action: "cycle-sort"},
+	{Mode: NormalMode, Sequence: "m", Action: "mark"},
+	{Mode: NormalMode, Sequence: "u", Action: "unmark-all"},
+	{Mode: NormalMode, Sequence: "<F2>", Action: "bulk-rename"},
+	{Mode: NormalMode, Sequence: "r", Action: "pattern-rename"},
+	{Mode: NormalMode, Sequence: "c", Action: "new-file"},
+	{Mode: NormalMode, Sequence: "<F7>", Action: "new-directory"},
+	{Mode: NormalMode, Sequence: "L", Action: "new-symlink"},
+	{Mode: NormalMode, Sequence: "H", Action: "new-hardlink"},
+	{Mode: NormalMode, Sequence: "p", Action: "properties"},
+}
+
+// vimBindings are the bindings which the vim preset adds to those of the default preset.
+var vimBindings = []keymap.Binding{
+	{Mode: NormalMode, Sequence: "k", Action: "up"},
+	{Mode: NormalMode, Sequence: "j", Action: "down"},
+	{Mode: NormalMode, Sequence: "h", Action: "parent"},
+	{Mode: NormalMode, Sequence: "<Left>", Action: "parent"},
+	{Mode: NormalMode, Sequence: "l", Action: "select"},
+	{Mode: NormalMode, Sequence: "gg", Action: "top"},
+	{Mode: NormalMode, Sequence: "G", Action: "bottom"},
+	{Mode: NormalMode, Sequence: "<C-u>", Action: "half-page-up"},
+	{Mode: NormalMode, Sequence: "<C-d>", Action: "half-page-down"},
+	{Mode: NormalMode, Sequence: "<C-b>", Action: "page-up"},
+	{Mode: NormalMode, Sequence: "<C-f>", Action: "page-down"},
+	{Mode: NormalMode, Sequence: "/", Action: "search"},
+	{Mode: NormalMode, Sequence: "n", Action: "search-next"},
+	{Mode: NormalMode, Sequence: "N", Action: "search-previous"},
+	{Mode: NormalMode, Sequence: ":", Action: "command"},
+	{Mode: NormalMode, Sequence: "gt", Action: "tab-next"},
+	{Mode: NormalMode, Sequence: "gT", Action: "tab-previous"},
+}
+
+// presets maps the name of each preset to its bindings in normal mode.
+var presets = map[string][]keymap.Binding{
+	"default": defaultBindings,
+	"vim":     append(append([]keymap.Binding{}, defaultBindings...), vimBindings...),
+}
+
+var (
+	// keys maps the keys which the user presses to actions.
+	keys = keymap.New()
+
+	// mode is the mode which the application is currently in.
+	mode = NormalMode
+
+	// count is the count typed before an action in normal mode, or 0 if none has been typed.
+	count int
+)
+
+// maxCount is the largest count which may be typed, as in vim. Further digits leave the count at
+// it, rather than letting it overflow.
+const maxCount = 999999999
+
+// loadKeymap binds the bindings of a preset, followed by the bindings which the user has
+// configured.
+func loadKeymap(preset string, configured config.Keys) error {
+	bindings, ok := presets[preset]
+	if !ok {
+		return fmt.Errorf("options.key_preset: unknown preset %q", preset)
+	}
+	bindings = append(append([]keymap.Binding{}, bindings...), promptBindings...)
+
+	keys = keymap.New()
+	for _, binding := range bindings {
+		if err := keys.Bind(binding.Mode, binding.Sequence, binding.Action); err != nil {
+			panic(err)
+		}
+	}
+
+	// The configured modes and sequences are bound in sorted order, so that any error is reported
+	// consistently.
+	var modes []string
+	for mode := range configured {
+		modes = append(modes, mode)
+	}
+	sort.Strings(modes)
+	for _, mode := range modes {
+		if mode != NormalMode && mode != SearchMode && mode != CommandMode && mode != PropertiesMode {
+			return fmt.Errorf("keys: unknown mode %q", mode)
+		}
+		var sequences []string
+		for sequence := range configured[mode] {
+			sequences = append(sequences, sequence)
+		}
+		sort.Strings(sequences)
+
+		for _, sequence := range sequences {
+			setting := fmt.Sprintf("keys.%s[%q]", mode, sequence)
+			name := configured[mode][sequence]
+			if name == "" {
+				if err := keys.Unbind(mode, sequence); err != nil {
+					return fmt.Errorf("%s: %v", setting, err)
+				}
+				continue
+			}
+			a, ok := actions[name]
+			if !ok {
+				return fmt.Errorf("%s: unknown action %q", setting, name)
+			}
+			if !usableIn(a, mode) {
+				return fmt.Errorf("%s: %q cannot be used in %s mode", setting, name, mode)
+			}
+			// Any preset binding which conflicts with the configured one is replaced by it, while
+			// conflicts between configured bindings are reported by Bind.
+			for _, binding := range keys.Bindings(mode) {
+				if contains(bindings, binding) && conflicts(binding.Sequence, sequence) {
+					keys.Unbind(mode, binding.Sequence)
+				}
+			}
+			if err := keys.Bind(mode, sequence, name); err != nil {
+				return fmt.Errorf("%s: %v", setting, err)
+			}
+		}
+	}
+	return nil
+}
+
+// usableIn reports whether an action may be bound in a mode. Actions which edit a prompt may be
+// bound in any mode but normal mode, actions of the properties dialog only in properties mode, and
+// every other action only in normal mode.
+func usableIn(a action, mode string) bool {
+	switch {
+	case a.prompt:
+		return mode != NormalMode
+	case a.dialog:
+		return mode == PropertiesMode
+	default:
+		return mode == NormalMode
+	}
+}
+
+// contains reports whether a binding is one of a list of bindings.
+func contains(bindings []keymap.Binding, binding keymap.Binding) bool {
+	for _, b := range bindings {
+		if b == binding {
+			return true
+		}
+	}
+	return false
+}
+
+// conflicts reports whether one sequence of keys begins with the other, in which case they cannot
+// both be bound in the same mode.
+func conflicts(a, b string) bool {
+	aKeys, err := keymap.ParseSequence(a)
+	if err != nil {
+		return false
+	}
+	bKeys, err := keymap.ParseSequence(b)
+	if err != nil {
+		return false
+	}
+	if len(aKeys) > len(bKeys) {
+		aKeys, bKeys = bKeys, aKeys
+	}
+	for i := range aKeys {
+		if aKeys[i] != bKeys[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// resolveKey feeds a key which the user has pressed to the keymap, returning the event which the
+// keys pressed so far are bound to in the current mode, if any. In normal mode, digits which do
+// not begin a bound sequence make up a count for the next action. In other modes, any character
+// which is not bound is typed into the prompt or the field of the properties dialog.
+func resolveKey(key keymap.Key) (keypress, bool) {
+	if mode == NormalMode && keys.Pending() == "" && isCountDigit(key) {
+		if count > maxCount/10 {
+			count = maxCount
+		} else {
+			count = count*10 + int(key.Ch-'0')
+		}
+		return keypress{}, false
+	}
+
+	name, status := keys.Feed(mode, key)
+	switch {
+	case status == keymap.Matched:
+		a := actions[name]
+		ev := keypress{EventType: a.event, Direction: a.direction, Count: count}
+		count = 0
+		return ev, true
+	case status == keymap.NoMatch && mode != NormalMode && unicode.IsPrint(key.Ch) && !key.Alt:
+		return keypress{EventType: InsertChar, Key: key}, true
+	case status == keymap.NoMatch:
+		count = 0
+	}
+	return keypress{}, false
+}
+
+// isCountDigit reports whether a key is a digit which continues the count in normal mode. A count
+// may not begin with 0, and digits which begin a bound sequence are not counted.
+func isCountDigit(key keymap.Key) bool {
+	if key.Alt || key.Ch < '0' || key.Ch > '9' || (key.Ch == '0' && count == 0) {
+		return false
+	}
+	return !keys.Starts(NormalMode, key)
+}
+
+// keyFunctions describes the keys bound to each action listed in the key functions, returning the
+// descriptions along with the name of the action which each describes. Actions without any keys
+// bound to them are left out.
+func keyFunctions() (functions, names []string) {
+	for _, name := range keyFunctionActions {
+		if description := keys.Describe(NormalMode, name, actions[name].label); description != "" {
+			functions = append(functions, description)
+			names = append(names, name)
+		}
+	}
+	return functions, names
+}
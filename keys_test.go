@@ -0,0 +1,66 @@
+// Copyright 2019 Max Godfrey
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 		http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/maxgodfrey2004/go-file-manager/keymap"
+)
+
+// feed resolves each of a sequence of keys in turn, returning the events which they resolve to.
+func feed(t *testing.T, sequence string) []keypress {
+	t.Helper()
+	pressed, err := keymap.ParseSequence(sequence)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var events []keypress
+	for _, key := range pressed {
+		if ev, ok := resolveKey(key); ok {
+			events = append(events, ev)
+		}
+	}
+	return events
+}
+
+func TestResolveKeyCount(t *testing.T) {
+	if err := loadKeymap("vim", nil); err != nil {
+		t.Fatal(err)
+	}
+	mode, count = NormalMode, 0
+
+	tests := []struct {
+		sequence string
+		action   string
+		count    int
+	}{
+		{"j", "down", 0},
+		{"12j", "down", 12},
+		{"3gg", "top", 3},
+		{"0j", "down", 0},  // a count may not begin with 0
+		{"10k", "up", 10},  // but it may contain one
+		{"5zj", "down", 0}, // an unbound key discards the count
+		{"99999999999999999999j", "down", maxCount},
+	}
+	for _, test := range tests {
+		events := feed(t, test.sequence)
+		a := actions[test.action]
+		want := keypress{EventType: a.event, Direction: a.direction, Count: test.count}
+		if len(events) != 1 || events[0] != want {
+			t.Errorf("%q resolved to %+v, want %+v", test.sequence, events, want)
+		}
+	}
+}
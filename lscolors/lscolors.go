@@ -0,0 +1,310 @@
+// Copyright 2019 Max Godfrey
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 		http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package lscolors colours the names of files in the same way as `ls --color`, according to the
+// LS_COLORS environment variable or a database in the format read by dircolors.
+package lscolors
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/maxgodfrey2004/go-file-manager/textrenderer"
+	"github.com/nsf/termbox-go"
+)
+
+// Colors holds the SGR sequences with which files are coloured, by type and by extension.
+type Colors struct {
+	types      map[string][]int // Sequences by two letter type, such as "di" for directories.
+	extensions []extension      // Sequences by suffix, in the order in which they were given.
+}
+
+// extension is the sequence with which files whose names end with a suffix are coloured.
+type extension struct {
+	suffix   string // The lower case suffix, such as ".tar".
+	sequence []int
+}
+
+// dircolorsKeywords maps the keywords of a dircolors database to the two letter types used in
+// LS_COLORS.
+var dircolorsKeywords = map[string]string{
+	"NORMAL":                "no",
+	"NORM":                  "no",
+	"FILE":                  "fi",
+	"RESET":                 "rs",
+	"DIR":                   "di",
+	"LINK":                  "ln",
+	"LNK":                   "ln",
+	"SYMLINK":               "ln",
+	"MULTIHARDLINK":         "mh",
+	"FIFO":                  "pi",
+	"PIPE":                  "pi",
+	"SOCK":                  "so",
+	"DOOR":                  "do",
+	"BLK":                   "bd",
+	"BLOCK":                 "bd",
+	"CHR":                   "cd",
+	"CHAR":                  "cd",
+	"ORPHAN":                "or",
+	"MISSING":               "mi",
+	"SETUID":                "su",
+	"SETGID":                "sg",
+	"CAPABILITY":            "ca",
+	"STICKY_OTHER_WRITABLE": "tw",
+	"OTHER_WRITABLE":        "ow",
+	"STICKY":                "st",
+	"EXEC":                  "ex",
+}
+
+// FromEnvironment parses the LS_COLORS environment variable, returning nil if it is not set.
+func FromEnvironment() (*Colors, error) {
+	lsColors := os.Getenv("LS_COLORS")
+	if lsColors == "" {
+		return nil, nil
+	}
+	colors, err := Parse(lsColors)
+	if err != nil {
+		return nil, fmt.Errorf("LS_COLORS: %v", err)
+	}
+	return colors, nil
+}
+
+// Parse parses colours in the format of the LS_COLORS environment variable: a list of entries
+// separated by colons, each of which assigns an SGR sequence to either a two letter type of file,
+// as in "di=01;34", or to a pattern matching the end of file names, as in "*.tar=01;31".
+func Parse(lsColors string) (*Colors, error) {
+	colors := &Colors{types: make(map[string][]int)}
+	for _, entry := range strings.Split(lsColors, ":") {
+		if entry == "" {
+			continue
+		}
+		equals := strings.IndexByte(entry, '=')
+		if equals < 0 {
+			return nil, fmt.Errorf("%q is missing an '='", entry)
+		}
+		if err := colors.add(entry[:equals], entry[equals+1:]); err != nil {
+			return nil, err
+		}
+	}
+	return colors, nil
+}
+
+// ParseDircolors parses colours from a database in the format read by dircolors, in which each line
+// holds either a keyword such as DIR or a pattern such as .tar or *.tar, followed by an SGR
+// sequence. Lines which do not assign colours, such as TERM lines, are ignored, as are comments.
+func ParseDircolors(r io.Reader) (*Colors, error) {
+	colors := &Colors{types: make(map[string][]int)}
+	scanner := bufio.NewScanner(r)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := scanner.Text()
+		if comment := strings.Index(line, "#"); comment == 0 ||
+			(comment > 0 && (line[comment-1] == ' ' || line[comment-1] == '\t')) {
+			line = line[:comment]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("line %d: expected a keyword or pattern followed by a colour",
+				lineNumber)
+		}
+
+		keyword, sequence := fields[0], fields[1]
+		var err error
+		switch {
+		case keyword[0] == '.':
+			err = colors.add("*"+keyword, sequence)
+		case keyword[0] == '*':
+			err = colors.add(keyword, sequence)
+		case dircolorsKeywords[strings.ToUpper(keyword)] != "":
+			err = colors.add(dircolorsKeywords[strings.ToUpper(keyword)], sequence)
+		}
+		// Other keywords, such as TERM, COLOR and OPTIONS, do not assign colours.
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", lineNumber, err)
+		}
+	}
+	return colors, scanner.Err()
+}
+
+// add assigns an SGR sequence to either a two letter type or a pattern beginning with '*'.
+func (c *Colors) add(key, value string) error {
+	sequence, err := parseSequence(value)
+	if err != nil {
+		return fmt.Errorf("%s: %v", key, err)
+	}
+	if strings.HasPrefix(key, "*") {
+		c.extensions = append(c.extensions,
+			extension{suffix: strings.ToLower(key[1:]), sequence: sequence})
+		return nil
+	}
+	c.types[key] = sequence
+	return nil
+}
+
+// parseSequence parses an SGR sequence, a list of numbers separated by semicolons such as "01;34".
+// The special value "target", which colours links as the file which they point to, is returned as
+// a nil sequence.
+func parseSequence(value string) ([]int, error) {
+	if value == "target" {
+		return nil, nil
+	}
+	sequence := []int{}
+	if value == "" {
+		return sequence, nil
+	}
+	for _, code := range strings.Split(value, ";") {
+		n, err := strconv.Atoi(code)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("%q is not a valid colour", value)
+		}
+		sequence = append(sequence, n)
+	}
+	return sequence, nil
+}
+
+// Style returns the style with which a file should be coloured, given its path and the result of
+// calling os.Lstat on it. The style is zero if the file should not be coloured.
+func (c *Colors) Style(path string, info os.FileInfo) textrenderer.Style {
+	return c.StyleWithStat(path, info, os.Stat)
+}
+
+// StyleWithStat returns the style with which a file should be coloured in the same way as Style,
+// finding what a symbolic link refers to with stat rather than os.Stat.
+func (c *Colors) StyleWithStat(path string, info os.FileInfo,
+	stat func(path string) (os.FileInfo, error)) textrenderer.Style {
+	mode := info.Mode()
+	switch {
+	case mode&os.ModeSymlink != 0:
+		target, err := stat(path)
+		if err != nil {
+			return c.first("or", "ln")
+		}
+		if sequence, ok := c.types["ln"]; ok && sequence == nil {
+			return c.StyleWithStat(path, target, stat)
+		}
+		return c.first("ln")
+	case mode.IsDir():
+		switch {
+		case mode&os.ModeSticky != 0 && mode&0002 != 0:
+			return c.first("tw", "ow", "st", "di")
+		case mode&0002 != 0:
+			return c.first("ow", "di")
+		case mode&os.ModeSticky != 0:
+			return c.first("st", "di")
+		}
+		return c.first("di")
+	case mode&os.ModeNamedPipe != 0:
+		return c.first("pi")
+	case mode&os.ModeSocket != 0:
+		return c.first("so")
+	case mode&os.ModeDevice != 0 && mode&os.ModeCharDevice != 0:
+		return c.first("cd")
+	case mode&os.ModeDevice != 0:
+		return c.first("bd")
+	case mode&os.ModeSetuid != 0:
+		return c.first("su", "ex", "fi")
+	case mode&os.ModeSetgid != 0:
+		return c.first("sg", "ex", "fi")
+	case mode&0111 != 0:
+		return c.first("ex", "fi")
+	}
+
+	// As with ls, only regular files which are not executable are coloured by their name.
+	name := strings.ToLower(info.Name())
+	for i := len(c.extensions) - 1; i >= 0; i-- {
+		if strings.HasSuffix(name, c.extensions[i].suffix) {
+			return sgrStyle(c.extensions[i].sequence)
+		}
+	}
+	return c.first("fi")
+}
+
+// first returns the style of the first of a list of types which has been assigned a sequence.
+func (c *Colors) first(types ...string) textrenderer.Style {
+	for _, t := range types {
+		if sequence, ok := c.types[t]; ok && sequence != nil {
+			return sgrStyle(sequence)
+		}
+	}
+	return textrenderer.Style{}
+}
+
+// sgrAttributes maps SGR codes to the termbox attributes which they set.
+var sgrAttributes = map[int]termbox.Attribute{
+	1: termbox.AttrBold,
+	2: termbox.AttrDim,
+	3: termbox.AttrCursive,
+	4: termbox.AttrUnderline,
+	5: termbox.AttrBlink,
+	7: termbox.AttrReverse,
+}
+
+// sgrStyle converts an SGR sequence into a style, in the current output mode. Codes which cannot
+// be drawn by termbox are ignored.
+func sgrStyle(sequence []int) textrenderer.Style {
+	var style textrenderer.Style
+	for i := 0; i < len(sequence); i++ {
+		code := sequence[i]
+		switch {
+		case code == 0:
+			style = textrenderer.Style{}
+		case sgrAttributes[code] != 0:
+			style.Fg |= sgrAttributes[code]
+		case code >= 30 && code <= 37:
+			style.Fg = style.Fg&^colorMask | textrenderer.Color256(uint8(code-30))
+		case code >= 90 && code <= 97:
+			style.Fg = style.Fg&^colorMask | textrenderer.Color256(uint8(code-90+8))
+		case code >= 40 && code <= 47:
+			style.Bg = textrenderer.Color256(uint8(code - 40))
+		case code >= 100 && code <= 107:
+			style.Bg = textrenderer.Color256(uint8(code - 100 + 8))
+		case code == 38 || code == 48:
+			color, consumed := extendedColor(sequence[i+1:])
+			i += consumed
+			if consumed == 0 {
+				continue
+			}
+			if code == 38 {
+				style.Fg = style.Fg&^colorMask | color
+			} else {
+				style.Bg = color
+			}
+		}
+	}
+	return style
+}
+
+// colorMask masks the bits of an attribute which do not hold attributes such as bold.
+const colorMask = ^termbox.Attribute(termbox.AttrBold | termbox.AttrDim | termbox.AttrCursive |
+	termbox.AttrUnderline | termbox.AttrBlink | termbox.AttrReverse | termbox.AttrHidden)
+
+// extendedColor parses the arguments of an extended colour code (38 or 48), which are either 5
+// followed by the index of a colour in a 256 colour palette, or 2 followed by red, green and blue
+// components. It returns the colour along with the number of arguments consumed, which is zero if
+// they are invalid.
+func extendedColor(args []int) (termbox.Attribute, int) {
+	switch {
+	case len(args) >= 2 && args[0] == 5 && args[1] < 256:
+		return textrenderer.Color256(uint8(args[1])), 2
+	case len(args) >= 4 && args[0] == 2 && args[1] < 256 && args[2] < 256 && args[3] < 256:
+		return textrenderer.RGB(uint8(args[1]), uint8(args[2]), uint8(args[3])), 4
+	}
+	return 0, 0
+}
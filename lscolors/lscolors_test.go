@@ -0,0 +1,162 @@
+// Copyright 2019 Max Godfrey
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 		http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lscolors
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/maxgodfrey2004/go-file-manager/textrenderer"
+	"github.com/nsf/termbox-go"
+)
+
+func TestSGRStyle(t *testing.T) {
+	tests := []struct {
+		sequence []int
+		want     textrenderer.Style
+	}{
+		{[]int{}, textrenderer.Style{}},
+		{[]int{1, 34}, textrenderer.Style{Fg: termbox.ColorBlue | termbox.AttrBold}},
+		{[]int{0, 91}, textrenderer.Style{Fg: termbox.ColorLightRed}},
+		{[]int{30, 42}, textrenderer.Style{Fg: termbox.ColorBlack, Bg: termbox.ColorGreen}},
+		{[]int{4, 38, 5, 3}, textrenderer.Style{Fg: termbox.ColorYellow | termbox.AttrUnderline}},
+		{[]int{48, 5, 9}, textrenderer.Style{Bg: termbox.ColorLightRed}},
+		{[]int{31, 1, 32}, textrenderer.Style{Fg: termbox.ColorGreen | termbox.AttrBold}},
+		{[]int{38, 9, 1}, textrenderer.Style{Fg: termbox.AttrBold}},
+	}
+	for _, test := range tests {
+		if got := sgrStyle(test.sequence); got != test.want {
+			t.Errorf("sgrStyle(%v) = %+v, want %+v", test.sequence, got, test.want)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, lsColors := range []string{"di", "di=01;x4", "*.tar=-1"} {
+		if _, err := Parse(lsColors); err == nil {
+			t.Errorf("Parse(%q) did not return an error", lsColors)
+		}
+	}
+	if _, err := ParseDircolors(strings.NewReader("TERM xterm\nDIR 01;34 extra\n")); err == nil ||
+		!strings.Contains(err.Error(), "line 2") {
+		t.Errorf("ParseDircolors returned %v, want an error on line 2", err)
+	}
+}
+
+// makeFiles creates a directory holding a directory, an executable, a tar archive, a text file, a
+// link to the directory and a link to a file which does not exist, returning its path and a
+// function which removes it.
+func makeFiles(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "lscolors")
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]os.FileMode{"run.sh": 0755, "backup.TAR": 0644, "notes.txt": 0644}
+	for name, perm := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), nil, perm); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(dir, "docs"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("docs", filepath.Join(dir, "link")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("missing", filepath.Join(dir, "orphan")); err != nil {
+		t.Fatal(err)
+	}
+	return dir, func() { os.RemoveAll(dir) }
+}
+
+// styleOf returns the style of a file within dir.
+func styleOf(t *testing.T, colors *Colors, dir, name string) textrenderer.Style {
+	path := filepath.Join(dir, name)
+	info, err := os.Lstat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return colors.Style(path, info)
+}
+
+func TestStyle(t *testing.T) {
+	dir, cleanup := makeFiles(t)
+	defer cleanup()
+
+	colors, err := Parse("di=01;34:ln=36:or=31:ex=32:*.tar=33:*.txt=35:*notes.txt=0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := map[string]textrenderer.Style{
+		"docs":       {Fg: termbox.ColorBlue | termbox.AttrBold},
+		"link":       {Fg: termbox.ColorCyan},
+		"orphan":     {Fg: termbox.ColorRed},
+		"run.sh":     {Fg: termbox.ColorGreen},
+		"backup.TAR": {Fg: termbox.ColorYellow},
+		"notes.txt":  {}, // The later pattern takes precedence.
+	}
+	for name, want := range tests {
+		if got := styleOf(t, colors, dir, name); got != want {
+			t.Errorf("style of %s = %+v, want %+v", name, got, want)
+		}
+	}
+
+	// With ln=target, links are coloured as the file which they point to.
+	colors, err = Parse("di=34:ln=target:or=31")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, want := styleOf(t, colors, dir, "link"), textrenderer.Style{Fg: termbox.ColorBlue}
+	if got != want {
+		t.Errorf("style of link with ln=target = %+v, want %+v", got, want)
+	}
+	got, want = styleOf(t, colors, dir, "orphan"), textrenderer.Style{Fg: termbox.ColorRed}
+	if got != want {
+		t.Errorf("style of orphan with ln=target = %+v, want %+v", got, want)
+	}
+}
+
+func TestParseDircolors(t *testing.T) {
+	dir, cleanup := makeFiles(t)
+	defer cleanup()
+
+	database := `# A dircolors database
+TERM xterm-256color
+COLOR tty
+DIR 01;34 # directories
+EXEC 01;32
+.tar 01;31
+*.txt 38;5;208
+`
+	colors, err := ParseDircolors(strings.NewReader(database))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := map[string]textrenderer.Style{
+		"docs":       {Fg: termbox.ColorBlue | termbox.AttrBold},
+		"run.sh":     {Fg: termbox.ColorGreen | termbox.AttrBold},
+		"backup.TAR": {Fg: termbox.ColorRed | termbox.AttrBold},
+		"notes.txt":  {Fg: textrenderer.Color256(208)},
+		"link":       {},
+	}
+	for name, want := range tests {
+		if got := styleOf(t, colors, dir, name); got != want {
+			t.Errorf("style of %s = %+v, want %+v", name, got, want)
+		}
+	}
+}
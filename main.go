@@ -15,9 +15,15 @@
 package main
 
 import (
+	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 
+	"github.com/maxgodfrey2004/go-file-manager/config"
 	"github.com/maxgodfrey2004/go-file-manager/explorer"
+	"github.com/maxgodfrey2004/go-file-manager/keymap"
+	"github.com/maxgodfrey2004/go-file-manager/lscolors"
 	"github.com/maxgodfrey2004/go-file-manager/textrenderer"
 	"github.com/nsf/termbox-go"
 )
@@ -38,8 +44,135 @@ const (
 	// directory contents whose names contain leading `.` characters.
 	ToggleListAll
 
+	// Redraw represents the terminal being resized, requiring everything to be rendered again.
+	Redraw
+
 	// Quit represents the termination of the application.
 	Quit
+
+	// Keypress represents the user pressing a key, which is resolved into one of the other events
+	// through the keymap.
+	Keypress
+
+	// HalfPage represents the user moving the caret by half of the height of the screen.
+	HalfPage
+
+	// Page represents the user moving the caret by the height of the screen.
+	Page
+
+	// Jump represents the user moving the caret to the first or last file or directory, or to the
+	// one at the position given by a count.
+	Jump
+
+	// Parent represents the user moving to the parent of the current directory.
+	Parent
+
+	// Search represents the user beginning to type a search for a file or directory.
+	Search
+
+	// SearchNext represents the user moving to the next or previous match of the last search.
+	SearchNext
+
+	// Command represents the user beginning to type a command.
+	Command
+
+	// Confirm represents the user confirming the search or command which they have typed.
+	Confirm
+
+	// Cancel represents the user abandoning the search or command which they are typing.
+	Cancel
+
+	// DeleteChar represents the user deleting the last character typed into a prompt.
+	DeleteChar
+
+	// InsertChar represents the user typing a character into a prompt.
+	InsertChar
+
+	// Mouse represents the user clicking or scrolling with the mouse.
+	Mouse
+
+	// Fatal represents an error reading events from the terminal, from which the application
+	// cannot recover.
+	Fatal
+
+	// NewTab represents the user opening a new tab in the current directory.
+	NewTab
+
+	// CloseTab represents the user closing the current tab.
+	CloseTab
+
+	// SwitchTab represents the user switching to the next or previous tab, or to the tab at the
+	// position given by a count.
+	SwitchTab
+
+	// ToggleDualPane represents the user switching between displaying a preview and displaying a
+	// second pane.
+	ToggleDualPane
+
+	// SwitchPane represents the user giving focus to the other pane in dual-pane mode.
+	SwitchPane
+
+	// CopyEntry represents the user beginning to copy the current selected file or directory.
+	CopyEntry
+
+	// MoveEntry represents the user beginning to move the current selected file or directory.
+	MoveEntry
+
+	// DeleteEntry represents the user beginning to delete the current selected file or directory.
+	DeleteEntry
+
+	// ToggleTree represents the user switching between listing the current directory alone and
+	// listing it as a tree.
+	ToggleTree
+
+	// ToggleExpand represents the user expanding or collapsing a directory in tree mode.
+	ToggleExpand
+
+	// CycleSort represents the user changing the order in which the current tab lists the
+	// contents of directories.
+	CycleSort
+
+	// ToggleMark represents the user marking or unmarking the current selected file or directory.
+	ToggleMark
+
+	// UnmarkAll represents the user unmarking every file and directory which has been marked.
+	UnmarkAll
+
+	// BulkRename represents the user renaming the marked files and directories in their editor.
+	BulkRename
+
+	// PatternRename represents the user beginning to rename the marked files and directories
+	// according to a rule.
+	PatternRename
+
+	// NewFile represents the user beginning to create a file in the current directory.
+	NewFile
+
+	// NewDirectory represents the user beginning to create a directory in the current directory.
+	NewDirectory
+
+	// NewSymlink represents the user beginning to create a symbolic link to the current selected
+	// file or directory.
+	NewSymlink
+
+	// NewHardlink represents the user beginning to create a hard link to the current selected file.
+	NewHardlink
+
+	// OpenProperties represents the user opening the properties dialog, in which the permissions
+	// and ownership of the marked or selected files and directories are changed.
+	OpenProperties
+
+	// MoveField represents the user moving to the field of the properties dialog above or below
+	// the current one.
+	MoveField
+
+	// MoveColumn represents the user moving to the column of the grid of permissions in the
+	// properties dialog to the left (Up) or right (Down) of the current one.
+	MoveColumn
+
+	// ToggleField represents the user toggling the checkbox of the properties dialog which has
+	// focus.
+	ToggleField
 )
 
 // Movement directions
@@ -51,7 +184,14 @@ const (
 // keypress represents a physical key being pressed on the keyboard.
 type keypress struct {
 	EventType KeyEvent
-	Key       termbox.Key
+	Direction int        // The direction in which the event moves, for events which move.
+	Count     int        // The count typed before the event, or 0 if none was typed.
+	Key       keymap.Key // The key which was pressed, for Keypress and InsertChar events.
+
+	Button termbox.Key // The mouse button which was pressed, for Mouse events.
+	X, Y   int         // The position of the mouse, for Mouse events.
+
+	Err error // The error which occurred, for Fatal events.
 }
 
 var (
@@ -66,186 +206,490 @@ var (
 	// listAll is used to determine whether the user wishes to see directory contents with
 	// a leading `.`. By default, we assume that they do not.
 	listAll = false
+
+	// userConfig holds the user's configuration, which is loaded when the application starts.
+	userConfig = config.Default()
+
+	// entryColors colours entries by their type and name, or is nil if they are only coloured by
+	// the theme.
+	entryColors *lscolors.Colors
 )
 
 // listenForEvents indefinitely listens for termbox events. Any events that take the form of
 // keyboard input are sent to a specified channel, where they will be proecessed externally.
 // Note that this method is intended to be called asynchronously (ie. as a goroutine).
 func listenForEvents(ch chan keypress) {
-	termbox.SetInputMode(termbox.InputEsc)
+	termbox.SetInputMode(termbox.InputAlt | termbox.InputMouse)
 
 	for {
 		switch ev := termbox.PollEvent(); ev.Type {
 		case termbox.EventKey:
-			switch ev.Key {
-			case termbox.KeyArrowDown:
-				ch <- keypress{EventType: Reselect, Key: ev.Key}
-			case termbox.KeyArrowUp:
-				ch <- keypress{EventType: Reselect, Key: ev.Key}
-			case termbox.KeyArrowRight, termbox.KeyEnter:
-				ch <- keypress{EventType: Select, Key: ev.Key}
-			case termbox.KeyCtrlC:
-				ch <- keypress{EventType: Quit, Key: ev.Key}
-			default:
-				switch ev.Ch {
-				case rune('Q'), rune('q'):
-					ch <- keypress{EventType: Quit, Key: ev.Key}
-				case rune('A'), rune('a'):
-					ch <- keypress{EventType: ToggleListAll, Key: ev.Key}
-				}
+			ch <- keypress{EventType: Keypress, Key: keymap.FromEvent(ev)}
+		case termbox.EventMouse:
+			// Releasing a button, and moving the mouse while it is held, are not acted upon.
+			if ev.Key != termbox.MouseRelease && ev.Mod&termbox.ModMotion == 0 {
+				ch <- keypress{EventType: Mouse, Button: ev.Key, X: ev.MouseX, Y: ev.MouseY}
 			}
 		case termbox.EventError:
-			panic(ev.Err)
+			ch <- keypress{EventType: Fatal, Err: ev.Err}
+			return
 		case termbox.EventInterrupt:
 			return
 		case termbox.EventResize:
-			screen.Render(genPreview())
+			ch <- keypress{EventType: Redraw}
 		}
 	}
 }
 
 // moveDirectory moves nav, the explorer, to the current directory which the user has selected.
 func moveDirectory() {
-	nextDir := screen.CurrentSelected()
-	if err := explorer.DirectoryExists(nav.GetPath() + nextDir); err == nil {
-		if err := nav.MoveOne(nextDir); err != nil {
-			panic(err)
-		}
-		dirContents, err := nav.List(listAll)
-		if err != nil {
-			panic(nav.Path)
-		}
-		screen.Init(nav.GetPath(), dirContents)
-		screen.Display(nav.GetPath(), dirContents, genPreview())
+	moveTo(screen.CurrentSelected())
+}
+
+// moveParent moves nav, the explorer, to the parent of the current directory, selecting the
+// directory which it has moved out of.
+func moveParent() {
+	if nav.Path == "" {
+		return
+	}
+	previous := filepath.Base(nav.Path) + explorer.PathSep
+	if moveTo("..") {
+		selectName(previous)
 	}
 }
 
-// keyToDirection converts a termbox Key code into a direction for the selected file or directory
-// when the up or down arrow keys are pressed.
-func keyToDirection(key termbox.Key) int {
-	switch key {
-	case termbox.KeyArrowDown:
-		return Down
-	case termbox.KeyArrowUp:
-		return Up
-	default:
-		return 0
+// moveTo moves nav, the explorer, to a directory adjacent to the one it is in and lists its
+// contents, reporting whether it moved. If the directory cannot be listed, the explorer stays
+// where it is and the error is reported on the status line.
+func moveTo(nextDir string) bool {
+	previous := nav
+	if err := nav.MoveOne(nextDir); err != nil {
+		report(err)
+		return false
 	}
+	if err := listDirectory(); err != nil {
+		nav = previous
+		report(err)
+		return false
+	}
+	return true
 }
 
-// genPreview returns a preview of the current selected file or directory.
-func genPreview() []string {
-	curSelected := screen.CurrentSelected()
-	var err error
-	var preview []string
-	if curSelected[len(curSelected)-1] != explorer.PathSepChar {
-		preview, err = nav.ReadN(curSelected, screen.PreviewHeight())
-		if err != nil {
-			panic(err)
+// listDirectory displays the contents of the directory which nav, the explorer, is in. If they
+// cannot be listed, the screen is left as it is and the error is returned.
+func listDirectory() error {
+	dirContents, guides, infos, err := listEntries()
+	if err != nil {
+		return err
+	}
+	showDirectory(dirContents, guides, infos)
+	return nil
+}
+
+// reloadDirectory lists the contents of the current directory again, keeping the caret on the
+// entry which was selected if it still exists.
+func reloadDirectory() error {
+	selected := screen.CurrentSelected()
+	if err := listDirectory(); err != nil {
+		return err
+	}
+	selectName(selected)
+	return nil
+}
+
+// showDirectory displays the contents of the directory which nav, the explorer, is in, along with
+// the tree guide drawn before each of them in tree mode. They are coloured according to the
+// information about each of them.
+func showDirectory(dirContents, guides []string, infos []os.FileInfo) {
+	screen.Init(nav.Location(), dirContents)
+	screen.Styles = entryStyles(nav, dirContents, infos)
+	screen.Guides = guides
+	screen.Marked = entryMarks(nav, dirContents)
+	updateParents()
+	updateTabLabels()
+	screen.Display(nav.Location(), dirContents, requestPreview())
+}
+
+// selectName moves the caret to the entry with the given name, reporting whether there is one.
+func selectName(name string) bool {
+	for i, entry := range screen.Text {
+		if entry == name {
+			selectIndex(i)
+			return true
 		}
-	} else {
-		preview, err = nav.ListN(curSelected, screen.PreviewHeight(), listAll)
-		if err != nil {
-			panic(err)
+	}
+	return false
+}
+
+// entryStyles returns the style with which each entry of the directory which an explorer is in is
+// coloured according to entryColors, or nil if entries are only coloured by the theme. Each entry
+// is coloured by the information about it which was read with the directory, so that only the
+// parent directory, whose information is nil, and the targets of symbolic links are looked up.
+func entryStyles(e explorer.Explorer, names []string, infos []os.FileInfo) []textrenderer.Style {
+	if entryColors == nil {
+		return nil
+	}
+	styles := make([]textrenderer.Style, len(names))
+	for i, name := range names {
+		name = strings.TrimSuffix(name, explorer.PathSep)
+		info := infos[i]
+		if info == nil {
+			var err error
+			if info, err = e.Lstat(name); err != nil {
+				continue
+			}
 		}
+		stat := func(string) (os.FileInfo, error) { return e.Stat(name) }
+		styles[i] = entryColors.StyleWithStat(e.GetPath()+name, info, stat)
 	}
+	return styles
+}
 
-	return preview
+// reselect moves the screen's display of files when the user presses a navigation key, moving the
+// caret by the count typed before the key.
+func reselect(ev keypress) {
+	selectIndex(screen.SelectedIndex + ev.Direction*max(ev.Count, 1))
 }
 
-// reselect moves the screen's display of files when the user presses either an up or down arrow
+// page moves the caret by the height of the screen, once for each of the count typed before the
 // key.
-func reselect(ev keypress) {
-	newIndex := screen.SelectedIndex + keyToDirection(ev.Key)
-	if newIndex < 0 || newIndex >= len(screen.Text) {
-		return
-	}
+func page(ev keypress) {
+	_, height := screen.TextViewSize()
+	selectIndex(screen.SelectedIndex + ev.Direction*max(height, 1)*max(ev.Count, 1))
+}
 
+// halfPage moves the caret by half of the height of the screen, once for each of the count typed
+// before the key.
+func halfPage(ev keypress) {
 	_, height := screen.TextViewSize()
-	screen.SelectedIndex = newIndex
-	if newIndex >= screen.StartIndex+height {
-		screen.StartIndex++
-	} else if newIndex < screen.StartIndex {
-		screen.StartIndex--
+	selectIndex(screen.SelectedIndex + ev.Direction*max(height/2, 1)*max(ev.Count, 1))
+}
+
+// jump moves the caret to the file or directory at the position given by the count typed before
+// the key, counting from 1, or otherwise to the first or last one.
+func jump(ev keypress) {
+	switch {
+	case ev.Count > 0:
+		selectIndex(ev.Count - 1)
+	case ev.Direction == Up:
+		selectIndex(0)
+	default:
+		selectIndex(len(screen.Text) - 1)
+	}
+}
+
+// selectIndex moves the caret to an index in the screen's text and renders the screen.
+func selectIndex(index int) {
+	screen.Select(index)
+	screen.Render(requestPreview())
+}
+
+// max returns the maximum of two integers.
+func max(a, b int) int {
+	if a > b {
+		return a
 	}
-	screen.Render(genPreview())
+	return b
 }
 
 // selectContents is called when the user selects either a file or a directory. It in turn will
 // either open an editor with the selected file, or move to the selected directory.
 func selectContents() {
 	curSelected := screen.CurrentSelected()
+	if curSelected == "" {
+		return
+	}
 	if curSelected[len(curSelected)-1] == explorer.PathSepChar {
 		moveDirectory()
-	} else {
-		pathCopy := nav.GetPath()
-		if err := nav.View(curSelected); err != nil {
-			panic(err)
+		return
+	}
+
+	err := suspend(func() error {
+		if command := userConfig.OpenerFor(curSelected); command != nil {
+			return nav.OpenWith(command, curSelected)
 		}
-		termbox.Interrupt()
-		startExplorer(pathCopy)
-		/*termbox.Clear(termbox.ColorDefault, termbox.ColorDefault)
+		return nav.View(curSelected)
+	})
+	// The file may have been changed, or others created, while it was open.
+	if reloadErr := reloadDirectory(); reloadErr != nil {
+		report(reloadErr)
+	}
+	if err != nil {
+		report(fmt.Errorf("%s: %v", curSelected, err))
+	}
+}
+
+// suspend closes termbox so that a command may take over the terminal, running it and then
+// restoring the explorer once it has finished. The command's error is returned.
+func suspend(run func() error) error {
+	stopListening()
+	termbox.Close()
+	err := run()
+	if initErr := initTerminal(); initErr != nil {
+		fatal(initErr)
+	}
+	go listenForEvents(keypressChan)
+	return err
+}
 
-		dirContents, err := nav.List(listAll)
-		if err != nil {
-			panic(err)
+// stopListening stops the goroutine which listens for termbox events. Events which it sends while
+// it is being stopped are discarded.
+func stopListening() {
+	stopped := make(chan struct{})
+	go func() {
+		termbox.Interrupt()
+		close(stopped)
+	}()
+	for {
+		select {
+		case <-keypressChan:
+		case <-stopped:
+			return
 		}
-		screen.Init(nav.GetPath(), dirContents)
-		screen.Display(nav.GetPath(), dirContents, genPreview())*/
 	}
 }
 
-// startExplorer runs the file manager until a Quit event is sent.
-func startExplorer(startDirectory string) {
+// initTerminal initialises termbox and the colours with which the explorer is drawn.
+func initTerminal() error {
 	if err := termbox.Init(); err != nil {
-		panic(err)
+		return err
+	}
+	textrenderer.InitColors()
+	return nil
+}
+
+// startExplorer runs the file manager until a Quit event is sent. If the terminal cannot be used,
+// an error is returned.
+func startExplorer() error {
+	if err := initTerminal(); err != nil {
+		return err
+	}
+	defer recoverFatal()
+
+	screen.Theme = userConfig.TextrendererTheme()
+	screen.ScrollOff = userConfig.Options.ScrollOff
+	screen.KeyFunctions, keyFunctionNames = keyFunctions()
+	screen.DualPane = userConfig.Options.Layout == config.LayoutDual
+	if userConfig.Options.Layout == config.LayoutColumns {
+		screen.ColumnWidths = userConfig.Options.ColumnWidths
 	}
 
 	keypressChan = make(chan keypress)
-	if err := nav.MoveAbsolute(startDirectory); err != nil {
-		panic(startDirectory)
+	startWatching()
+	dirContents, guides, infos, err := listEntries()
+	if screen.DualPane {
+		// Both panes begin in the start directory.
+		screen.Init(nav.Location(), dirContents)
+		otherPane = currentPane().clone()
+		updateOtherPane()
 	}
-	dirContents, err := nav.List(listAll)
+	showDirectory(dirContents, guides, infos)
 	if err != nil {
-		panic(err)
-	}
-	screen.KeyFunctions = []string{
-		"[Q|q: Quit]",
-		"[A|a: List]",
+		report(err)
 	}
-	screen.Init(nav.GetPath(), dirContents)
-	screen.Display(nav.GetPath(), dirContents, genPreview())
 
 	go listenForEvents(keypressChan)
 
 	for {
 		select {
 		case ev := <-keypressChan:
-			switch ev.EventType {
-			case Reselect:
-				reselect(ev)
-			case Select:
-				selectContents()
-			case ToggleListAll:
-				toggleListAll()
-			case Quit:
-				termbox.Close()
-				os.Exit(0)
+			handleEvent(ev)
+			if refreshPending && mode == NormalMode {
+				refresh()
 			}
+		case result := <-previewChan:
+			receivePreview(result)
+		case <-watchChanges():
+			refresh()
+		case line := <-serverLog:
+			logRequest(line)
+		case result := <-dialled():
+			receiveDial(result)
+		case result := <-transferred():
+			receiveTransfer(result)
+		case description := <-panics:
+			exitPanic(description)
+		case p := <-watchPanics():
+			exitPanic(fmt.Sprintf("%v\n%s", p.Value, p.Stack))
+		}
+	}
+}
+
+// handleEvent performs the action which an event represents.
+func handleEvent(ev keypress) {
+	switch ev.EventType {
+	case Reselect:
+		reselect(ev)
+	case Select:
+		selectContents()
+	case ToggleListAll:
+		toggleListAll()
+	case Redraw:
+		// The view is scrolled again, as its height may have changed.
+		screen.Select(screen.SelectedIndex)
+		screen.Render(requestPreview())
+	case Quit:
+		termbox.Close()
+		closeConnections()
+		os.Exit(0)
+	case Keypress:
+		clearMessage()
+		if ev, ok := resolveKey(ev.Key); ok {
+			handleEvent(ev)
+		}
+	case Mouse:
+		clearMessage()
+		handleMouse(ev)
+	case Fatal:
+		fatal(ev.Err)
+	case NewTab:
+		newTab()
+	case CloseTab:
+		if err := closeTab(); err != nil {
+			report(err)
+		}
+	case SwitchTab:
+		switchTab(ev)
+	case ToggleDualPane:
+		toggleDualPane()
+	case SwitchPane:
+		switchPane()
+	case CopyEntry:
+		promptTransfer("copy")
+	case MoveEntry:
+		promptTransfer("move")
+	case DeleteEntry:
+		promptDelete()
+	case ToggleTree:
+		toggleTree()
+	case ToggleExpand:
+		toggleExpand()
+	case CycleSort:
+		cycleSort()
+	case ToggleMark:
+		toggleMark(ev)
+	case UnmarkAll:
+		unmarkAll()
+	case BulkRename:
+		bulkRename()
+	case PatternRename:
+		promptRename()
+	case NewFile:
+		promptCreate("touch")
+	case NewDirectory:
+		promptCreate("mkdir")
+	case NewSymlink:
+		promptLink("symlink")
+	case NewHardlink:
+		promptLink("link")
+	case OpenProperties:
+		openProperties()
+	case MoveField:
+		moveField(ev)
+	case MoveColumn:
+		moveColumn(ev)
+	case ToggleField:
+		toggleField()
+	case Page:
+		page(ev)
+	case HalfPage:
+		halfPage(ev)
+	case Jump:
+		jump(ev)
+	case Parent:
+		for i := 0; i < max(ev.Count, 1); i++ {
+			moveParent()
+		}
+	case Search:
+		openPrompt(SearchMode)
+	case SearchNext:
+		searchNext(ev)
+	case Command:
+		openPrompt(CommandMode)
+	case Confirm:
+		if mode == PropertiesMode {
+			applyProperties()
+		} else {
+			confirmPrompt()
+		}
+	case Cancel:
+		if mode == PropertiesMode {
+			closeProperties()
+		} else {
+			cancelPrompt()
+		}
+	case DeleteChar:
+		if mode == PropertiesMode {
+			deleteFieldChar()
+		} else {
+			deleteChar()
+		}
+	case InsertChar:
+		if mode == PropertiesMode {
+			insertFieldChar(ev.Key.Ch)
+		} else {
+			insertChar(ev.Key.Ch)
 		}
 	}
 }
 
 func toggleListAll() {
 	listAll = !listAll
-	dirContents, err := nav.List(listAll)
+	if err := reloadDirectory(); err != nil {
+		listAll = !listAll
+		report(err)
+	}
+}
+
+// cycleSort lists the contents of directories in the next sort order of the current tab: by name,
+// size or modification time in turn.
+func cycleSort() {
+	previous := nav.Sort
+	nav.Sort = (nav.Sort + 1) % (explorer.SortByTime + 1)
+	if err := reloadDirectory(); err != nil {
+		nav.Sort = previous
+		report(err)
+		return
+	}
+	showMessage(textrenderer.Info, "Sorted by "+nav.Sort.String())
+}
+
+// loadConfig loads the user's configuration file from the configuration directory, and the
+// keymap which it describes.
+func loadConfig() (config.Config, error) {
+	dir, err := config.Dir()
+	if err != nil {
+		return config.Default(), err
+	}
+	path := filepath.Join(dir, config.FileName)
+	userConfig, err := config.Load(path)
 	if err != nil {
-		panic(err)
+		return userConfig, err
+	}
+	if err := loadKeymap(userConfig.Options.KeyPreset, userConfig.Keys); err != nil {
+		return userConfig, fmt.Errorf("%s: %v", path, err)
 	}
-	screen.Init(nav.GetPath(), dirContents)
-	screen.Display(nav.GetPath(), dirContents, genPreview())
+	if entryColors, err = userConfig.LSColors(); err != nil {
+		return userConfig, fmt.Errorf("%s: theme: %v", path, err)
+	}
+	return userConfig, nil
 }
 
 func main() {
-	startExplorer("~")
+	var err error
+	if userConfig, err = loadConfig(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	listAll = userConfig.Options.ListAll
+	nav.Editor = userConfig.Options.Editor
+	if err = nav.MoveAbsolute(userConfig.Options.StartDirectory); err != nil {
+		fmt.Fprintf(os.Stderr, "cannot start in %s: %v\n", userConfig.Options.StartDirectory, err)
+		os.Exit(1)
+	}
+
+	if err = startExplorer(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
 }
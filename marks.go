@@ -0,0 +1,107 @@
+// Copyright 2019 Max Godfrey
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 		http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"strings"
+
+	"github.com/maxgodfrey2004/go-file-manager/explorer"
+)
+
+// marked holds the location of each entry which the user has marked, as given by markLocation.
+// Entries stay marked as the user moves between directories, so that entries of several
+// directories may be marked at once.
+var marked = make(map[string]bool)
+
+// markLocation returns the location by which an entry of the directory which an explorer is in is
+// marked: its path, preceded by the URL of any remote host on which it lies.
+func markLocation(e explorer.Explorer, name string) string {
+	return e.Location() + strings.TrimSuffix(name, explorer.PathSep)
+}
+
+// entryMarks returns whether each entry of the directory which an explorer is in is marked, or nil
+// if nothing is marked.
+func entryMarks(e explorer.Explorer, names []string) []bool {
+	if len(marked) == 0 {
+		return nil
+	}
+	marks := make([]bool, len(names))
+	for i, name := range names {
+		marks[i] = marked[markLocation(e, name)]
+	}
+	return marks
+}
+
+// isMarkable reports whether an entry of a listing may be marked, which every entry but the parent
+// directory may be.
+func isMarkable(name string) bool {
+	return name != "" && name != ".."+explorer.PathSep
+}
+
+// toggleMark marks the current selected entry, or unmarks it if it is already marked, and moves the
+// caret to the next entry, once for each of the count typed before the key.
+func toggleMark(ev keypress) {
+	for i := 0; i < max(ev.Count, 1) && screen.SelectedIndex < len(screen.Text); i++ {
+		if name := screen.CurrentSelected(); isMarkable(name) {
+			location := markLocation(nav, name)
+			if marked[location] {
+				delete(marked, location)
+			} else {
+				marked[location] = true
+			}
+		}
+		if screen.SelectedIndex == len(screen.Text)-1 {
+			break
+		}
+		screen.Select(screen.SelectedIndex + 1)
+	}
+	showMarks()
+	screen.Render(requestPreview())
+}
+
+// unmarkAll unmarks every entry which has been marked, in any directory.
+func unmarkAll() {
+	marked = make(map[string]bool)
+	showMarks()
+	screen.Render(requestPreview())
+}
+
+// showMarks updates which of the entries on the screen are shown to be marked, without rendering
+// the screen.
+func showMarks() {
+	screen.Marked = entryMarks(nav, screen.Text)
+	if screen.DualPane {
+		screen.OtherPane.Marked = entryMarks(otherPane.nav, screen.OtherPane.Text)
+	}
+}
+
+// markedNames returns the names of the marked entries of the current directory, in the order in
+// which they are listed.
+func markedNames() []string {
+	var names []string
+	for _, name := range screen.Text {
+		if isMarkable(name) && marked[markLocation(nav, name)] {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// unmark unmarks entries of the current directory.
+func unmark(names []string) {
+	for _, name := range names {
+		delete(marked, markLocation(nav, name))
+	}
+}
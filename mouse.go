@@ -0,0 +1,89 @@
+// Copyright 2019 Max Godfrey
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 		http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"time"
+
+	"github.com/nsf/termbox-go"
+)
+
+// doubleClickInterval is the longest time between two clicks on the same entry for them to be
+// treated as a double click.
+const doubleClickInterval = 400 * time.Millisecond
+
+// wheelLines is the number of lines moved by each step of the mouse wheel.
+const wheelLines = 3
+
+var (
+	// keyFunctionNames holds the name of the action described by each of the screen's key
+	// functions, so that they may be performed by clicking on them.
+	keyFunctionNames []string
+
+	// lastClickIndex is the index of the entry which was most recently clicked, or -1.
+	lastClickIndex = -1
+
+	// lastClickTime is the time at which an entry was most recently clicked.
+	lastClickTime time.Time
+)
+
+// handleMouse performs the action of a mouse event. Clicking an entry selects it, and clicking it
+// again within doubleClickInterval opens it. Clicking a key function performs its action. The
+// mouse wheel moves the caret when it is over the list of entries, and scrolls the preview when it
+// is over the preview. The mouse is ignored while the user is typing into a prompt.
+func handleMouse(ev keypress) {
+	if mode != NormalMode {
+		return
+	}
+
+	switch ev.Button {
+	case termbox.MouseLeft:
+		// Clicking the pane without focus gives it focus.
+		_, height := screen.TextViewSize()
+		if screen.DualPane && ev.Y > 0 && ev.Y <= height && screen.PaneAt(ev.X) != screen.ActivePane {
+			switchPane()
+		}
+		if index := screen.EntryAt(ev.X, ev.Y); index >= 0 {
+			clickEntry(index)
+		} else if i := screen.KeyFunctionAt(ev.X, ev.Y); i >= 0 {
+			a := actions[keyFunctionNames[i]]
+			handleEvent(keypress{EventType: a.event, Direction: a.direction})
+		}
+	case termbox.MouseWheelUp, termbox.MouseWheelDown:
+		direction := Down
+		if ev.Button == termbox.MouseWheelUp {
+			direction = Up
+		}
+		if screen.InPreview(ev.X, ev.Y) {
+			scrollPreview(direction * wheelLines)
+		} else {
+			selectIndex(screen.SelectedIndex + direction*wheelLines)
+		}
+	}
+}
+
+// clickEntry selects the entry at an index in the screen's text, opening it if it was already
+// clicked within doubleClickInterval.
+func clickEntry(index int) {
+	now := time.Now()
+	if index == lastClickIndex && now.Sub(lastClickTime) <= doubleClickInterval {
+		lastClickIndex = -1
+		selectContents()
+		return
+	}
+	lastClickIndex = index
+	lastClickTime = now
+	selectIndex(index)
+}
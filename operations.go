@@ -0,0 +1,53 @@
+// Copyright 2019 Max Godfrey
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 		http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/maxgodfrey2004/go-file-manager/explorer"
+	"github.com/maxgodfrey2004/go-file-manager/textrenderer"
+)
+
+// promptDelete begins typing a command which deletes the current selected file or directory, which
+// is only deleted once the user confirms the command.
+func promptDelete() {
+	openPrompt(CommandMode)
+	input = []rune("delete " + screen.CurrentSelected())
+	renderPrompt()
+}
+
+// deleteEntry deletes a file or directory within the current directory, along with all of the
+// contents of a directory. The caret stays at the same position in the list.
+func deleteEntry(command, name string) error {
+	if name == "" {
+		return fmt.Errorf("%s: nothing to delete was given", command)
+	}
+	if err := nav.Remove(name); err != nil {
+		return fmt.Errorf("%s %s: %v", command, name, err)
+	}
+
+	index := screen.SelectedIndex
+	if err := listDirectory(); err != nil {
+		return err
+	}
+	if err := updateOtherPane(); err != nil {
+		return err
+	}
+	selectIndex(index)
+	showMessage(textrenderer.Info, "Deleted "+strings.TrimSuffix(name, explorer.PathSep))
+	return nil
+}
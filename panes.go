@@ -0,0 +1,326 @@
+// Copyright 2019 Max Godfrey
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 		http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/maxgodfrey2004/go-file-manager/explorer"
+	"github.com/maxgodfrey2004/go-file-manager/filesystem"
+	"github.com/maxgodfrey2004/go-file-manager/textrenderer"
+)
+
+// pane holds the state of a listing of a directory which is not being displayed with focus, so that
+// it may be restored when the user switches to it.
+type pane struct {
+	nav        explorer.Explorer // The explorer of the pane.
+	listAll    bool              // Whether the pane lists entries beginning with a '.'.
+	selected   string            // The name of the entry which was selected.
+	startIndex int               // The index from which the pane's entries were rendered.
+	treeMode   bool              // Whether the pane lists its directory as a tree.
+	expanded   map[string]bool   // The path of each directory expanded within the tree.
+}
+
+// otherPane holds the state of the pane without focus in dual-pane mode. The state of the pane with
+// focus is held by nav, listAll, treeMode, expanded and screen.
+var otherPane pane
+
+// parentKey identifies the listing of an ancestor of the current directory.
+type parentKey struct {
+	location string             // The location of the ancestor.
+	listAll  bool               // Whether entries beginning with a '.' were listed.
+	sort     explorer.SortOrder // The order in which the entries were listed.
+}
+
+// parentListing is the listing of an ancestor of the current directory, as it was displayed.
+type parentListing struct {
+	modTime time.Time // When the ancestor had last been modified as it was listed.
+	parent  textrenderer.Pane
+}
+
+// parentListings holds the listing of each ancestor which was displayed for the current directory,
+// so that moving between directories only lists the ancestors which are new, or which have been
+// modified since they were listed.
+var parentListings = make(map[parentKey]parentListing)
+
+// currentPane returns the state of the pane with focus.
+func currentPane() pane {
+	return pane{
+		nav:        nav,
+		listAll:    listAll,
+		selected:   screen.CurrentSelected(),
+		startIndex: screen.StartIndex,
+		treeMode:   treeMode,
+		expanded:   expanded,
+	}
+}
+
+// clone returns a copy of a pane, whose directories may be expanded and collapsed without
+// affecting the pane which it was copied from.
+func (p pane) clone() pane {
+	expanded := make(map[string]bool, len(p.expanded))
+	for path := range p.expanded {
+		expanded[path] = true
+	}
+	p.expanded = expanded
+	return p
+}
+
+// showPane gives focus to a pane, restoring its state without rendering the screen. If its
+// directory cannot be listed, as much of it as was listed is shown and the error is returned.
+func showPane(p pane) error {
+	nav = p.nav
+	listAll = p.listAll
+	treeMode = p.treeMode
+	expanded = p.expanded
+	if expanded == nil {
+		expanded = make(map[string]bool)
+	}
+	dirContents, guides, infos, err := listEntries()
+	screen.Init(nav.Location(), dirContents)
+	screen.Styles = entryStyles(nav, dirContents, infos)
+	screen.Guides = guides
+	screen.Marked = entryMarks(nav, dirContents)
+	screen.StartIndex = p.startIndex
+	screen.Select(indexOf(dirContents, p.selected))
+	updateParents()
+	return err
+}
+
+// updateParents lists the ancestors of the current directory, for as many columns as the screen
+// has before the current directory, with the directory leading towards the current one selected
+// in each.
+func updateParents() {
+	screen.Parents = nil
+	listings := make(map[parentKey]parentListing)
+	e := nav
+	for i := 0; i < len(screen.ColumnWidths)-2 && e.Path != ""; i++ {
+		child := filepath.Base(e.Path) + explorer.PathSep
+		if err := e.MoveOne(".."); err != nil {
+			break
+		}
+		key := parentKey{location: e.Location(), listAll: listAll, sort: e.Sort}
+		listing := listParent(e, parentListings[key])
+		listings[key] = listing
+		parent := listing.parent
+		parent.SelectedIndex = indexOf(parent.Text, child)
+		screen.Parents = append([]textrenderer.Pane{parent}, screen.Parents...)
+	}
+	parentListings = listings
+}
+
+// listParent returns the listing of an ancestor of the current directory, which the explorer is
+// in. The previous listing of the ancestor is reused if it has not been modified since, unless it
+// could not be listed or its file system does not record when directories are modified.
+func listParent(e explorer.Explorer, previous parentListing) parentListing {
+	info, err := e.FS.Stat(e.Dir())
+	if err == nil && !info.ModTime().IsZero() && info.ModTime().Equal(previous.modTime) {
+		return previous
+	}
+	// The parent is still shown if it cannot be listed, as with the current directory.
+	dirContents, infos, listErr := e.ListInfo(listAll)
+	listing := parentListing{
+		parent: textrenderer.Pane{
+			Text:      dirContents,
+			Styles:    entryStyles(e, dirContents, infos),
+			Highlight: true,
+		},
+	}
+	if err == nil && listErr == nil {
+		listing.modTime = info.ModTime()
+	}
+	return listing
+}
+
+// updateOtherPane lists the directory of the pane without focus again, so that the screen shows
+// any changes to it. It does nothing unless the screen is in dual-pane mode.
+func updateOtherPane() error {
+	if !screen.DualPane {
+		return nil
+	}
+	p := otherPane
+	dirContents, guides, infos, err := listPane(p.nav, p.listAll, p.treeMode, p.expanded)
+	screen.OtherPane = textrenderer.Pane{
+		Header:        p.nav.Location(),
+		Text:          dirContents,
+		Styles:        entryStyles(p.nav, dirContents, infos),
+		SelectedIndex: indexOf(dirContents, p.selected),
+		StartIndex:    p.startIndex,
+		Guides:        guides,
+		Marked:        entryMarks(p.nav, dirContents),
+	}
+	return err
+}
+
+// indexOf returns the index of a name within the entries of a directory, or 0 if it is not one of
+// them.
+func indexOf(dirContents []string, name string) int {
+	for i, entry := range dirContents {
+		if entry == name {
+			return i
+		}
+	}
+	return 0
+}
+
+// toggleDualPane switches between displaying a preview of the current selected file or directory
+// and displaying a second pane beside the first. The second pane begins in the same directory as
+// the first.
+func toggleDualPane() {
+	screen.DualPane = !screen.DualPane
+	if screen.DualPane && otherPane.nav == (explorer.Explorer{}) {
+		otherPane = currentPane().clone()
+	}
+	err := updateOtherPane()
+	screen.Render(requestPreview())
+	if err != nil {
+		report(err)
+	}
+}
+
+// switchPane gives focus to the pane without it in dual-pane mode.
+func switchPane() {
+	if !screen.DualPane {
+		return
+	}
+	active := currentPane()
+	err := showPane(otherPane)
+	otherPane = active
+	screen.ActivePane = 1 - screen.ActivePane
+	if otherErr := updateOtherPane(); err == nil {
+		err = otherErr
+	}
+	updateTabLabels()
+	screen.Render(requestPreview())
+	if err != nil {
+		report(err)
+	}
+}
+
+// promptTransfer begins typing a command which copies or moves the current selected file or
+// directory, with the destination defaulting to the directory of the other pane in dual-pane mode,
+// or otherwise the current directory.
+func promptTransfer(command string) {
+	dest := nav.Location()
+	if screen.DualPane {
+		dest = otherPane.nav.Location()
+	}
+	openPrompt(CommandMode)
+	input = []rune(command + " " + dest)
+	renderPrompt()
+}
+
+// transfer copies or moves the current selected file or directory into a directory given either
+// as an absolute path, a path beginning with '~', a path relative to the current directory, or the
+// URL of a directory on a remote host. Paths beginning with '~' lie on the local file system, while
+// other paths lie on the same file system as the current directory. The file or directory is
+// transferred in the background, once any remote host which has not been connected to has been.
+func transfer(command, dest string, move bool) error {
+	name := screen.CurrentSelected()
+	destFS, destDir := nav.FS, dest
+	switch {
+	case name == "":
+		return fmt.Errorf("%s: %v", command, errNothingSelected)
+	case dest == "":
+		return fmt.Errorf("%s: no destination was given", command)
+	case isRemote(dest):
+		source, t := nav, transferResult{command: command, name: name, dest: dest, move: move}
+		openRemote(dest, func(target explorer.Explorer, err error) {
+			if err != nil {
+				report(fmt.Errorf("%s %s: %v", command, name, err))
+				return
+			}
+			transferTo(source, t, target.FS, target.Path)
+		})
+		return nil
+	case dest[0] == '~':
+		destFS, destDir = filesystem.OS(), nav.CurrentUser.HomeDir+dest[1:]
+	case !filepath.IsAbs(dest):
+		destDir = nav.GetPath() + dest
+	}
+	t := transferResult{command: command, name: name, dest: dest, move: move}
+	transferTo(nav, t, destFS, destDir)
+	return nil
+}
+
+// transferResult is the outcome of copying or moving a file or directory in the background.
+type transferResult struct {
+	command string // The command which began the transfer.
+	name    string // The name of the file or directory which was transferred.
+	dest    string // The destination, as it was given.
+	move    bool   // Whether the file or directory was moved rather than copied.
+	err     error  // The error which prevented the transfer.
+}
+
+// transferChan is used to receive the outcome of each transfer made in the background.
+var transferChan = make(chan transferResult)
+
+// transferTo begins copying or moving a file or directory in the directory which an explorer is in
+// to a directory of a file system, in the background so that the file manager stays usable while
+// large directories or remote files are transferred. The outcome is sent to transferChan.
+func transferTo(source explorer.Explorer, t transferResult, destFS filesystem.FileSystem,
+	destDir string) {
+	verb := "Copying"
+	if t.move {
+		verb = "Moving"
+	}
+	showMessage(textrenderer.Info, fmt.Sprintf("%s %s to %s…", verb,
+		strings.TrimSuffix(t.name, explorer.PathSep), t.dest))
+	go func() {
+		defer recoverPanic()
+		if t.move {
+			t.err = source.MoveTo(t.name, destFS, destDir)
+		} else {
+			t.err = source.CopyTo(t.name, destFS, destDir)
+		}
+		transferChan <- t
+	}()
+}
+
+// transferred returns the channel which receives the outcome of transfers made in the background,
+// or nil while the user is typing into a prompt, so that the panes are not listed again beneath it.
+func transferred() <-chan transferResult {
+	if mode != NormalMode {
+		return nil
+	}
+	return transferChan
+}
+
+// receiveTransfer lists the panes again once a file or directory has been transferred in the
+// background, describing the outcome on the status line.
+func receiveTransfer(t transferResult) {
+	// A transfer which failed may have changed the panes before it did.
+	err := reloadDirectory()
+	if otherErr := updateOtherPane(); err == nil {
+		err = otherErr
+	}
+	screen.Render(requestPreview())
+	switch {
+	case t.err != nil:
+		report(fmt.Errorf("%s %s: %v", t.command, t.name, t.err))
+	case err != nil:
+		report(err)
+	default:
+		verb := "Copied"
+		if t.move {
+			verb = "Moved"
+		}
+		showMessage(textrenderer.Info, fmt.Sprintf("%s %s to %s", verb,
+			strings.TrimSuffix(t.name, explorer.PathSep), t.dest))
+	}
+}
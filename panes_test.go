@@ -0,0 +1,79 @@
+// Copyright 2019 Max Godfrey
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 		http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/maxgodfrey2004/go-file-manager/explorer"
+)
+
+func TestListParent(t *testing.T) {
+	dir, err := ioutil.TempDir("", "parent")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := os.Mkdir(filepath.Join(dir, "child"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	e := explorer.New()
+	if err := e.MoveAbsolute(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	first := listParent(e, parentListing{})
+	want := []string{".." + explorer.PathSep, "child" + explorer.PathSep}
+	if !reflect.DeepEqual(first.parent.Text, want) {
+		t.Fatalf("listParent listed %q, want %q", first.parent.Text, want)
+	}
+	// An unmodified directory is not listed again.
+	previous := first
+	previous.parent.Text = []string{"previous"}
+	if again := listParent(e, previous); !reflect.DeepEqual(again.parent.Text, []string{"previous"}) {
+		t.Errorf("listParent listed an unmodified directory again, as %q", again.parent.Text)
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "new.txt"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	// The modification time may not have changed if it is recorded coarsely.
+	later := first.modTime.Add(time.Second)
+	if err := os.Chtimes(dir, later, later); err != nil {
+		t.Fatal(err)
+	}
+	want = append(want, "new.txt")
+	if modified := listParent(e, previous); !reflect.DeepEqual(modified.parent.Text, want) {
+		t.Errorf("listParent listed a modified directory as %q, want %q", modified.parent.Text, want)
+	}
+}
+
+func TestClonePane(t *testing.T) {
+	p := pane{treeMode: true, expanded: map[string]bool{"/a": true}}
+	clone := p.clone()
+	clone.expanded["/b"] = true
+	delete(clone.expanded, "/a")
+	if !reflect.DeepEqual(p.expanded, map[string]bool{"/a": true}) {
+		t.Errorf("expanding a directory in a clone of a pane changed the pane to %v", p.expanded)
+	}
+	if !clone.treeMode {
+		t.Error("the clone of a pane in tree mode is not in tree mode")
+	}
+}
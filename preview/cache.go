@@ -0,0 +1,93 @@
+// Copyright 2019 Max Godfrey
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 		http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package preview
+
+import (
+	"container/list"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/maxgodfrey2004/go-file-manager/textrenderer"
+)
+
+// Cache holds a fixed number of previews, discarding the least recently used preview when it is
+// full. It is safe for concurrent use.
+type Cache struct {
+	mu       sync.Mutex
+	capacity int
+	order    *list.List               // Entries, from most to least recently used.
+	entries  map[string]*list.Element // Entries, by key.
+}
+
+// cacheEntry is a preview held in a Cache.
+type cacheEntry struct {
+	key   string
+	lines []textrenderer.Line
+}
+
+// NewCache returns an empty Cache which holds at most capacity previews.
+func NewCache(capacity int) *Cache {
+	return &Cache{
+		capacity: capacity,
+		order:    list.New(),
+		entries:  make(map[string]*list.Element),
+	}
+}
+
+// CacheKey returns the key under which the preview of a file is cached. The key changes whenever
+// the file is modified, or the preview is generated under different conditions.
+func CacheKey(path string, info os.FileInfo, width, height int, listAll bool) string {
+	return fmt.Sprintf("%s\x00%d\x00%d\x00%dx%d\x00%t",
+		path, info.ModTime().UnixNano(), info.Size(), width, height, listAll)
+}
+
+// Get returns the preview held under a key, if there is one.
+func (c *Cache) Get(key string) ([]textrenderer.Line, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(element)
+	return element.Value.(*cacheEntry).lines, true
+}
+
+// Add holds a preview under a key, replacing any preview already held under it.
+func (c *Cache) Add(key string, lines []textrenderer.Line) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, ok := c.entries[key]; ok {
+		element.Value.(*cacheEntry).lines = lines
+		c.order.MoveToFront(element)
+		return
+	}
+	c.entries[key] = c.order.PushFront(&cacheEntry{key: key, lines: lines})
+	for c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).key)
+	}
+}
+
+// Len returns the number of previews held in the cache.
+func (c *Cache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
@@ -0,0 +1,83 @@
+// Copyright 2019 Max Godfrey
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 		http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package preview
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/maxgodfrey2004/go-file-manager/textrenderer"
+)
+
+func TestCache(t *testing.T) {
+	c := NewCache(2)
+	c.Add("a", textrenderer.PlainLines([]string{"a"}))
+	c.Add("b", textrenderer.PlainLines([]string{"b"}))
+	if _, ok := c.Get("a"); !ok {
+		t.Fatal("a was not cached")
+	}
+
+	// b is now the least recently used preview, so it is discarded to make room for c.
+	c.Add("c", textrenderer.PlainLines([]string{"c"}))
+	if _, ok := c.Get("b"); ok {
+		t.Error("b was not discarded")
+	}
+	if lines, ok := c.Get("a"); !ok || lines[0].String() != "a" {
+		t.Errorf("Get(\"a\") = %q, %t", lines, ok)
+	}
+	if c.Len() != 2 {
+		t.Errorf("Len() = %d, want 2", c.Len())
+	}
+
+	c.Add("a", textrenderer.PlainLines([]string{"replaced"}))
+	if lines, _ := c.Get("a"); lines[0].String() != "replaced" {
+		t.Errorf("Get(\"a\") = %q after replacing it", lines)
+	}
+}
+
+func TestCacheKey(t *testing.T) {
+	file, err := ioutil.TempFile("", "preview")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(file.Name())
+	file.Close()
+
+	info, err := os.Stat(file.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := CacheKey(file.Name(), info, 40, 20, false)
+	if CacheKey(file.Name(), info, 40, 20, true) == key {
+		t.Error("key does not depend on whether all files are listed")
+	}
+	if CacheKey(file.Name(), info, 41, 20, false) == key {
+		t.Error("key does not depend on the size of the preview")
+	}
+
+	modified := info.ModTime().Add(time.Second)
+	if err := os.Chtimes(file.Name(), modified, modified); err != nil {
+		t.Fatal(err)
+	}
+	info, err = os.Stat(file.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if CacheKey(file.Name(), info, 40, 20, false) == key {
+		t.Error("key does not change when the file is modified")
+	}
+}
@@ -0,0 +1,352 @@
+// Copyright 2019 Max Godfrey
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 		http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package preview
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/maxgodfrey2004/go-file-manager/textrenderer"
+	"github.com/nsf/termbox-go"
+)
+
+// Colours used when highlighting structured data.
+const (
+	keyColor     = termbox.ColorCyan
+	stringColor  = termbox.ColorGreen
+	numberColor  = termbox.ColorYellow
+	literalColor = termbox.ColorMagenta
+	guideColor   = termbox.ColorDarkGray
+	commentColor = termbox.ColorDarkGray
+)
+
+// maxColumnWidth is the widest that a column of a CSV or TSV preview may be. Wider fields are
+// truncated.
+const maxColumnWidth = 32
+
+// formatJSON pretty-prints data as JSON, returning at most n colourised lines. The data is read one
+// token at a time and only as far as is needed, so that a file which is too large to be read in
+// full may be previewed even though the data ends partway through it. An error is returned if data
+// is not valid JSON up to the last line which is returned.
+func formatJSON(data []byte, n int) ([]textrenderer.Line, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	var lines []textrenderer.Line
+	var line strings.Builder
+	var objects []bool // Whether each enclosing array or object is an object.
+	key, done := false, false
+	// endValue ends the line holding a value, adding a comma if another value follows it.
+	endValue := func() {
+		if len(objects) == 0 {
+			done = true
+		} else {
+			key = objects[len(objects)-1]
+			if dec.More() {
+				line.WriteByte(',')
+			}
+		}
+		lines = append(lines, highlightJSON(line.String()))
+		line.Reset()
+	}
+
+	for len(lines) < n {
+		tok, err := dec.Token()
+		switch {
+		case err == io.EOF && done:
+			return lines, nil
+		case err == io.EOF:
+			return nil, io.ErrUnexpectedEOF
+		case err != nil:
+			return nil, err
+		case done:
+			return nil, errors.New("invalid data after the top-level value")
+		}
+		if line.Len() == 0 {
+			line.WriteString(strings.Repeat("  ", len(objects)))
+		}
+
+		switch tok := tok.(type) {
+		case json.Delim:
+			switch tok {
+			case '{', '[':
+				line.WriteRune(rune(tok))
+				if !dec.More() {
+					// An empty array or object is written on one line, as in "[]".
+					end, err := dec.Token()
+					if err != nil {
+						return nil, err
+					}
+					line.WriteRune(rune(end.(json.Delim)))
+					endValue()
+					continue
+				}
+				objects = append(objects, tok == '{')
+				key = tok == '{'
+				lines = append(lines, highlightJSON(line.String()))
+				line.Reset()
+			default:
+				objects = objects[:len(objects)-1]
+				line.Reset()
+				line.WriteString(strings.Repeat("  ", len(objects)))
+				line.WriteRune(rune(tok))
+				endValue()
+			}
+		case string:
+			line.WriteString(quoteJSON(tok))
+			if key {
+				line.WriteString(": ")
+				key = false
+			} else {
+				endValue()
+			}
+		case json.Number:
+			line.WriteString(tok.String())
+			endValue()
+		case bool:
+			line.WriteString(strconv.FormatBool(tok))
+			endValue()
+		case nil:
+			line.WriteString("null")
+			endValue()
+		}
+	}
+	return lines, nil
+}
+
+// quoteJSON returns a string quoted as JSON, leaving characters such as '<' unescaped.
+func quoteJSON(s string) string {
+	var out bytes.Buffer
+	enc := json.NewEncoder(&out)
+	enc.SetEscapeHTML(false)
+	enc.Encode(s)
+	return strings.TrimSuffix(out.String(), "\n")
+}
+
+// highlightJSON colourises a single line of pretty-printed JSON.
+func highlightJSON(s string) textrenderer.Line {
+	var line textrenderer.Line
+	for i := 0; i < len(s); {
+		j := i + 1
+		var fgColor termbox.Attribute
+		switch c := s[i]; {
+		case c == '"':
+			for j < len(s) && s[j] != '"' {
+				if s[j] == '\\' {
+					j++
+				}
+				j++
+			}
+			if j < len(s) {
+				j++
+			}
+			fgColor = stringColor
+			if strings.HasPrefix(strings.TrimLeft(s[j:], " "), ":") {
+				fgColor = keyColor
+			}
+		case c == '-' || (c >= '0' && c <= '9'):
+			for j < len(s) && strings.IndexByte("0123456789.eE+-", s[j]) >= 0 {
+				j++
+			}
+			fgColor = numberColor
+		case c >= 'a' && c <= 'z':
+			for j < len(s) && s[j] >= 'a' && s[j] <= 'z' {
+				j++
+			}
+			fgColor = literalColor
+		default:
+			for j < len(s) && strings.IndexByte("\"-0123456789abcdefghijklmnopqrstuvwxyz", s[j]) < 0 {
+				j++
+			}
+		}
+		line = append(line, textrenderer.Span{Text: s[i:j], Fg: fgColor})
+		i = j
+	}
+	return line
+}
+
+// formatDelimited renders data containing values separated by comma as aligned columns, returning
+// at most n lines. The first record is treated as a header row. An error is returned if not even
+// the first record can be parsed.
+func formatDelimited(data []byte, comma rune, n int) ([]textrenderer.Line, error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.Comma = comma
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	// One of the n lines is taken up by the rule beneath the header row.
+	var records [][]string
+	for len(records) < n-1 {
+		record, err := reader.Read()
+		if err != nil {
+			// The data may have been cut short when it was read, so everything parsed so far is
+			// still worth displaying.
+			break
+		}
+		for i, field := range record {
+			record[i] = truncate(strings.NewReplacer("\r", "", "\n", " ").Replace(field), maxColumnWidth)
+		}
+		records = append(records, record)
+	}
+	if len(records) == 0 {
+		return nil, errors.New("no records could be parsed")
+	}
+
+	var widths []int
+	for _, record := range records {
+		for i, field := range record {
+			if i == len(widths) {
+				widths = append(widths, 0)
+			}
+			if width := textrenderer.StringWidth(field); width > widths[i] {
+				widths[i] = width
+			}
+		}
+	}
+
+	var lines []textrenderer.Line
+	for r, record := range records {
+		fgColor := termbox.ColorDefault
+		if r == 0 {
+			fgColor = keyColor | termbox.AttrBold
+		}
+		var line textrenderer.Line
+		for i, width := range widths {
+			field := ""
+			if i < len(record) {
+				field = record[i]
+			}
+			if i > 0 {
+				line = append(line, textrenderer.Span{Text: " │ ", Fg: guideColor})
+			}
+			padding := strings.Repeat(" ", width-textrenderer.StringWidth(field))
+			line = append(line, textrenderer.Span{Text: field + padding, Fg: fgColor})
+		}
+		lines = append(lines, line)
+
+		if r == 0 && n > 1 {
+			rule := make([]string, len(widths))
+			for i, width := range widths {
+				rule[i] = strings.Repeat("─", width)
+			}
+			lines = append(lines, textrenderer.Line{{Text: strings.Join(rule, "─┼─"), Fg: guideColor}})
+		}
+	}
+	return lines, nil
+}
+
+// truncate shortens s so that it occupies at most width columns, marking any truncation with an
+// ellipsis.
+func truncate(s string, width int) string {
+	if textrenderer.StringWidth(s) <= width {
+		return s
+	}
+	var b strings.Builder
+	used := 0
+	for _, r := range s {
+		runeWidth := textrenderer.StringWidth(string(r))
+		if used+runeWidth+1 > width {
+			break
+		}
+		b.WriteRune(r)
+		used += runeWidth
+	}
+	return b.String() + "…"
+}
+
+// formatYAML returns at most n lines of data, drawing a guide beneath each parent whose children
+// are indented below it, and colourising keys, list markers and comments.
+func formatYAML(data []byte, n int) []textrenderer.Line {
+	var lines []textrenderer.Line
+	var indents []int
+	for _, s := range formatText(data, n) {
+		text := s.String()
+		trimmed := strings.TrimLeft(text, " ")
+		if trimmed == "" {
+			lines = append(lines, textrenderer.Line{})
+			continue
+		}
+
+		indent := len(text) - len(trimmed)
+		for len(indents) > 0 && indents[len(indents)-1] >= indent {
+			indents = indents[:len(indents)-1]
+		}
+
+		var guides strings.Builder
+		col := 0
+		for _, level := range indents {
+			guides.WriteString(strings.Repeat(" ", level-col))
+			guides.WriteString("│")
+			col = level + 1
+		}
+		guides.WriteString(strings.Repeat(" ", indent-col))
+		indents = append(indents, indent)
+
+		line := textrenderer.Line{{Text: guides.String(), Fg: guideColor}}
+		lines = append(lines, append(line, highlightYAML(trimmed)...))
+	}
+	return lines
+}
+
+// highlightYAML colourises a single line of YAML which has had its indentation removed.
+func highlightYAML(s string) textrenderer.Line {
+	var line textrenderer.Line
+	if strings.HasPrefix(s, "#") {
+		return append(line, textrenderer.Span{Text: s, Fg: commentColor})
+	}
+	if s == "---" || s == "..." {
+		return append(line, textrenderer.Span{Text: s, Fg: literalColor})
+	}
+
+	for strings.HasPrefix(s, "- ") || s == "-" {
+		marker := s[:1]
+		s = s[1:]
+		if s != "" {
+			marker += " "
+			s = s[1:]
+		}
+		line = append(line, textrenderer.Span{Text: marker, Fg: numberColor})
+	}
+
+	end := strings.Index(s, ": ")
+	if end < 0 && strings.HasSuffix(s, ":") {
+		end = len(s) - 1
+	}
+	if end >= 0 {
+		line = append(line, textrenderer.Span{Text: s[:end], Fg: keyColor}, textrenderer.Span{Text: ":"})
+		s = s[end+1:]
+	}
+
+	value := s
+	comment := ""
+	if start := strings.Index(s, " #"); start >= 0 {
+		value, comment = s[:start], s[start:]
+	}
+	fgColor := termbox.ColorDefault
+	trimmed := strings.TrimLeft(value, " ")
+	if strings.HasPrefix(trimmed, "\"") || strings.HasPrefix(trimmed, "'") {
+		fgColor = stringColor
+	}
+	line = append(line, textrenderer.Span{Text: value, Fg: fgColor})
+	if comment != "" {
+		line = append(line, textrenderer.Span{Text: comment, Fg: commentColor})
+	}
+	return line
+}
@@ -0,0 +1,143 @@
+// Copyright 2019 Max Godfrey
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 		http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package preview
+
+import (
+	"testing"
+
+	"github.com/nsf/termbox-go"
+)
+
+func TestFormatJSON(t *testing.T) {
+	lines, err := formatJSON([]byte(`{"name":"gfm","tags":["a"],"stars":12,"fork":false}`), 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"{",
+		"  \"name\": \"gfm\",",
+		"  \"tags\": [",
+		"    \"a\"",
+		"  ],",
+		"  \"stars\": 12,",
+		"  \"fork\": false",
+		"}",
+	}
+	if len(lines) != len(want) {
+		t.Fatalf("got %d lines, want %d", len(lines), len(want))
+	}
+	for i := range want {
+		if lines[i].String() != want[i] {
+			t.Errorf("line %d = %q, want %q", i, lines[i].String(), want[i])
+		}
+	}
+
+	if span := lines[1][1]; span.Text != "\"name\"" || span.Fg != keyColor {
+		t.Errorf("key span = %+v, want \"name\" coloured as a key", span)
+	}
+	if span := lines[1][3]; span.Text != "\"gfm\"" || span.Fg != stringColor {
+		t.Errorf("value span = %+v, want \"gfm\" coloured as a string", span)
+	}
+
+	lines, _ = formatJSON([]byte("[1, 2, 3]"), 2)
+	if len(lines) != 2 {
+		t.Errorf("got %d lines, want the preview to be limited to 2", len(lines))
+	}
+
+	// A file larger than MaxBytes is cut off partway through, after the lines which are previewed.
+	lines, err = formatJSON([]byte(`{"empty": {}, "list": [1, null, "<a>"], "more": [1, 2, 3, 4`), 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = []string{
+		"{",
+		"  \"empty\": {},",
+		"  \"list\": [",
+		"    1,",
+		"    null,",
+		"    \"<a>\"",
+		"  ],",
+	}
+	for i := range want {
+		if i >= len(lines) || lines[i].String() != want[i] {
+			t.Fatalf("lines of truncated JSON = %q, want %q", lines, want)
+		}
+	}
+	if _, err := formatJSON([]byte(`{"a": 1} {"b": 2}`), 10); err == nil {
+		t.Error("formatJSON of two values did not fail")
+	}
+}
+
+func TestFormatDelimited(t *testing.T) {
+	lines, err := formatDelimited([]byte("name,size\nexplorer.go,4096\n\"a, b\",1\n"), ',', 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"name        │ size",
+		"────────────┼─────",
+		"explorer.go │ 4096",
+		"a, b        │ 1   ",
+	}
+	if len(lines) != len(want) {
+		t.Fatalf("got %d lines, want %d", len(lines), len(want))
+	}
+	for i := range want {
+		if lines[i].String() != want[i] {
+			t.Errorf("line %d = %q, want %q", i, lines[i].String(), want[i])
+		}
+	}
+	if lines[0][0].Fg&termbox.AttrBold == 0 {
+		t.Error("header row is not bold")
+	}
+
+	lines, _ = formatDelimited([]byte("日本\tx\n"), '\t', 10)
+	if got := lines[0].String(); got != "日本 │ x" {
+		t.Errorf("TSV line = %q, want %q", got, "日本 │ x")
+	}
+}
+
+func TestFormatYAML(t *testing.T) {
+	data := "# comment\nlanguage: go\ngo:\n  - \"1.10\"\n" +
+		"script:\n  nested:\n    key: value\n  other: 1\n"
+	want := []string{
+		"# comment",
+		"language: go",
+		"go:",
+		"│ - \"1.10\"",
+		"script:",
+		"│ nested:",
+		"│ │ key: value",
+		"│ other: 1",
+	}
+	lines := formatYAML([]byte(data), 100)
+	if len(lines) != len(want) {
+		t.Fatalf("got %d lines, want %d", len(lines), len(want))
+	}
+	for i := range want {
+		if lines[i].String() != want[i] {
+			t.Errorf("line %d = %q, want %q", i, lines[i].String(), want[i])
+		}
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	if s := truncate("abcdef", 4); s != "abc…" {
+		t.Errorf("truncate = %q, want %q", s, "abc…")
+	}
+	if s := truncate("abc", 4); s != "abc" {
+		t.Errorf("truncate = %q, want %q", s, "abc")
+	}
+}
@@ -0,0 +1,80 @@
+// Copyright 2019 Max Godfrey
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 		http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package preview
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/maxgodfrey2004/go-file-manager/explorer"
+	"github.com/maxgodfrey2004/go-file-manager/textrenderer"
+	"github.com/nsf/termbox-go"
+)
+
+// SummaryHeight is the number of lines which the summary at the top of a directory preview takes
+// up.
+const SummaryHeight = 3
+
+// Directory returns a preview of a directory: a summary of its contents, followed by the contents
+// themselves.
+func Directory(summary explorer.DirectorySummary, contents []string) []textrenderer.Line {
+	newest := "never modified"
+	if !summary.Newest.IsZero() {
+		newest = "newest " + summary.Newest.Format("2006-01-02 15:04")
+	}
+	lines := []textrenderer.Line{
+		{{
+			Text: fmt.Sprintf("%s: %s, %s", plural(summary.Entries, "entry", "entries"),
+				plural(summary.Directories, "directory", "directories"),
+				plural(summary.Files, "file", "files")),
+			Fg: keyColor,
+		}},
+		{{Text: fmt.Sprintf("%s in files, %s", FormatSize(summary.TotalSize), newest), Fg: keyColor}},
+		{{Text: strings.Repeat("─", maxColumnWidth), Fg: guideColor}},
+	}
+
+	for _, name := range contents {
+		fgColor := termbox.ColorDefault
+		if strings.HasSuffix(name, explorer.PathSep) {
+			fgColor = termbox.ColorBlue
+		}
+		lines = append(lines, textrenderer.Line{{Text: name, Fg: fgColor}})
+	}
+	return lines
+}
+
+// FormatSize returns a human readable representation of a number of bytes, such as "4.2 KiB".
+func FormatSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return plural(int(size), "byte", "bytes")
+	}
+	value := float64(size) / unit
+	for _, prefix := range "KMGTP" {
+		if value < unit {
+			return fmt.Sprintf("%.1f %ciB", value, prefix)
+		}
+		value /= unit
+	}
+	return fmt.Sprintf("%.1f EiB", value)
+}
+
+// plural returns a count followed by the singular or plural form of a noun, as appropriate.
+func plural(count int, singular, plural string) string {
+	if count == 1 {
+		return fmt.Sprintf("%d %s", count, singular)
+	}
+	return fmt.Sprintf("%d %s", count, plural)
+}
@@ -0,0 +1,64 @@
+// Copyright 2019 Max Godfrey
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 		http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package preview
+
+import (
+	"testing"
+	"time"
+
+	"github.com/maxgodfrey2004/go-file-manager/explorer"
+	"github.com/nsf/termbox-go"
+)
+
+func TestDirectory(t *testing.T) {
+	summary := explorer.DirectorySummary{
+		Entries:     3,
+		Files:       2,
+		Directories: 1,
+		TotalSize:   4300,
+		Newest:      time.Date(2019, 6, 1, 12, 30, 0, 0, time.UTC),
+	}
+	lines := Directory(summary, []string{"bin" + explorer.PathSep, "a.txt", "b.txt"})
+	if len(lines) != SummaryHeight+3 {
+		t.Fatalf("got %d lines, want %d", len(lines), SummaryHeight+3)
+	}
+	if got, want := lines[0].String(), "3 entries: 1 directory, 2 files"; got != want {
+		t.Errorf("first line = %q, want %q", got, want)
+	}
+	if got, want := lines[1].String(), "4.2 KiB in files, newest 2019-06-01 12:30"; got != want {
+		t.Errorf("second line = %q, want %q", got, want)
+	}
+	if lines[SummaryHeight][0].Fg != termbox.ColorBlue {
+		t.Errorf("directory is not coloured blue")
+	}
+}
+
+func TestFormatSize(t *testing.T) {
+	tests := []struct {
+		size int64
+		want string
+	}{
+		{1, "1 byte"},
+		{1023, "1023 bytes"},
+		{1024, "1.0 KiB"},
+		{5 << 20, "5.0 MiB"},
+		{3 << 40, "3.0 TiB"},
+	}
+	for _, test := range tests {
+		if got := FormatSize(test.size); got != test.want {
+			t.Errorf("FormatSize(%d) = %q, want %q", test.size, got, test.want)
+		}
+	}
+}
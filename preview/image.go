@@ -0,0 +1,180 @@
+// Copyright 2019 Max Godfrey
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 		http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package preview
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"strings"
+
+	// Register the image formats which can be previewed.
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+
+	"github.com/maxgodfrey2004/go-file-manager/textrenderer"
+	"github.com/nsf/termbox-go"
+)
+
+// MaxImageBytes is the maximum number of bytes of an image which are read in order to preview it.
+const MaxImageBytes = 32 << 20
+
+// maxImagePixels is the largest number of pixels which an image may have in order to be previewed.
+// A small compressed file may describe an enormous image, which would take far too much memory to
+// decode.
+const maxImagePixels = 1 << 25
+
+// samplesPerAxis is the number of pixels sampled along each axis of the area of an image which is
+// scaled down into a single pixel of its preview.
+const samplesPerAxis = 4
+
+// opaqueThreshold is the alpha value at or above which a pixel is drawn rather than left as the
+// terminal's background.
+const opaqueThreshold = 0x8000
+
+// formatImage renders data as an image using half-block characters, so that each cell of the
+// terminal holds two vertically stacked pixels. The first line describes the image's format and
+// dimensions, and the image itself is scaled down to fit within width columns and the remaining
+// n-1 lines. If data cannot be decoded, the reason why is shown in place of the image.
+func formatImage(data []byte, width, n int) []textrenderer.Line {
+	config, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return []textrenderer.Line{errorLine(err)}
+	}
+	info := textrenderer.Line{{
+		Text: fmt.Sprintf("%s image, %d×%d", strings.ToUpper(format), config.Width, config.Height),
+		Fg:   keyColor,
+	}}
+	if int64(config.Width)*int64(config.Height) > maxImagePixels {
+		return []textrenderer.Line{info, errorLine(errors.New("image is too large"))}
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return []textrenderer.Line{info, errorLine(err)}
+	}
+	return append([]textrenderer.Line{info}, halfBlocks(img, width, n-1)...)
+}
+
+// errorLine returns a line describing why an image could not be previewed.
+func errorLine(err error) textrenderer.Line {
+	return textrenderer.Line{{Text: "cannot preview image: " + err.Error(), Bg: termbox.ColorRed}}
+}
+
+// halfBlocks scales an image down (never up) to fit within width columns and n lines of half-block
+// characters, preserving its aspect ratio.
+func halfBlocks(img image.Image, width, n int) []textrenderer.Line {
+	bounds := img.Bounds()
+	if width <= 0 || n <= 0 || bounds.Empty() {
+		return nil
+	}
+
+	scale := 1.0
+	if s := float64(width) / float64(bounds.Dx()); s < scale {
+		scale = s
+	}
+	if s := float64(2*n) / float64(bounds.Dy()); s < scale {
+		scale = s
+	}
+	cols := int(float64(bounds.Dx()) * scale)
+	rows := int(float64(bounds.Dy()) * scale)
+	if cols == 0 {
+		cols = 1
+	}
+	if rows == 0 {
+		rows = 1
+	}
+
+	pixel := func(x, y int) (color.RGBA64, bool) {
+		x0 := bounds.Min.X + x*bounds.Dx()/cols
+		x1 := bounds.Min.X + (x+1)*bounds.Dx()/cols
+		y0 := bounds.Min.Y + y*bounds.Dy()/rows
+		y1 := bounds.Min.Y + (y+1)*bounds.Dy()/rows
+		return average(img, image.Rect(x0, y0, x1, y1))
+	}
+
+	var lines []textrenderer.Line
+	for y := 0; y < rows; y += 2 {
+		var line textrenderer.Line
+		for x := 0; x < cols; x++ {
+			top, topOpaque := pixel(x, y)
+			bottom, bottomOpaque := color.RGBA64{}, false
+			if y+1 < rows {
+				bottom, bottomOpaque = pixel(x, y+1)
+			}
+
+			switch {
+			case topOpaque && bottomOpaque:
+				line = append(line, textrenderer.Span{Text: "▀", Fg: attribute(top), Bg: attribute(bottom)})
+			case topOpaque:
+				line = append(line, textrenderer.Span{Text: "▀", Fg: attribute(top)})
+			case bottomOpaque:
+				line = append(line, textrenderer.Span{Text: "▄", Fg: attribute(bottom)})
+			default:
+				line = append(line, textrenderer.Span{Text: " "})
+			}
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// average returns the mean colour of a sample of the pixels within an area of an image, along with
+// whether the area is opaque enough to be drawn. The area always contains at least one pixel.
+func average(img image.Image, area image.Rectangle) (color.RGBA64, bool) {
+	if area.Dx() == 0 {
+		area.Max.X = area.Min.X + 1
+	}
+	if area.Dy() == 0 {
+		area.Max.Y = area.Min.Y + 1
+	}
+	stepX := max(area.Dx()/samplesPerAxis, 1)
+	stepY := max(area.Dy()/samplesPerAxis, 1)
+
+	var r, g, b, a, count uint64
+	for y := area.Min.Y; y < area.Max.Y; y += stepY {
+		for x := area.Min.X; x < area.Max.X; x += stepX {
+			pr, pg, pb, pa := img.At(x, y).RGBA()
+			r, g, b, a = r+uint64(pr), g+uint64(pg), b+uint64(pb), a+uint64(pa)
+			count++
+		}
+	}
+	if a/count < opaqueThreshold {
+		return color.RGBA64{}, false
+	}
+	// The channels are premultiplied by alpha, so they are divided by it to recover the colour.
+	return color.RGBA64{
+		R: uint16(r * 0xffff / a),
+		G: uint16(g * 0xffff / a),
+		B: uint16(b * 0xffff / a),
+		A: 0xffff,
+	}, true
+}
+
+// attribute converts a colour into a termbox attribute for the terminal's output mode.
+func attribute(c color.RGBA64) termbox.Attribute {
+	return textrenderer.RGB(uint8(c.R>>8), uint8(c.G>>8), uint8(c.B>>8))
+}
+
+// max returns the maximum of two integers.
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,96 @@
+// Copyright 2019 Max Godfrey
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 		http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package preview
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+func TestFormatImage(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 4, 3))
+	for x := 0; x < 4; x++ {
+		img.Set(x, 0, color.NRGBA{R: 255, A: 255})
+		img.Set(x, 1, color.NRGBA{B: 255, A: 255})
+		img.Set(x, 2, color.NRGBA{G: 255, A: 255})
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := formatImage(buf.Bytes(), 80, 10)
+	if len(lines) != 3 {
+		t.Fatalf("got %d lines, want an information line and 2 lines of blocks", len(lines))
+	}
+	if info := lines[0].String(); info != "PNG image, 4×3" {
+		t.Errorf("information line = %q, want %q", info, "PNG image, 4×3")
+	}
+	if blocks := lines[1].String(); blocks != "▀▀▀▀" {
+		t.Errorf("first line of blocks = %q, want %q", blocks, "▀▀▀▀")
+	}
+	// The last row of pixels has no pixels beneath it, so only the top half of each cell is drawn.
+	if span := lines[2][0]; span.Text != "▀" || span.Bg != 0 {
+		t.Errorf("last line of blocks begins with %+v, want a block with the default background", span)
+	}
+}
+
+func TestFormatImageScaling(t *testing.T) {
+	img := image.NewGray(image.Rect(0, 0, 400, 100))
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := formatImage(buf.Bytes(), 40, 21)
+	if len(lines) != 6 {
+		t.Fatalf("got %d lines, want an information line and 5 lines of blocks", len(lines))
+	}
+	for _, line := range lines[1:] {
+		if width := line.Width(); width != 40 {
+			t.Errorf("line is %d columns wide, want 40", width)
+		}
+	}
+}
+
+func TestFormatImageInvalid(t *testing.T) {
+	lines := formatImage([]byte("not an image"), 80, 10)
+	if len(lines) != 1 || !strings.HasPrefix(lines[0].String(), "cannot preview image") {
+		t.Errorf("got %q, want an explanation of why the image cannot be previewed", lines)
+	}
+}
+
+func TestFormatImageTooLarge(t *testing.T) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, 1, 1))); err != nil {
+		t.Fatal(err)
+	}
+	// Claim that the image is 100000×100000 pixels, updating the header's checksum to match.
+	data := buf.Bytes()
+	binary.BigEndian.PutUint32(data[16:], 100000)
+	binary.BigEndian.PutUint32(data[20:], 100000)
+	binary.BigEndian.PutUint32(data[29:], crc32.ChecksumIEEE(data[12:29]))
+
+	lines := formatImage(data, 80, 10)
+	if len(lines) != 2 || lines[1].String() != "cannot preview image: image is too large" {
+		t.Errorf("got %q, want the image to be refused as too large", lines)
+	}
+}
@@ -0,0 +1,122 @@
+// Copyright 2019 Max Godfrey
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 		http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package preview generates the styled previews of files which are shown alongside the list of
+// directory contents.
+package preview
+
+import (
+	"bufio"
+	"bytes"
+	"path/filepath"
+	"strings"
+
+	"github.com/maxgodfrey2004/go-file-manager/textrenderer"
+)
+
+// MaxBytes is the maximum number of bytes of a file which are read in order to preview it.
+const MaxBytes = 1 << 20
+
+// Kind enumerates the different types of file which may be previewed.
+type Kind int
+
+const (
+	// Text represents a file which is previewed as plain text.
+	Text Kind = iota
+
+	// JSON represents a file containing JSON, which is pretty-printed and colourised.
+	JSON
+
+	// CSV represents a file containing comma separated values, which are aligned into columns.
+	CSV
+
+	// TSV represents a file containing tab separated values, which are aligned into columns.
+	TSV
+
+	// YAML represents a file containing YAML, which is drawn with indentation guides.
+	YAML
+
+	// Image represents a PNG, JPEG or GIF image, which is drawn using coloured blocks.
+	Image
+)
+
+// extensionKinds maps lower case file extensions to the kind of preview used for them.
+var extensionKinds = map[string]Kind{
+	".json":    JSON,
+	".geojson": JSON,
+	".csv":     CSV,
+	".tsv":     TSV,
+	".tab":     TSV,
+	".yaml":    YAML,
+	".yml":     YAML,
+	".png":     Image,
+	".jpg":     Image,
+	".jpeg":    Image,
+	".gif":     Image,
+}
+
+// KindOf returns the kind of preview which should be generated for a file, based on its name.
+func KindOf(fileName string) Kind {
+	if kind, ok := extensionKinds[strings.ToLower(filepath.Ext(fileName))]; ok {
+		return kind
+	}
+	return Text
+}
+
+// Limit returns the maximum number of bytes of a file of the given kind which should be read in
+// order to preview it.
+func Limit(kind Kind) int64 {
+	if kind == Image {
+		return MaxImageBytes
+	}
+	return MaxBytes
+}
+
+// Generate returns at most n lines, each at most width columns wide, previewing the contents of a
+// file of the given kind. If the contents cannot be understood as that kind of file, they are
+// previewed as plain text instead.
+func Generate(kind Kind, data []byte, width, n int) []textrenderer.Line {
+	var lines []textrenderer.Line
+	var err error
+	switch kind {
+	case JSON:
+		lines, err = formatJSON(data, n)
+	case CSV:
+		lines, err = formatDelimited(data, ',', n)
+	case TSV:
+		lines, err = formatDelimited(data, '\t', n)
+	case YAML:
+		lines = formatYAML(data, n)
+	case Image:
+		lines = formatImage(data, width, n)
+	default:
+		return formatText(data, n)
+	}
+
+	if err != nil {
+		return formatText(data, n)
+	}
+	return lines
+}
+
+// formatText splits data into at most n lines of plain text.
+func formatText(data []byte, n int) []textrenderer.Line {
+	var lines []textrenderer.Line
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), MaxBytes)
+	for i := 0; i < n && scanner.Scan(); i++ {
+		lines = append(lines, textrenderer.PlainLine(scanner.Text()))
+	}
+	return lines
+}
@@ -0,0 +1,47 @@
+// Copyright 2019 Max Godfrey
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 		http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package preview
+
+import (
+	"testing"
+)
+
+func TestKindOf(t *testing.T) {
+	tests := []struct {
+		fileName string
+		kind     Kind
+	}{
+		{"package.json", JSON},
+		{"DATA.CSV", CSV},
+		{"table.tsv", TSV},
+		{".travis.yml", YAML},
+		{"config.yaml", YAML},
+		{"photo.JPG", Image},
+		{"main.go", Text},
+		{"Makefile", Text},
+	}
+	for _, test := range tests {
+		if kind := KindOf(test.fileName); kind != test.kind {
+			t.Errorf("KindOf(%q) = %d, want %d", test.fileName, kind, test.kind)
+		}
+	}
+}
+
+func TestGenerateFallback(t *testing.T) {
+	lines := Generate(JSON, []byte("{\"truncated\": [1, 2"), 80, 10)
+	if len(lines) != 1 || lines[0].String() != "{\"truncated\": [1, 2" {
+		t.Errorf("invalid JSON was not previewed as text: %q", lines)
+	}
+}
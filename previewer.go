@@ -0,0 +1,234 @@
+// Copyright 2019 Max Godfrey
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 		http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/maxgodfrey2004/go-file-manager/explorer"
+	"github.com/maxgodfrey2004/go-file-manager/preview"
+	"github.com/maxgodfrey2004/go-file-manager/textrenderer"
+	"github.com/nsf/termbox-go"
+)
+
+// placeholderDelay is how long to wait for a preview to be generated before displaying a
+// placeholder in its place. Cached previews are ready well within this time.
+const placeholderDelay = 20 * time.Millisecond
+
+// previewCacheSize is the number of previews which are cached, so that they may be displayed
+// instantly when a file or directory is selected again.
+const previewCacheSize = 256
+
+// previewResult is a preview which has been generated in the background.
+type previewResult struct {
+	id    int // The previewID of the request for the preview.
+	lines []textrenderer.Line
+	err   error
+}
+
+var (
+	// previewCache holds recently generated previews.
+	previewCache = preview.NewCache(previewCacheSize)
+
+	// previewChan is used to receive previews which have been generated in the background.
+	previewChan = make(chan previewResult)
+
+	// previewID identifies the most recent request for a preview. Previews generated for any
+	// earlier request are discarded.
+	previewID int
+
+	// cancelPreview cancels the generation of the most recently requested preview.
+	cancelPreview context.CancelFunc = func() {}
+
+	// previewOffset is the number of lines of the current preview which have been scrolled past.
+	// It is reset whenever a different file or directory is previewed.
+	previewOffset int
+
+	// previewedPath is the path of the file or directory which was most recently previewed,
+	// preceded by the URL of the remote host on which it lies.
+	previewedPath string
+
+	// placeholder is displayed in place of a preview which is still being generated.
+	placeholder = []textrenderer.Line{{{Text: "loading…", Fg: termbox.ColorDarkGray}}}
+)
+
+// requestPreview cancels the generation of any previously requested preview, and begins generating
+// a preview of the current selected file or directory in the background. If the preview is ready
+// within placeholderDelay it is returned, otherwise a placeholder is returned in its place and the
+// preview is rendered once it is received by the event loop. No preview is displayed in dual-pane
+// mode.
+func requestPreview() []textrenderer.Line {
+	cancelPreview()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancelPreview = cancel
+	previewID++
+	if screen.DualPane {
+		updateWatches()
+		return nil
+	}
+
+	id := previewID
+	e := nav
+	curSelected := screen.CurrentSelected()
+	if curSelected == "" {
+		// An empty directory at the root of a file system lists nothing to preview.
+		previewedPath = ""
+		updateWatches()
+		return nil
+	}
+	if path := e.Location() + curSelected; path != previewedPath {
+		previewedPath = path
+		previewOffset = 0
+	}
+	updateWatches()
+	screen.PreviewOffset = previewOffset
+	width, height := screen.PreviewWidth(), screen.PreviewHeight()
+	all := listAll
+	scroll := previewOffset
+	go func() {
+		defer recoverPanic()
+		lines, err := cachedPreview(ctx, e, curSelected, width, height, scroll, all)
+		select {
+		case previewChan <- previewResult{id: id, lines: lines, err: err}:
+		case <-ctx.Done():
+		}
+	}()
+
+	timeout := time.NewTimer(placeholderDelay)
+	defer timeout.Stop()
+	for {
+		select {
+		case result := <-previewChan:
+			if result.id != id {
+				continue
+			}
+			if result.err != nil {
+				return errorPreview(result.err)
+			}
+			clampPreviewOffset(result.lines)
+			return result.lines
+		case <-timeout.C:
+			return placeholder
+		}
+	}
+}
+
+// discardPreview cancels the generation of any previously requested preview, so that something
+// else may be displayed in its place.
+func discardPreview() {
+	cancelPreview()
+	previewID++
+}
+
+// receivePreview renders a preview which has been generated in the background, provided that it
+// is a preview of the current selected file or directory.
+func receivePreview(result previewResult) {
+	if result.id != previewID {
+		return
+	}
+	if result.err != nil {
+		screen.Render(errorPreview(result.err))
+		return
+	}
+	clampPreviewOffset(result.lines)
+	screen.Render(result.lines)
+}
+
+// errorPreview is displayed in place of a preview which could not be generated.
+func errorPreview(err error) []textrenderer.Line {
+	return []textrenderer.Line{{{Text: err.Error(), Fg: termbox.ColorDefault, Bg: screen.Theme.Error}}}
+}
+
+// scrollPreview scrolls the preview of the current selected file or directory by a number of
+// lines, generating more of the preview if required.
+func scrollPreview(lines int) {
+	previewOffset = max(previewOffset+lines, 0)
+	screen.Render(requestPreview())
+}
+
+// clampPreviewOffset limits previewOffset so that the preview cannot be scrolled past its last
+// line. A preview is generated with previewOffset lines beyond those which fit in the preview box,
+// so fewer lines means that it ended.
+func clampPreviewOffset(lines []textrenderer.Line) {
+	if len(lines) < screen.PreviewHeight()+previewOffset {
+		previewOffset = max(len(lines)-screen.PreviewHeight(), 0)
+		screen.PreviewOffset = previewOffset
+	}
+}
+
+// cachedPreview returns the cached preview of a file if it has not been modified since the preview
+// was generated, and otherwise generates and caches a new preview. Previews of directories are
+// never cached, as the sizes and other details of their entries change without the directory
+// itself being modified.
+func cachedPreview(ctx context.Context, e explorer.Explorer, curSelected string, width, height,
+	scroll int, listAll bool) ([]textrenderer.Line, error) {
+	path := e.Location() + curSelected
+	info, err := e.Stat(curSelected)
+	if err != nil || info.IsDir() {
+		return genPreview(ctx, e, curSelected, width, height, scroll, listAll)
+	}
+
+	key := preview.CacheKey(path, info, width, height+scroll, listAll)
+	if lines, ok := previewCache.Get(key); ok {
+		return lines, nil
+	}
+	lines, err := genPreview(ctx, e, curSelected, width, height, scroll, listAll)
+	if err == nil {
+		previewCache.Add(key, lines)
+	}
+	return lines, err
+}
+
+// genPreview returns a preview of a file or directory adjacent to the directory which an explorer
+// is in, sized to fit within a preview box of the given dimensions along with scroll further lines
+// which are shown when the preview is scrolled. Directories are previewed with a summary of their
+// contents, files containing structured data are previewed according to their type, and all other
+// files are previewed as plain text. Images are always sized to fit within the box. If the context
+// is cancelled, generation stops early and the context's error is returned.
+func genPreview(ctx context.Context, e explorer.Explorer, curSelected string, width, height,
+	scroll int, listAll bool) ([]textrenderer.Line, error) {
+	if curSelected[len(curSelected)-1] == explorer.PathSepChar {
+		contents, err := e.ListN(ctx, curSelected, height+scroll-preview.SummaryHeight, listAll)
+		if err != nil {
+			return nil, err
+		}
+		summary, err := e.Summarise(ctx, curSelected, listAll)
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		} else if err != nil {
+			// The directory cannot be read, in which case ListN has described why.
+			return textrenderer.PlainLines(contents), nil
+		}
+		return preview.Directory(summary, contents), ctx.Err()
+	}
+
+	kind := preview.KindOf(curSelected)
+	if kind == preview.Text {
+		lines, err := e.ReadN(ctx, curSelected, height+scroll)
+		if err != nil {
+			return nil, err
+		}
+		return textrenderer.PlainLines(lines), ctx.Err()
+	}
+	data, err := e.ReadBytes(ctx, curSelected, preview.Limit(kind))
+	if err != nil {
+		return nil, err
+	}
+	if kind != preview.Image {
+		height += scroll
+	}
+	return preview.Generate(kind, data, width, height), ctx.Err()
+}
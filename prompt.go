@@ -0,0 +1,304 @@
+// Copyright 2019 Max Godfrey
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 		http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/maxgodfrey2004/go-file-manager/explorer"
+	"github.com/maxgodfrey2004/go-file-manager/filesystem"
+	"github.com/maxgodfrey2004/go-file-manager/textrenderer"
+)
+
+// promptPrefixes maps each mode in which the user types into a prompt to the character displayed
+// before what they have typed.
+var promptPrefixes = map[string]string{
+	SearchMode:  "/",
+	CommandMode: ":",
+}
+
+var (
+	// input holds what the user has typed into the prompt.
+	input []rune
+
+	// lastSearch is the most recent search which the user confirmed.
+	lastSearch string
+
+	// searchOrigin is the index which was selected when the user began typing a search. It is
+	// restored if the search is cancelled.
+	searchOrigin int
+)
+
+// openPrompt switches to a mode in which the user types into a prompt at the bottom of the screen.
+func openPrompt(promptMode string) {
+	mode = promptMode
+	input = nil
+	searchOrigin = screen.SelectedIndex
+	renderPrompt()
+}
+
+// renderPrompt renders the prompt along with what the user has typed into it.
+func renderPrompt() {
+	screen.Prompt = promptPrefixes[mode] + string(input)
+	screen.Render(promptPreview())
+}
+
+// closePrompt returns to normal mode, removing the prompt from the screen.
+func closePrompt() {
+	mode = NormalMode
+	input = nil
+	screen.Prompt = ""
+}
+
+// insertChar types a character into the prompt. While the user types a search, the first match is
+// selected as they type.
+func insertChar(ch rune) {
+	input = append(input, ch)
+	if mode == SearchMode {
+		if index, ok := search(string(input), searchOrigin, Down); ok {
+			screen.Select(index)
+		}
+	}
+	renderPrompt()
+}
+
+// deleteChar deletes the last character typed into the prompt, or abandons the prompt if nothing
+// has been typed, as in vim.
+func deleteChar() {
+	if len(input) == 0 {
+		cancelPrompt()
+		return
+	}
+	input = input[:len(input)-1]
+	if mode == SearchMode {
+		index, ok := search(string(input), searchOrigin, Down)
+		if !ok {
+			index = searchOrigin
+		}
+		screen.Select(index)
+	}
+	renderPrompt()
+}
+
+// cancelPrompt abandons the prompt, restoring the selection from before a search was typed.
+func cancelPrompt() {
+	wasSearch := mode == SearchMode
+	closePrompt()
+	if wasSearch {
+		selectIndex(searchOrigin)
+		return
+	}
+	screen.Render(requestPreview())
+}
+
+// confirmPrompt performs the search or command which the user has typed.
+func confirmPrompt() {
+	typed := string(input)
+	promptMode := mode
+	closePrompt()
+
+	switch promptMode {
+	case SearchMode:
+		if typed == "" {
+			typed = lastSearch
+		}
+		lastSearch = typed
+		searchFrom(searchOrigin, Down, 1)
+	case CommandMode:
+		screen.Render(requestPreview())
+		if err := runCommand(typed); err != nil {
+			report(err)
+		}
+	}
+}
+
+// searchNext moves the caret to the next match of the last search in a direction, once for each of
+// the count typed before the key.
+func searchNext(ev keypress) {
+	searchFrom(screen.SelectedIndex, ev.Direction, max(ev.Count, 1))
+}
+
+// searchFrom moves the caret to a match of the last search, skipping over matches so that it
+// moves to the times'th match from an index in a direction.
+func searchFrom(index, direction, times int) {
+	if lastSearch == "" {
+		showMessage(textrenderer.Warning, "No previous search")
+		return
+	}
+	for i := 0; i < times; i++ {
+		next, ok := search(lastSearch, index, direction)
+		if !ok {
+			selectIndex(index)
+			showMessage(textrenderer.Warning, "Pattern not found: "+lastSearch)
+			return
+		}
+		index = next
+	}
+	selectIndex(index)
+}
+
+// search returns the index of the first name in the screen's text which contains query, searching
+// in a direction from the name after an index and wrapping around at either end, so that the name
+// at the index itself is considered last. The search ignores case unless the query contains an
+// upper case letter.
+func search(query string, from, direction int) (int, bool) {
+	n := len(screen.Text)
+	if query == "" || n == 0 {
+		return 0, false
+	}
+	ignoreCase := strings.ToLower(query) == query
+	if ignoreCase {
+		query = strings.ToLower(query)
+	}
+
+	for i := 1; i <= n; i++ {
+		index := ((from+i*direction)%n + n) % n
+		name := screen.Text[index]
+		if ignoreCase {
+			name = strings.ToLower(name)
+		}
+		if strings.Contains(name, query) {
+			return index, true
+		}
+	}
+	return 0, false
+}
+
+// runCommand runs a command typed by the user. A command is either a number, which moves the caret
+// to the file or directory at that position, "cd" followed by a directory to move to, "copy" or
+// "move" followed by a directory into which the current selected file or directory is copied or
+// moved, "delete" followed by a file or directory to delete, "serve" optionally followed by an
+// address at which to share the current directory over HTTP or by "stop", "rename" followed by a
+// rule with which to rename the marked entries, "touch" or "mkdir" followed by the path of a file
+// or directory to create, "symlink" or "link" followed by the path of a link to create to the
+// current selected file or directory, "q" or "quit", or the name of any action which may be bound
+// in normal mode.
+func runCommand(command string) error {
+	fields := strings.Fields(command)
+	if len(fields) == 0 {
+		return nil
+	}
+	name, args := fields[0], fields[1:]
+	_, rest := splitCommand(command)
+
+	if line, err := strconv.Atoi(name); err == nil && len(args) == 0 {
+		selectIndex(line - 1)
+		return nil
+	}
+	switch name {
+	case "q", "quit", "q!", "quit!":
+		handleEvent(keypress{EventType: Quit})
+		return nil
+	case "cd":
+		return changeDirectory(rest)
+	case "copy", "cp":
+		return transfer(name, rest, false)
+	case "move", "mv":
+		return transfer(name, rest, true)
+	case "delete", "rm":
+		return deleteEntry(name, rest)
+	case "serve":
+		return serveCommand(name, strings.TrimSpace(rest))
+	case "touch":
+		return createFile(name, rest)
+	case "mkdir":
+		return makeDirectory(name, rest)
+	case "symlink":
+		return linkEntry(name, rest, true)
+	case "link":
+		return linkEntry(name, rest, false)
+	case "rename":
+		return renameWithRule(name, rest)
+	}
+
+	a, ok := actions[name]
+	if !ok || !usableIn(a, NormalMode) || len(args) > 0 {
+		return fmt.Errorf("not a command: %s", command)
+	}
+	handleEvent(keypress{EventType: a.event, Direction: a.direction})
+	return nil
+}
+
+// splitCommand splits a command into its name and the rest of the command after the space which
+// follows the name. The rest is otherwise left as it was typed, as names and rules may begin or end
+// with spaces, or contain several in a row.
+func splitCommand(command string) (string, string) {
+	command = strings.TrimLeftFunc(command, unicode.IsSpace)
+	end := strings.IndexFunc(command, unicode.IsSpace)
+	if end < 0 {
+		return command, ""
+	}
+	_, size := utf8.DecodeRuneInString(command[end:])
+	return command[:end], command[end+size:]
+}
+
+// changeDirectory moves nav, the explorer, to a directory given either as an absolute path, a path
+// beginning with '~', a path relative to the current directory, or the URL of a directory on a
+// remote host. Paths beginning with '~' lie on the local file system, while other paths lie on the
+// same file system as the current directory. With no path, it moves to the user's home directory.
+// A remote host which has not been connected to is connected to in the background, and nav moves
+// once it has been.
+func changeDirectory(path string) error {
+	if path == "" {
+		path = "~"
+	}
+	if isRemote(path) {
+		openRemote(path, func(moved explorer.Explorer, err error) {
+			if err == nil {
+				err = enterDirectory(moved)
+			}
+			if err != nil {
+				report(fmt.Errorf("cd %s: %v", path, err))
+			}
+		})
+		return nil
+	}
+
+	moved := nav
+	var err error
+	if path == explorer.PathSep {
+		moved.Path = ""
+	} else if path[0] == '~' {
+		moved.FS, moved.Remote = filesystem.OS(), ""
+		err = moved.MoveAbsolute(path)
+	} else if path[0] == explorer.PathSepChar {
+		err = moved.MoveAbsolute(path)
+	} else {
+		err = moved.MoveMultiple(path)
+	}
+	if err == nil {
+		err = enterDirectory(moved)
+	}
+	if err != nil {
+		return fmt.Errorf("cd %s: %v", path, err)
+	}
+	return nil
+}
+
+// enterDirectory moves nav, the explorer, to the directory which another explorer is in, listing
+// it. If it cannot be listed, nav stays where it was.
+func enterDirectory(moved explorer.Explorer) error {
+	previous := nav
+	nav = moved
+	if err := listDirectory(); err != nil {
+		nav = previous
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+// Copyright 2019 Max Godfrey
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 		http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"testing"
+)
+
+func TestSearch(t *testing.T) {
+	defer func(text []string) { screen.Text = text }(screen.Text)
+	screen.Text = []string{"../", "Alpha", "beta/", "ALPHABET", "gamma"}
+
+	tests := []struct {
+		query     string
+		from      int
+		direction int
+		index     int
+		found     bool
+	}{
+		{"alpha", 0, Down, 1, true},
+		{"alpha", 1, Down, 3, true},
+		{"alpha", 3, Down, 1, true}, // wraps around at the end
+		{"alpha", 1, Up, 3, true},   // wraps around at the start
+		{"ALPHA", 0, Down, 3, true}, // an upper case letter makes the search case sensitive
+		{"gamma", 4, Down, 4, true}, // the name at the index itself is considered last
+		{"delta", 0, Down, 0, false},
+		{"", 0, Down, 0, false},
+	}
+	for _, test := range tests {
+		index, found := search(test.query, test.from, test.direction)
+		if index != test.index || found != test.found {
+			t.Errorf("search(%q, %d, %d) = %d, %v, want %d, %v", test.query, test.from,
+				test.direction, index, found, test.index, test.found)
+		}
+	}
+}
+
+func TestSplitCommand(t *testing.T) {
+	tests := []struct {
+		command string
+		name    string
+		rest    string
+	}{
+		{"quit", "quit", ""},
+		{"  cd", "cd", ""},
+		{"copy /tmp/dir/", "copy", "/tmp/dir/"},
+		{"move  leading", "move", " leading"},
+		{"copy trailing ", "copy", "trailing "},
+		{"move two  spaces", "move", "two  spaces"},
+		{"delete foo  bar", "delete", "foo  bar"},
+		{"delete foo ", "delete", "foo "},
+	}
+	for _, test := range tests {
+		name, rest := splitCommand(test.command)
+		if name != test.name || rest != test.rest {
+			t.Errorf("splitCommand(%q) = %q, %q, want %q, %q", test.command, name, rest, test.name,
+				test.rest)
+		}
+	}
+}
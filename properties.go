@@ -0,0 +1,470 @@
+// Copyright 2019 Max Godfrey
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 		http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os/user"
+	"strconv"
+	"strings"
+
+	"github.com/maxgodfrey2004/go-file-manager/explorer"
+	"github.com/maxgodfrey2004/go-file-manager/filesystem"
+	"github.com/maxgodfrey2004/go-file-manager/textrenderer"
+	"github.com/nsf/termbox-go"
+)
+
+// permissionRows are the rows of the grid of checkboxes in the properties dialog, each holding the
+// bits of a file's mode which are toggled in its columns.
+var permissionRows = [...]struct {
+	label string
+	bits  [3]fs.FileMode
+}{
+	{"owner", [3]fs.FileMode{0400, 0200, 0100}},
+	{"group", [3]fs.FileMode{0040, 0020, 0010}},
+	{"others", [3]fs.FileMode{0004, 0002, 0001}},
+	{"special", [3]fs.FileMode{fs.ModeSetuid, fs.ModeSetgid, fs.ModeSticky}},
+}
+
+// The rows of the properties dialog which follow the grid of checkboxes.
+const (
+	octalRow = len(permissionRows) + iota
+	userRow
+	groupRow
+	recursiveRow
+)
+
+// maxFailures is the number of files which could not be changed which are listed after changing
+// the properties of files, beyond which they are only counted.
+const maxFailures = 10
+
+// propertiesDialog holds the state of the properties dialog while it is open.
+type propertiesDialog struct {
+	targets   []string    // The entries of the current directory which are changed.
+	local     bool        // Whether the entries lie on the local file system.
+	hasDir    bool        // Whether any of the entries is a directory.
+	initial   fs.FileMode // The mode of the first entry when the dialog was opened.
+	mode      fs.FileMode // The mode which the entries are to be given.
+	octal     string      // The mode as it has been typed in octal.
+	user      string      // The user which is to own the entries, as it has been typed.
+	group     string      // The group which is to own the entries, as it has been typed.
+	owner     [2]string   // The user and group which owned the first entry when it was opened.
+	invalid   [2]bool     // Whether the user and group could not be found when last applied.
+	recursive bool        // Whether the contents of directories are changed as well.
+	row       int         // The row which has focus.
+	column    int         // The column of the grid of checkboxes which has focus.
+	failures  []error     // The files which could not be changed, once the change has been made.
+}
+
+// properties is the properties dialog, while it is open.
+var properties propertiesDialog
+
+// openProperties opens the properties dialog, in which the permissions and ownership of the marked
+// entries of the current directory, or of the current selected entry if none are marked, are
+// changed. The dialog begins with those of the first entry.
+func openProperties() {
+	targets := markedNames()
+	if len(targets) == 0 && isMarkable(screen.CurrentSelected()) {
+		targets = []string{screen.CurrentSelected()}
+	}
+	if len(targets) == 0 {
+		showMessage(textrenderer.Warning, "There is nothing to change")
+		return
+	}
+	info, err := nav.Stat(strings.TrimSuffix(targets[0], explorer.PathSep))
+	if err != nil {
+		report(err)
+		return
+	}
+
+	properties = propertiesDialog{
+		targets: targets,
+		local:   nav.Remote == "",
+		initial: info.Mode() & filesystem.ModeBits,
+	}
+	for _, name := range targets {
+		if strings.HasSuffix(name, explorer.PathSep) {
+			properties.hasDir = true
+		}
+	}
+	properties.setMode(properties.initial)
+	if uid, gid, ok := filesystem.Owner(info); ok {
+		properties.owner = [2]string{userName(uid, properties.local), groupName(gid, properties.local)}
+	}
+	properties.user, properties.group = properties.owner[0], properties.owner[1]
+	mode = PropertiesMode
+	renderProperties()
+}
+
+// closeProperties closes the properties dialog without changing anything.
+func closeProperties() {
+	mode = NormalMode
+	properties = propertiesDialog{}
+	screen.Dialog = nil
+	screen.Render(requestPreview())
+}
+
+// renderProperties renders the screen along with the properties dialog.
+func renderProperties() {
+	screen.Dialog = propertiesLines()
+	screen.Render(requestPreview())
+}
+
+// setMode sets the mode which the entries are to be given, along with the mode typed in octal.
+func (p *propertiesDialog) setMode(mode fs.FileMode) {
+	p.mode = mode
+	p.octal = formatOctal(mode)
+}
+
+// lastRow returns the last row of the dialog, which is only the checkbox for changing the contents
+// of directories if there are any directories to change.
+func (p *propertiesDialog) lastRow() int {
+	if p.hasDir {
+		return recursiveRow
+	}
+	return groupRow
+}
+
+// moveField moves the focus to the row above or below the current one, wrapping around at either
+// end, once for each of the count typed before the key.
+func moveField(ev keypress) {
+	rows := properties.lastRow() + 1
+	properties.row = ((properties.row+ev.Direction*max(ev.Count, 1))%rows + rows) % rows
+	renderProperties()
+}
+
+// moveColumn moves the focus to the column of the grid of checkboxes to the left or right of the
+// current one.
+func moveColumn(ev keypress) {
+	column := properties.column + ev.Direction
+	if properties.row < len(permissionRows) && column >= 0 && column < 3 {
+		properties.column = column
+	}
+	renderProperties()
+}
+
+// toggleField toggles the checkbox which has focus.
+func toggleField() {
+	p := &properties
+	switch {
+	case p.failures != nil:
+		return
+	case p.row < len(permissionRows):
+		p.setMode(p.mode ^ permissionRows[p.row].bits[p.column])
+	case p.row == recursiveRow:
+		p.recursive = !p.recursive
+	}
+	renderProperties()
+}
+
+// field returns the text field of the dialog which has focus, if it is one.
+func (p *propertiesDialog) field() *string {
+	switch p.row {
+	case octalRow:
+		return &p.octal
+	case userRow:
+		return &p.user
+	case groupRow:
+		return &p.group
+	}
+	return nil
+}
+
+// insertFieldChar types a character into the text field which has focus. Only octal digits may
+// be typed into the mode, which is updated as soon as it has been typed in full.
+func insertFieldChar(ch rune) {
+	field := properties.field()
+	if field == nil || properties.failures != nil {
+		return
+	}
+	if properties.row == octalRow && (ch < '0' || ch > '7' || len(*field) == 4) {
+		return
+	}
+	*field += string(ch)
+	properties.edited()
+	if mode, err := parseOctal(properties.octal); err == nil && properties.row == octalRow {
+		properties.mode = mode
+	}
+	renderProperties()
+}
+
+// edited notes that the text field which has focus was edited, so that a user or group which could
+// not be found is no longer drawn as an error until it is applied again.
+func (p *propertiesDialog) edited() {
+	switch p.row {
+	case userRow:
+		p.invalid[0] = false
+	case groupRow:
+		p.invalid[1] = false
+	}
+}
+
+// deleteFieldChar deletes the last character typed into the text field which has focus.
+func deleteFieldChar() {
+	field := properties.field()
+	if field == nil || *field == "" || properties.failures != nil {
+		return
+	}
+	runes := []rune(*field)
+	*field = string(runes[:len(runes)-1])
+	properties.edited()
+	if mode, err := parseOctal(properties.octal); err == nil && properties.row == octalRow {
+		properties.mode = mode
+	}
+	renderProperties()
+}
+
+// applyProperties changes the permissions and ownership of the entries as they have been set in
+// the dialog. Only the bits of the mode which were changed from that of the first entry are changed
+// in each entry, and the owner is only changed if another user or group was given. Any files which
+// could not be changed are listed in the dialog, which is otherwise closed. Users and groups are
+// only looked up here, rather than as they are typed.
+func applyProperties() {
+	p := properties
+	if p.failures != nil {
+		closeProperties()
+		return
+	}
+	mode, err := parseOctal(p.octal)
+	if err != nil {
+		report(err)
+		return
+	}
+	attrs := explorer.Attributes{Set: mode &^ p.initial, Clear: p.initial &^ mode, UID: -1, GID: -1}
+	if p.user != p.owner[0] {
+		if attrs.UID, err = lookupUser(p.user, p.local); err != nil {
+			properties.invalid[0] = true
+			renderProperties()
+			report(err)
+			return
+		}
+	}
+	if p.group != p.owner[1] {
+		if attrs.GID, err = lookupGroup(p.group, p.local); err != nil {
+			properties.invalid[1] = true
+			renderProperties()
+			report(err)
+			return
+		}
+	}
+	if attrs.Set == 0 && attrs.Clear == 0 && attrs.UID < 0 && attrs.GID < 0 {
+		closeProperties()
+		showMessage(textrenderer.Info, "Nothing was changed")
+		return
+	}
+
+	var failures []error
+	for _, name := range p.targets {
+		failures = append(failures, nav.ChangeAttributes(name, attrs, p.recursive)...)
+	}
+	if err := reloadDirectory(); err != nil {
+		report(err)
+	}
+	if err := updateOtherPane(); err != nil {
+		report(err)
+	}
+	if len(failures) == 0 {
+		closeProperties()
+		showMessage(textrenderer.Info, "Changed the properties of "+describeEntries(p.targets))
+		return
+	}
+	properties.failures = failures
+	renderProperties()
+	message := fmt.Sprintf("%d files could not be changed", len(failures))
+	if len(failures) == 1 {
+		message = "1 file could not be changed"
+	}
+	showMessage(textrenderer.Error, message)
+}
+
+// describeEntries returns a description of entries of the current directory: the name of a single
+// entry, or otherwise their number.
+func describeEntries(names []string) string {
+	if len(names) == 1 {
+		return strings.TrimSuffix(names[0], explorer.PathSep)
+	}
+	return fmt.Sprintf("%d entries", len(names))
+}
+
+// propertiesLines returns the lines of the properties dialog. The field with focus is drawn in
+// reverse, and fields which hold something invalid are drawn as errors: a mode as soon as it is
+// typed, and a user or group once it could not be found when applying the change. Once the change
+// has been made, the dialog instead lists the files which could not be changed.
+func propertiesLines() []textrenderer.Line {
+	p := &properties
+	title := textrenderer.Line{
+		{Text: "Properties of " + describeEntries(p.targets), Fg: termbox.ColorCyan},
+	}
+	if p.failures != nil {
+		lines := []textrenderer.Line{title, {}}
+		for i, err := range p.failures {
+			if i == maxFailures {
+				lines = append(lines, textrenderer.PlainLine(fmt.Sprintf("and %d more",
+					len(p.failures)-maxFailures)))
+				break
+			}
+			lines = append(lines, textrenderer.Line{{Text: err.Error(), Bg: screen.Theme.Error}})
+		}
+		return append(lines, textrenderer.Line{}, textrenderer.Line{
+			{Text: "Enter or Esc closes", Fg: termbox.ColorDarkGray}})
+	}
+
+	heading := func(columns ...string) textrenderer.Line {
+		return textrenderer.Line{{Text: fmt.Sprintf("%-10s%-7s%-7s%s", "", columns[0], columns[1],
+			columns[2]), Fg: termbox.ColorDarkGray}}
+	}
+	lines := []textrenderer.Line{title, {}, heading("read", "write", "execute")}
+	for row, r := range permissionRows {
+		if row == len(permissionRows)-1 {
+			lines = append(lines, heading("setuid", "setgid", "sticky"))
+		}
+		line := textrenderer.Line{{Text: fmt.Sprintf("%-10s", r.label)}}
+		for column, bit := range r.bits {
+			line = append(line, p.span(checkbox(p.mode&bit != 0), row, column, true),
+				textrenderer.Span{Text: "    "})
+		}
+		lines = append(lines, line)
+	}
+
+	_, octalErr := parseOctal(p.octal)
+	lines = append(lines, textrenderer.Line{},
+		p.textLine("mode", p.octal, octalRow, octalErr == nil),
+		p.textLine("user", p.user, userRow, !p.invalid[0]),
+		p.textLine("group", p.group, groupRow, !p.invalid[1]))
+	if p.hasDir {
+		lines = append(lines, textrenderer.Line{
+			{Text: fmt.Sprintf("%-10s", "contents")},
+			p.span(checkbox(p.recursive), recursiveRow, 0, true),
+			{Text: " also change everything within directories", Fg: termbox.ColorDarkGray},
+		})
+	}
+	return append(lines, textrenderer.Line{}, textrenderer.Line{
+		{Text: "Space toggles, Enter applies, Esc cancels", Fg: termbox.ColorDarkGray}})
+}
+
+// textLine returns the line of the dialog holding a text field.
+func (p *propertiesDialog) textLine(label, text string, row int, valid bool) textrenderer.Line {
+	return textrenderer.Line{{Text: fmt.Sprintf("%-10s", label)}, p.span(fmt.Sprintf("%-8s", text),
+		row, 0, valid)}
+}
+
+// span returns a field of the dialog, drawn in reverse if it has focus.
+func (p *propertiesDialog) span(text string, row, column int, valid bool) textrenderer.Span {
+	span := textrenderer.Span{Text: text}
+	if row == p.row && (row >= len(permissionRows) || column == p.column) {
+		span.Fg = termbox.AttrReverse
+	}
+	if !valid {
+		span.Bg = screen.Theme.Error
+	}
+	return span
+}
+
+// checkbox returns a checkbox of the dialog, which is checked if set is.
+func checkbox(set bool) string {
+	if set {
+		return "[x]"
+	}
+	return "[ ]"
+}
+
+// formatOctal returns the permissions of a mode in octal, preceded by a digit holding its setuid
+// (4), setgid (2) and sticky (1) bits, as chmod accepts them.
+func formatOctal(mode fs.FileMode) string {
+	special := 0
+	for i, bit := range permissionRows[len(permissionRows)-1].bits {
+		if mode&bit != 0 {
+			special |= 4 >> i
+		}
+	}
+	return fmt.Sprintf("%d%03o", special, mode.Perm())
+}
+
+// parseOctal returns the mode described by three or four octal digits, as chmod accepts them.
+func parseOctal(octal string) (fs.FileMode, error) {
+	n, err := strconv.ParseUint(octal, 8, 32)
+	if err != nil || len(octal) < 3 || len(octal) > 4 {
+		return 0, fmt.Errorf("%q is not a mode of three or four octal digits", octal)
+	}
+	mode := fs.FileMode(n) & fs.ModePerm
+	for i, bit := range permissionRows[len(permissionRows)-1].bits {
+		if n>>9&(4>>i) != 0 {
+			mode |= bit
+		}
+	}
+	return mode, nil
+}
+
+// userName returns the name by which the user with an ID is shown: its name in the user database
+// for local files, or otherwise its ID, as the database does not describe other hosts.
+func userName(uid int, local bool) string {
+	id := strconv.Itoa(uid)
+	if local {
+		if u, err := user.LookupId(id); err == nil {
+			return u.Username
+		}
+	}
+	return id
+}
+
+// groupName returns the name by which the group with an ID is shown, in the same way as userName.
+func groupName(gid int, local bool) string {
+	id := strconv.Itoa(gid)
+	if local {
+		if g, err := user.LookupGroupId(id); err == nil {
+			return g.Name
+		}
+	}
+	return id
+}
+
+// lookupUser returns the ID of a user given by its name or ID. Users of local files must be found
+// in the user database, while users of remote files may only be given by their ID.
+func lookupUser(name string, local bool) (int, error) {
+	if !local {
+		return remoteID("user", name)
+	}
+	u, err := user.Lookup(name)
+	if err != nil {
+		if u, err = user.LookupId(name); err != nil {
+			return -1, fmt.Errorf("unknown user %q", name)
+		}
+	}
+	return strconv.Atoi(u.Uid)
+}
+
+// lookupGroup returns the ID of a group given by its name or ID, in the same way as lookupUser.
+func lookupGroup(name string, local bool) (int, error) {
+	if !local {
+		return remoteID("group", name)
+	}
+	g, err := user.LookupGroup(name)
+	if err != nil {
+		if g, err = user.LookupGroupId(name); err != nil {
+			return -1, fmt.Errorf("unknown group %q", name)
+		}
+	}
+	return strconv.Atoi(g.Gid)
+}
+
+// remoteID returns the ID of the user or group of a remote file, which must be given by its ID.
+func remoteID(kind, id string) (int, error) {
+	n, err := strconv.Atoi(id)
+	if err != nil || n < 0 {
+		return -1, fmt.Errorf("the %s of a remote file must be given by its ID, not %q", kind, id)
+	}
+	return n, nil
+}
@@ -0,0 +1,136 @@
+// Copyright 2019 Max Godfrey
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 		http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"time"
+
+	"github.com/maxgodfrey2004/go-file-manager/explorer"
+	"github.com/maxgodfrey2004/go-file-manager/watch"
+)
+
+// refreshDelay is how long the file system must be left unchanged before the explorer is refreshed
+// to show the changes, so that a burst of changes causes a single refresh.
+const refreshDelay = 200 * time.Millisecond
+
+var (
+	// watcher watches the directories and file being displayed, or is nil if they are not watched.
+	watcher *watch.Watcher
+
+	// watchedPaths are the paths which watcher is currently watching.
+	watchedPaths []string
+
+	// refreshPending is whether the file system changed while the user was typing into a prompt,
+	// in which case the explorer is refreshed once they have finished.
+	refreshPending bool
+)
+
+// startWatching begins watching for changes to what is displayed, unless the user has disabled
+// it. The explorer is still usable if they cannot be watched.
+func startWatching() {
+	if !userConfig.Options.AutoRefresh {
+		return
+	}
+	watcher, _ = watch.New(refreshDelay)
+}
+
+// watchChanges returns the channel which receives changes to what is displayed, or nil if they are
+// not watched.
+func watchChanges() <-chan struct{} {
+	if watcher == nil {
+		return nil
+	}
+	return watcher.Changes()
+}
+
+// watchPanics returns the channel which receives a panic which occurred while watching for changes,
+// or nil if they are not watched.
+func watchPanics() <-chan watch.Panic {
+	if watcher == nil {
+		return nil
+	}
+	return watcher.Panics()
+}
+
+// updateWatches watches the current directory, any directories expanded within it, the directory
+// of the other pane, and the file or directory being previewed. Directories on remote hosts cannot
+// be watched.
+func updateWatches() {
+	if watcher == nil {
+		return
+	}
+	var paths []string
+	if nav.Remote == "" {
+		paths = append(paths, nav.GetPath())
+		if treeMode {
+			for _, name := range screen.Text {
+				if explorer.IsExpandable(name) && expanded[nav.EntryPath(name)] {
+					paths = append(paths, nav.EntryPath(name))
+				}
+			}
+		}
+		if !screen.DualPane && previewedPath != "" {
+			paths = append(paths, previewedPath)
+		}
+	}
+	if screen.DualPane && otherPane.nav.Remote == "" {
+		paths = append(paths, otherPane.nav.GetPath())
+	}
+
+	if equalStrings(paths, watchedPaths) {
+		return
+	}
+	watchedPaths = paths
+	if err := watcher.Watch(paths...); err != nil {
+		report(err)
+	}
+}
+
+// equalStrings reports whether two lists of strings are the same.
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// refresh lists the displayed directories again to show changes made to them on disk, keeping the
+// selection and scroll position. If the selected entry no longer exists, the caret stays where it
+// was. While the user is typing into a prompt, refreshing waits until they have finished.
+func refresh() {
+	if mode != NormalMode {
+		refreshPending = true
+		return
+	}
+	refreshPending = false
+	p := currentPane()
+	index := screen.SelectedIndex
+	err := showPane(p)
+	if screen.CurrentSelected() != p.selected {
+		screen.Select(index)
+	}
+	if otherErr := updateOtherPane(); err == nil {
+		err = otherErr
+	}
+	screen.Render(requestPreview())
+	if err != nil {
+		report(err)
+	}
+}
@@ -0,0 +1,299 @@
+// Copyright 2019 Max Godfrey
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 		http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/maxgodfrey2004/go-file-manager/explorer"
+	"github.com/maxgodfrey2004/go-file-manager/filesystem"
+	"github.com/maxgodfrey2004/go-file-manager/textrenderer"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// sshTimeout is how long to wait for a connection to a remote host to be established.
+const sshTimeout = 10 * time.Second
+
+// sshKeyFiles are the names of the private keys within ~/.ssh with which connections to remote
+// hosts are authenticated, if they are not protected by a passphrase.
+var sshKeyFiles = []string{"id_ed25519", "id_ecdsa", "id_rsa"}
+
+var (
+	// connections holds the file system of each remote host or bucket which has been connected to,
+	// by its URL, so that each is only connected to once.
+	connections = make(map[string]filesystem.FileSystem)
+
+	// dialChan is used to receive connections which have been made in the background.
+	dialChan = make(chan dialResult)
+)
+
+// dialResult is the outcome of connecting to a remote host or bucket in the background.
+type dialResult struct {
+	remote string                             // The URL of the remote host or bucket.
+	fsys   filesystem.FileSystem              // Its file system, if it was connected to.
+	err    error                              // The error which prevented connecting to it.
+	open   func(filesystem.FileSystem, error) // Opens the location which was being connected to.
+}
+
+// isRemote reports whether a location is the URL of a remote host, such as sftp://user@host/path.
+func isRemote(location string) bool {
+	return strings.Contains(location, "://")
+}
+
+// openRemote opens a directory of a remote host or object store, given by a URL of the form
+// sftp://user@host:port/path or s3://bucket/prefix, calling done with an explorer in it or the
+// error which prevented opening it. If the remote host or bucket has not been connected to, or its
+// connection has been lost, it is connected to in the background so that the file manager stays
+// usable, and done is called once the user is no longer typing into a prompt.
+func openRemote(location string, done func(explorer.Explorer, error)) {
+	u, err := url.Parse(location)
+	if err != nil {
+		done(nav, err)
+		return
+	}
+	var remote string
+	var dial func() (filesystem.FileSystem, error)
+	switch u.Scheme {
+	case "sftp":
+		remote, dial, err = sftpRemote(u)
+	case "s3":
+		remote, dial, err = s3Remote(u)
+	default:
+		err = errors.New("only sftp:// and s3:// locations are supported")
+	}
+	if err != nil {
+		done(nav, fmt.Errorf("%s: %v", location, err))
+		return
+	}
+
+	open := func(fsys filesystem.FileSystem, err error) {
+		if err != nil {
+			done(nav, fmt.Errorf("%s: %v", remote, err))
+			return
+		}
+		done(remoteExplorer(fsys, remote, u.Path))
+	}
+	if fsys, ok := connections[remote]; ok && !isLost(fsys) {
+		open(fsys, nil)
+		return
+	}
+	showMessage(textrenderer.Info, fmt.Sprintf("Connecting to %s…", remote))
+	go func() {
+		defer recoverPanic()
+		fsys, err := dial()
+		dialChan <- dialResult{remote: remote, fsys: fsys, err: err, open: open}
+	}()
+}
+
+// dialled returns the channel which receives connections made in the background, or nil while the
+// user is typing into a prompt, so that the locations being opened do not replace the screen
+// beneath it.
+func dialled() <-chan dialResult {
+	if mode != NormalMode {
+		return nil
+	}
+	return dialChan
+}
+
+// receiveDial opens a location once its remote host or bucket has been connected to, replacing
+// any connection to it which has been lost.
+func receiveDial(result dialResult) {
+	if result.err == nil {
+		if fsys, ok := connections[result.remote]; ok && !isLost(fsys) {
+			// It was connected to again while this connection was being made.
+			closeConnection(result.fsys)
+			result.fsys = fsys
+		} else {
+			if ok {
+				closeConnection(fsys)
+			}
+			connections[result.remote] = result.fsys
+		}
+	}
+	screen.Message = ""
+	result.open(result.fsys, result.err)
+}
+
+// isLost reports whether the connection to a remote host has been lost, so that it must be
+// connected to again.
+func isLost(fsys filesystem.FileSystem) bool {
+	l, ok := fsys.(interface{ Lost() bool })
+	return ok && l.Lost()
+}
+
+// remoteExplorer returns an explorer in a directory of the file system of a remote host or bucket.
+// Without a directory, it is in the directory in which the remote host's SFTP server started.
+func remoteExplorer(fsys filesystem.FileSystem, remote, dir string) (explorer.Explorer, error) {
+	e := nav
+	e.FS, e.Remote, e.Path = fsys, remote, ""
+	if wd, ok := fsys.(interface{ Getwd() (string, error) }); ok && dir == "" {
+		name, err := wd.Getwd()
+		if err != nil {
+			return e, err
+		}
+		dir = "/" + strings.TrimPrefix(name, ".")
+	}
+	if dir == "" || dir == "/" {
+		return e, e.DirectoryExists("/")
+	}
+	return e, e.MoveAbsolute(dir)
+}
+
+// sftpRemote returns the URL of the remote host of an sftp:// location, along with a function
+// which connects to it. The user defaults to the local user, and the port to 22. Without a path,
+// the location is the directory in which the SFTP server started, which is usually the user's home
+// directory.
+func sftpRemote(u *url.URL) (string, func() (filesystem.FileSystem, error), error) {
+	if u.Hostname() == "" {
+		return "", nil, errors.New("no host was given")
+	}
+	username := u.User.Username()
+	// The current user is read here rather than while dialling, which happens in the background.
+	var homeDir string
+	if nav.CurrentUser != nil {
+		if username == "" {
+			username = nav.CurrentUser.Username
+		}
+		homeDir = nav.CurrentUser.HomeDir
+	}
+	addr := u.Host
+	if u.Port() == "" {
+		addr = net.JoinHostPort(u.Hostname(), "22")
+	}
+	dial := func() (filesystem.FileSystem, error) {
+		password, _ := u.User.Password()
+		config, agentConn, err := sshConfig(homeDir, username, password)
+		if err != nil {
+			return nil, err
+		}
+		if agentConn != nil {
+			// The agent is only needed to authenticate, which is done by the time DialSFTP returns.
+			defer agentConn.Close()
+		}
+		return filesystem.DialSFTP(addr, config)
+	}
+	return "sftp://" + username + "@" + u.Host, dial, nil
+}
+
+// s3Remote returns the URL of the bucket of an s3:// location, along with a function which
+// connects to it. The object store is reached with the credentials, region and endpoint given by
+// the same environment variables as the AWS command line interface, so that AWS_ENDPOINT_URL
+// selects another S3-compatible store such as MinIO.
+func s3Remote(u *url.URL) (string, func() (filesystem.FileSystem, error), error) {
+	if u.Host == "" {
+		return "", nil, errors.New("no bucket was given")
+	}
+	region := firstEnv("AWS_REGION", "AWS_DEFAULT_REGION")
+	if region == "" {
+		region = "us-east-1"
+	}
+	endpoint := firstEnv("AWS_ENDPOINT_URL_S3", "AWS_ENDPOINT_URL")
+	if endpoint == "" {
+		endpoint = "https://s3." + region + ".amazonaws.com"
+	}
+	dial := func() (filesystem.FileSystem, error) {
+		return filesystem.NewS3(filesystem.S3Config{
+			Endpoint:     endpoint,
+			Region:       region,
+			Bucket:       u.Host,
+			AccessKey:    os.Getenv("AWS_ACCESS_KEY_ID"),
+			SecretKey:    os.Getenv("AWS_SECRET_ACCESS_KEY"),
+			SessionToken: os.Getenv("AWS_SESSION_TOKEN"),
+		})
+	}
+	return "s3://" + u.Host, dial, nil
+}
+
+// firstEnv returns the value of the first of a number of environment variables which is set.
+func firstEnv(names ...string) string {
+	for _, name := range names {
+		if value := os.Getenv(name); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// sshConfig returns the configuration with which to connect to a remote host as a user. The user is
+// authenticated by an SSH agent if one is running, by any keys in ~/.ssh without a passphrase, and
+// by a password if one is given. The host's key must be listed in ~/.ssh/known_hosts, within the
+// given home directory. The connection to the SSH agent, if there is one, is returned so that it
+// may be closed once the user has been authenticated.
+func sshConfig(homeDir, username, password string) (*ssh.ClientConfig, net.Conn, error) {
+	if homeDir == "" {
+		return nil, nil, errors.New("cannot find the home directory, which holds ~/.ssh")
+	}
+	sshDir := filepath.Join(homeDir, ".ssh")
+	hostKeys, err := knownhosts.New(filepath.Join(sshDir, "known_hosts"))
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot read known hosts: %v", err)
+	}
+
+	var auth []ssh.AuthMethod
+	var agentConn net.Conn
+	if sock := os.Getenv("SSH_AUTH_SOCK"); sock != "" {
+		if agentConn, err = net.Dial("unix", sock); err == nil {
+			auth = append(auth, ssh.PublicKeysCallback(agent.NewClient(agentConn).Signers))
+		}
+	}
+	var signers []ssh.Signer
+	for _, name := range sshKeyFiles {
+		data, err := ioutil.ReadFile(filepath.Join(sshDir, name))
+		if err != nil {
+			continue
+		}
+		if signer, err := ssh.ParsePrivateKey(data); err == nil {
+			signers = append(signers, signer)
+		}
+	}
+	if len(signers) > 0 {
+		auth = append(auth, ssh.PublicKeys(signers...))
+	}
+	if password != "" {
+		auth = append(auth, ssh.Password(password))
+	}
+
+	return &ssh.ClientConfig{
+		User:            username,
+		Auth:            auth,
+		HostKeyCallback: hostKeys,
+		Timeout:         sshTimeout,
+	}, agentConn, nil
+}
+
+// closeConnections closes the connection to every remote host.
+func closeConnections() {
+	for _, fsys := range connections {
+		closeConnection(fsys)
+	}
+}
+
+// closeConnection closes the connection to a remote host.
+func closeConnection(fsys filesystem.FileSystem) {
+	if c, ok := fsys.(io.Closer); ok {
+		c.Close()
+	}
+}
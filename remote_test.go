@@ -0,0 +1,63 @@
+// Copyright 2019 Max Godfrey
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 		http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/maxgodfrey2004/go-file-manager/filesystem"
+)
+
+func TestEmptyS3Root(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Every listing of the bucket is empty.
+		w.Write([]byte("<ListBucketResult></ListBucketResult>"))
+	}))
+	defer server.Close()
+	fsys, err := filesystem.NewS3(filesystem.S3Config{
+		Endpoint: server.URL,
+		Bucket:   "bucket",
+		Client:   server.Client(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	previous := nav
+	defer func() { nav = previous }()
+	if nav, err = remoteExplorer(fsys, "s3://bucket", ""); err != nil {
+		t.Fatal(err)
+	}
+	dirContents, _, _, err := listEntries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	screen.Init(nav.Location(), dirContents)
+	if selected := screen.CurrentSelected(); selected != "" {
+		t.Errorf("CurrentSelected() = %q in an empty bucket, want \"\"", selected)
+	}
+	if lines := requestPreview(); lines != nil {
+		t.Errorf("requestPreview() = %v in an empty bucket, want nothing", lines)
+	}
+	if err := transfer("copy", "/elsewhere", false); err == nil {
+		t.Error("copy in an empty bucket did not fail")
+	}
+	selectContents()
+	if isMarkable(screen.CurrentSelected()) {
+		t.Error("nothing was selected, but it may be marked")
+	}
+}
@@ -0,0 +1,280 @@
+// Copyright 2019 Max Godfrey
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 		http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/maxgodfrey2004/go-file-manager/explorer"
+	"github.com/maxgodfrey2004/go-file-manager/rename"
+	"github.com/maxgodfrey2004/go-file-manager/textrenderer"
+	"github.com/nsf/termbox-go"
+)
+
+// bulkRename renames the marked entries of the current directory, or every entry if none are
+// marked, in the user's editor.
+func bulkRename() {
+	if err := renameInEditor(); err != nil {
+		report(fmt.Errorf("bulk rename: %v", err))
+	}
+}
+
+// renameInEditor writes the names of the entries to be renamed by bulkRename to a temporary file,
+// one on each line, which is opened in the user's editor. Once the editor exits, each entry is
+// renamed to the name left on its line, and the entries are renamed together so that names may be
+// swapped between them. Lines must not be added or removed.
+func renameInEditor() error {
+	names := renameTargets()
+	if len(names) == 0 {
+		return errors.New("there is nothing to rename")
+	}
+
+	file, err := ioutil.TempFile("", "go-file-manager-rename-*.txt")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+	_, err = file.WriteString(strings.Join(names, "\n") + "\n")
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	if err := suspend(func() error { return nav.Edit(file.Name()) }); err != nil {
+		screen.Render(requestPreview())
+		return err
+	}
+	data, err := ioutil.ReadFile(file.Name())
+	if err != nil {
+		return err
+	}
+
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	if len(lines) != len(names) {
+		screen.Render(requestPreview())
+		return fmt.Errorf("%d names were given for %d entries, but lines must not be added or removed",
+			len(lines), len(names))
+	}
+	renames := make([]explorer.Rename, len(names))
+	for i, name := range names {
+		// Editors on Windows may end lines with "\r\n".
+		renames[i] = explorer.Rename{Old: name, New: strings.TrimSuffix(lines[i], "\r")}
+	}
+	return applyRenames(renames)
+}
+
+// renameTargets returns the names of the entries which are renamed together: the marked entries of
+// the current directory, or every entry if none are marked.
+func renameTargets() []string {
+	names := markedNames()
+	if len(names) == 0 {
+		for _, name := range screen.Text {
+			if isMarkable(name) {
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
+
+// promptRename begins typing a command which renames the entries returned by renameTargets
+// according to a rule, whose effect is previewed as it is typed.
+func promptRename() {
+	openPrompt(CommandMode)
+	input = []rune("rename ")
+	renderPrompt()
+}
+
+// renameWithRule renames the entries returned by renameTargets according to a rule, as understood
+// by rename.Parse. Each entry is given its position among them for the rule's counter.
+func renameWithRule(command, rule string) error {
+	renames, err := ruleRenames(rule)
+	if err == nil {
+		err = applyRenames(renames)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %v", command, err)
+	}
+	return nil
+}
+
+// ruleRenames returns the renames which a rule makes to the entries returned by renameTargets. The
+// rule is applied to the last element of each entry's name, so that entries listed within expanded
+// directories in tree mode stay where they are.
+func ruleRenames(rule string) ([]explorer.Rename, error) {
+	r, err := rename.Parse(rule)
+	if err != nil {
+		return nil, err
+	}
+	names := renameTargets()
+	if len(names) == 0 {
+		return nil, errors.New("there is nothing to rename")
+	}
+	renames := make([]explorer.Rename, len(names))
+	for i, name := range names {
+		trimmed := strings.TrimSuffix(name, explorer.PathSep)
+		dir := trimmed[:strings.LastIndex(trimmed, explorer.PathSep)+1]
+		isDir := trimmed != name
+		newName := dir + r.Apply(trimmed[len(dir):], i+1, isDir)
+		if isDir {
+			newName += explorer.PathSep
+		}
+		renames[i] = explorer.Rename{Old: name, New: newName}
+	}
+	return renames, nil
+}
+
+// typedRenameRule returns the rule of a rename command which the user is typing into the prompt,
+// and whether they are typing one.
+func typedRenameRule() (string, bool) {
+	if mode != CommandMode {
+		return "", false
+	}
+	name, rule := splitCommand(string(input))
+	return rule, name == "rename" && strings.ContainsAny(string(input), " \t")
+}
+
+// promptPreview returns the preview displayed while the user types into the prompt: a preview of
+// the renames which a rename command makes while one is being typed, or otherwise the preview of
+// the current selected entry.
+func promptPreview() []textrenderer.Line {
+	if rule, ok := typedRenameRule(); ok && !screen.DualPane {
+		discardPreview()
+		screen.PreviewOffset = 0
+		return renamePreview(rule)
+	}
+	return requestPreview()
+}
+
+// renamePreview returns a preview of the renames which a rule makes, listing each entry which it
+// renames beside its new name. New names which would be given to more than one entry, or which
+// belong to an entry which is not being renamed, are highlighted as errors, although only the
+// listed entries are checked until the entries are renamed.
+func renamePreview(rule string) []textrenderer.Line {
+	if strings.TrimSpace(rule) == "" {
+		return textrenderer.PlainLines(renameHelp)
+	}
+	renames, err := ruleRenames(rule)
+	if err != nil {
+		return errorPreview(err)
+	}
+
+	listed := make(map[string]bool)
+	for _, name := range screen.Text {
+		listed[strings.TrimSuffix(name, explorer.PathSep)] = true
+	}
+	renamed := make(map[string]bool)
+	given := make(map[string]int)
+	var changed []explorer.Rename
+	for _, r := range renames {
+		oldName := strings.TrimSuffix(r.Old, explorer.PathSep)
+		newName := strings.TrimSuffix(r.New, explorer.PathSep)
+		if oldName != newName {
+			renamed[oldName] = true
+			given[newName]++
+			changed = append(changed, r)
+		}
+	}
+
+	lines := []textrenderer.Line{
+		{{
+			Text: fmt.Sprintf("Renames %d of %d entries", len(changed), len(renames)),
+			Fg:   termbox.ColorCyan,
+		}},
+		{{Text: strings.Repeat("─", screen.PreviewWidth()), Fg: termbox.ColorDarkGray}},
+	}
+	for _, r := range changed {
+		newName := strings.TrimSuffix(r.New, explorer.PathSep)
+		span := textrenderer.Span{Text: r.New}
+		if given[newName] > 1 || listed[newName] && !renamed[newName] {
+			span.Bg = screen.Theme.Error
+		}
+		lines = append(lines, textrenderer.Line{
+			{Text: r.Old},
+			{Text: " → ", Fg: termbox.ColorDarkGray},
+			span,
+		})
+	}
+	return lines
+}
+
+// renameHelp describes the steps of which a rule is made, and is displayed in place of a preview
+// until a rule has been typed.
+var renameHelp = []string{
+	"Steps, separated by spaces:",
+	"",
+	"s/re/text/gi  replace matches of re",
+	"lower, upper  change the case",
+	"title         capitalise each word",
+	"ext:png       change the extension",
+	"photo_{n:03}  replace the name",
+	"",
+	"{n} counts the entries, {n:03}",
+	"pads the count with zeros, and",
+	"{name} is the name without its",
+	"extension. In text, \\1 and & are",
+	"the groups of the match.",
+}
+
+// applyRenames renames entries of the current directory together, and then lists the directory
+// again with the caret on the entry which was selected, under its new name if it was renamed. The
+// renamed entries are unmarked.
+func applyRenames(renames []explorer.Rename) error {
+	selected := screen.CurrentSelected()
+	var oldNames []string
+	for _, r := range renames {
+		if strings.TrimSuffix(r.Old, explorer.PathSep) == strings.TrimSuffix(r.New, explorer.PathSep) {
+			continue
+		}
+		oldNames = append(oldNames, r.Old)
+		if r.Old == selected {
+			selected = strings.TrimSuffix(r.New, explorer.PathSep)
+			if strings.HasSuffix(r.Old, explorer.PathSep) {
+				selected += explorer.PathSep
+			}
+		}
+	}
+	if len(oldNames) == 0 {
+		screen.Render(requestPreview())
+		showMessage(textrenderer.Info, "Nothing was renamed")
+		return nil
+	}
+
+	err := nav.RenameAll(renames)
+	if err == nil {
+		unmark(oldNames)
+	}
+	if listErr := listDirectory(); listErr != nil {
+		return listErr
+	}
+	selectName(selected)
+	if otherErr := updateOtherPane(); otherErr != nil {
+		return otherErr
+	}
+	if err != nil {
+		return err
+	}
+	message := fmt.Sprintf("Renamed %d entries", len(oldNames))
+	if len(oldNames) == 1 {
+		message = "Renamed 1 entry"
+	}
+	showMessage(textrenderer.Info, message)
+	return nil
+}
@@ -0,0 +1,363 @@
+// Copyright 2019 Max Godfrey
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 		http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package rename parses rules which rename many files at once, such as "s/IMG_/photo_/ lower", and
+// applies them to the names of files.
+//
+// A rule is a number of steps separated by spaces, which are applied to a name in turn:
+//
+//	s/pattern/replacement/flags  replaces the first match of a regular expression, or every match
+//	                             with the flag g, ignoring case with the flag i. Any of |#,:@!%;~
+//	                             may be used in place of '/'. In the replacement, \1 to \9 are
+//	                             replaced by the groups of the match, and & by the whole match.
+//	lower, upper                 converts the whole name to lower or upper case.
+//	title                        capitalises each word of the name and converts the rest of it to
+//	                             lower case, leaving its extension as it is.
+//	ext:png                      changes the extension of a file, or removes it if none is given.
+//	photo_{n:03}                 any other step is a template which replaces the name, leaving its
+//	                             extension as it is.
+//
+// Both replacements and templates may contain the placeholders {n}, which is replaced by the
+// position of the file among those being renamed counting from 1, {n:03}, which pads the position
+// with zeros to a width of 3, and {name}, which is replaced by the name without its extension.
+// {{ and }} stand for literal braces.
+package rename
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// delimiters are the characters which may separate the parts of a substitution.
+const delimiters = "/|#,:@!%;~"
+
+// Rule renames files according to a number of steps.
+type Rule struct {
+	steps []step
+}
+
+// step is a single change which a rule makes to a name.
+type step interface {
+	apply(name string, n int, isDir bool) string
+}
+
+// Parse parses a rule.
+func Parse(rule string) (*Rule, error) {
+	r := &Rule{}
+	rest := strings.TrimSpace(rule)
+	for ; rest != ""; rest = strings.TrimLeftFunc(rest, unicode.IsSpace) {
+		var s step
+		var err error
+		if len(rest) > 1 && rest[0] == 's' && strings.IndexByte(delimiters, rest[1]) >= 0 {
+			s, rest, err = parseSubstitution(rest)
+		} else {
+			word := rest
+			if i := strings.IndexFunc(rest, unicode.IsSpace); i >= 0 {
+				word, rest = rest[:i], rest[i:]
+			} else {
+				rest = ""
+			}
+			s, err = parseWord(word)
+		}
+		if err != nil {
+			return nil, err
+		}
+		r.steps = append(r.steps, s)
+	}
+	if len(r.steps) == 0 {
+		return nil, errors.New("the rule is empty")
+	}
+	return r, nil
+}
+
+// Apply returns the name which a rule gives to a file, given its name, its position among the files
+// being renamed counting from 1, and whether it is a directory.
+func (r *Rule) Apply(name string, n int, isDir bool) string {
+	for _, s := range r.steps {
+		name = s.apply(name, n, isDir)
+	}
+	return name
+}
+
+// splitExt splits a name into its base and its extension, which begins with the last '.' of the
+// name. Directories, and names whose only '.' begins them, have no extension.
+func splitExt(name string, isDir bool) (string, string) {
+	if i := strings.LastIndexByte(name, '.'); i > 0 && !isDir {
+		return name[:i], name[i:]
+	}
+	return name, ""
+}
+
+// parseWord parses a step which is a single word: a case conversion, a change of extension, or a
+// template.
+func parseWord(word string) (step, error) {
+	switch {
+	case word == "lower" || word == "upper" || word == "title":
+		return caseStep(word), nil
+	case strings.HasPrefix(word, "ext:"):
+		ext := strings.TrimPrefix(strings.TrimPrefix(word, "ext:"), ".")
+		if strings.ContainsAny(ext, "/\\") {
+			return nil, fmt.Errorf("%s: the extension must not contain a separator", word)
+		}
+		return extStep(ext), nil
+	}
+	parts, err := parseTemplate(word, false)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", word, err)
+	}
+	return templateStep(parts), nil
+}
+
+// parseSubstitution parses a substitution at the beginning of a rule, returning it along with the
+// rest of the rule.
+func parseSubstitution(rule string) (step, string, error) {
+	delim := rule[1]
+	fields := make([]string, 0, 2)
+	var field strings.Builder
+	i := 2
+	for ; i < len(rule) && len(fields) < 2; i++ {
+		switch {
+		case rule[i] == '\\' && i+1 < len(rule) && rule[i+1] == delim:
+			field.WriteByte(delim)
+			i++
+		case rule[i] == delim:
+			fields = append(fields, field.String())
+			field.Reset()
+		case rule[i] == '\\' && i+1 < len(rule):
+			field.WriteString(rule[i : i+2])
+			i++
+		default:
+			field.WriteByte(rule[i])
+		}
+	}
+	if len(fields) < 2 {
+		return nil, "", fmt.Errorf("%s: the substitution is missing a closing %q", rule, delim)
+	}
+
+	end := i
+	for end < len(rule) && !unicode.IsSpace(rune(rule[end])) {
+		end++
+	}
+	flags := rule[i:end]
+	sub := &substitution{}
+	expr := fields[0]
+	for _, flag := range flags {
+		switch flag {
+		case 'g':
+			sub.global = true
+		case 'i':
+			expr = "(?i)" + expr
+		default:
+			return nil, "", fmt.Errorf("%s: unknown flag %q, the flags are g and i", rule[:end], flag)
+		}
+	}
+	var err error
+	if sub.re, err = regexp.Compile(expr); err != nil {
+		return nil, "", fmt.Errorf("%s: %v", rule[:end], err)
+	}
+	if sub.replacement, err = parseTemplate(fields[1], true); err != nil {
+		return nil, "", fmt.Errorf("%s: %v", rule[:end], err)
+	}
+	for _, p := range sub.replacement {
+		if p.kind == groupPart && p.value > sub.re.NumSubexp() {
+			return nil, "", fmt.Errorf("%s: there is no group %d", rule[:end], p.value)
+		}
+	}
+	return sub, rule[end:], nil
+}
+
+// The kinds of part of which replacements and templates are made.
+const (
+	literalPart = iota // Text which is inserted as it is.
+	groupPart          // A group of the match of a regular expression.
+	counterPart        // The position of the file among those being renamed.
+	namePart           // The name of the file without its extension.
+)
+
+// part is a part of a replacement or template.
+type part struct {
+	kind  int
+	text  string // The text of a literal part.
+	value int    // The number of a group, or the width to which a counter is padded.
+}
+
+// parseTemplate parses a template, or the replacement of a substitution if groups is set, in which
+// case it may refer to the groups of the match.
+func parseTemplate(template string, groups bool) ([]part, error) {
+	var parts []part
+	var literal strings.Builder
+	flush := func() {
+		if literal.Len() > 0 {
+			parts = append(parts, part{kind: literalPart, text: literal.String()})
+			literal.Reset()
+		}
+	}
+	for i := 0; i < len(template); i++ {
+		c := template[i]
+		switch {
+		case groups && c == '\\' && i+1 < len(template):
+			i++
+			if d := template[i]; d >= '0' && d <= '9' {
+				flush()
+				parts = append(parts, part{kind: groupPart, value: int(d - '0')})
+			} else {
+				literal.WriteByte(d)
+			}
+		case groups && c == '&':
+			flush()
+			parts = append(parts, part{kind: groupPart})
+		case c == '{' && strings.HasPrefix(template[i:], "{{"),
+			c == '}' && strings.HasPrefix(template[i:], "}}"):
+			literal.WriteByte(c)
+			i++
+		case c == '{':
+			end := strings.IndexByte(template[i:], '}')
+			if end < 0 {
+				return nil, errors.New("a placeholder is missing a closing '}'")
+			}
+			p, err := parsePlaceholder(template[i+1 : i+end])
+			if err != nil {
+				return nil, err
+			}
+			flush()
+			parts = append(parts, p)
+			i += end
+		default:
+			literal.WriteByte(c)
+		}
+	}
+	flush()
+	return parts, nil
+}
+
+// parsePlaceholder parses the placeholder between a pair of braces.
+func parsePlaceholder(placeholder string) (part, error) {
+	switch {
+	case placeholder == "n":
+		return part{kind: counterPart}, nil
+	case placeholder == "name":
+		return part{kind: namePart}, nil
+	case strings.HasPrefix(placeholder, "n:0"):
+		width, err := strconv.Atoi(placeholder[3:])
+		if err == nil && width > 0 && width <= 20 {
+			return part{kind: counterPart, value: width}, nil
+		}
+	}
+	return part{}, fmt.Errorf("unknown placeholder {%s}, the placeholders are {n}, {n:03} and {name}",
+		placeholder)
+}
+
+// expand returns the text of a replacement or template, given the name of the file which is being
+// renamed, the groups of a match within it as returned by FindStringSubmatchIndex, and the file's
+// position among those being renamed.
+func expand(parts []part, name string, match []int, n int, isDir bool) string {
+	var b strings.Builder
+	for _, p := range parts {
+		switch p.kind {
+		case literalPart:
+			b.WriteString(p.text)
+		case groupPart:
+			if start := match[2*p.value]; start >= 0 {
+				b.WriteString(name[start:match[2*p.value+1]])
+			}
+		case counterPart:
+			fmt.Fprintf(&b, "%0*d", p.value, n)
+		case namePart:
+			base, _ := splitExt(name, isDir)
+			b.WriteString(base)
+		}
+	}
+	return b.String()
+}
+
+// substitution replaces matches of a regular expression within a name.
+type substitution struct {
+	re          *regexp.Regexp
+	replacement []part
+	global      bool
+}
+
+func (s *substitution) apply(name string, n int, isDir bool) string {
+	limit := 1
+	if s.global {
+		limit = -1
+	}
+	var b strings.Builder
+	last := 0
+	for _, match := range s.re.FindAllStringSubmatchIndex(name, limit) {
+		b.WriteString(name[last:match[0]])
+		b.WriteString(expand(s.replacement, name, match, n, isDir))
+		last = match[1]
+	}
+	b.WriteString(name[last:])
+	return b.String()
+}
+
+// caseStep converts the case of a name.
+type caseStep string
+
+func (c caseStep) apply(name string, n int, isDir bool) string {
+	switch c {
+	case "lower":
+		return strings.ToLower(name)
+	case "upper":
+		return strings.ToUpper(name)
+	}
+	base, ext := splitExt(name, isDir)
+	return titleCase(base) + ext
+}
+
+// titleCase capitalises the first letter of each word of a name and converts the rest of the word
+// to lower case, where words are separated by any character which is not a letter or digit.
+func titleCase(name string) string {
+	var b strings.Builder
+	inWord := false
+	for _, r := range name {
+		isWordChar := unicode.IsLetter(r) || unicode.IsDigit(r)
+		if isWordChar && !inWord {
+			r = unicode.ToUpper(r)
+		} else {
+			r = unicode.ToLower(r)
+		}
+		inWord = isWordChar
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// extStep changes the extension of a file's name.
+type extStep string
+
+func (e extStep) apply(name string, n int, isDir bool) string {
+	if isDir {
+		return name
+	}
+	base, _ := splitExt(name, isDir)
+	if e == "" {
+		return base
+	}
+	return base + "." + string(e)
+}
+
+// templateStep replaces a name, other than its extension, with a template.
+type templateStep []part
+
+func (t templateStep) apply(name string, n int, isDir bool) string {
+	_, ext := splitExt(name, isDir)
+	return expand(t, name, nil, n, isDir) + ext
+}
@@ -0,0 +1,93 @@
+// Copyright 2019 Max Godfrey
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 		http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rename
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestApply(t *testing.T) {
+	tests := []struct {
+		rule  string
+		name  string
+		n     int
+		isDir bool
+		want  string
+	}{
+		{"s/IMG_/photo_/", "IMG_0042.JPG", 1, false, "photo_0042.JPG"},
+		{"s/a/b/", "banana", 1, false, "bbnana"},
+		{"s/a/b/g", "banana", 1, false, "bbnbnb"},
+		{"s/A/b/gi", "bAnana", 1, false, "bbnbnb"},
+		{`s/(\w+)-(\w+)/\2-\1/`, "left-right.txt", 1, false, "right-left.txt"},
+		{"s/an/[&]/g", "banana", 1, false, "b[an][an]a"},
+		{"s|/|_|", "a", 1, false, "a"},
+		{`s/ /\//`, "a b", 1, false, "a/b"},
+		{"s/ /_/g", "a b c", 1, false, "a_b_c"},
+		{"s/^/{n:03}_/", "song.mp3", 7, false, "007_song.mp3"},
+		{"s/x/y/", "unmatched", 1, false, "unmatched"},
+		{"photo_{n:03}", "IMG_0042.JPG", 12, false, "photo_012.JPG"},
+		{"photo_{n}", "album", 3, true, "photo_3"},
+		{"{name}-copy", "report.final.pdf", 1, false, "report.final-copy.pdf"},
+		{"{{{n}}}", "x.txt", 2, false, "{2}.txt"},
+		{"lower", "IMG_0042.JPG", 1, false, "img_0042.jpg"},
+		{"upper", "notes.txt", 1, false, "NOTES.TXT"},
+		{"title", "my FAVOURITE song.MP3", 1, false, "My Favourite Song.MP3"},
+		{"ext:png", "image.jpeg", 1, false, "image.png"},
+		{"ext:.png", "image", 1, false, "image.png"},
+		{"ext:", "archive.tar.gz", 1, false, "archive.tar"},
+		{"ext:png", "photos.old", 1, true, "photos.old"},
+		{"ext:txt", ".bashrc", 1, false, ".bashrc.txt"},
+		{"s/IMG_//  lower   ext:jpg", "IMG_0042.JPEG", 1, false, "0042.jpg"},
+		{"photo_{n:02} lower", "IMG.JPG", 5, false, "photo_05.jpg"},
+	}
+	for _, test := range tests {
+		rule, err := Parse(test.rule)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", test.rule, err)
+			continue
+		}
+		if got := rule.Apply(test.name, test.n, test.isDir); got != test.want {
+			t.Errorf("Parse(%q).Apply(%q, %d, %t) = %q, want %q", test.rule, test.name, test.n,
+				test.isDir, got, test.want)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		rule string
+		want string
+	}{
+		{"", "empty"},
+		{"   ", "empty"},
+		{"s/a/b", "missing a closing"},
+		{"s/a/b/x", "unknown flag"},
+		{"s/(/b/", "missing closing )"},
+		{`s/a/\1/`, "no group 1"},
+		{"photo_{n", "missing a closing '}'"},
+		{"photo_{count}", "unknown placeholder {count}"},
+		{"photo_{n:3}", "unknown placeholder {n:3}"},
+		{"ext:a/b", "separator"},
+	}
+	for _, test := range tests {
+		_, err := Parse(test.rule)
+		if err == nil {
+			t.Errorf("Parse(%q) did not return an error", test.rule)
+		} else if !strings.Contains(err.Error(), test.want) {
+			t.Errorf("Parse(%q) returned %q, want it to mention %q", test.rule, err, test.want)
+		}
+	}
+}
@@ -0,0 +1,167 @@
+// Copyright 2019 Max Godfrey
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 		http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/maxgodfrey2004/go-file-manager/fileserver"
+	"github.com/maxgodfrey2004/go-file-manager/textrenderer"
+)
+
+// Timeouts of the server, so that clients which are slow or idle cannot hold its connections open
+// forever. Downloads are not limited, as large files may take a long time to send.
+const (
+	serverReadHeaderTimeout = 10 * time.Second
+	serverReadTimeout       = 10 * time.Minute
+	serverIdleTimeout       = 2 * time.Minute
+)
+
+var (
+	// server shares a directory over HTTP, or is nil if nothing is being shared.
+	server *http.Server
+
+	// serverDescription describes the directory being shared and the URL at which it is shared.
+	serverDescription string
+
+	// serverLog receives a line describing each request which the server has answered. Lines
+	// which arrive while the explorer is busy are discarded rather than delaying the server.
+	serverLog = make(chan string, 16)
+)
+
+// serveCommand runs the serve command. With no argument, it shares the current directory over
+// HTTP at the configured address, or describes the directory being shared if there is one. An
+// argument of "stop" stops sharing the directory, and any other argument is the address at which
+// to share it.
+func serveCommand(command, arg string) error {
+	switch {
+	case arg == "stop":
+		if server == nil {
+			return fmt.Errorf("%s: nothing is being shared", command)
+		}
+		err := server.Close()
+		server = nil
+		showMessage(textrenderer.Info, "Stopped "+serverDescription)
+		return err
+	case server != nil:
+		if arg != "" {
+			return fmt.Errorf("%s: already %s", command, serverDescription)
+		}
+		showMessage(textrenderer.Info, serverDescription)
+		return nil
+	case arg == "":
+		arg = userConfig.Server.Address
+	}
+	if err := startServer(arg); err != nil {
+		return fmt.Errorf("%s %s: %v", command, arg, err)
+	}
+	showMessage(textrenderer.Info, serverDescription)
+	return nil
+}
+
+// startServer begins sharing the current directory over HTTP at an address, in the background.
+// Hidden files are only shared if they are listed.
+func startServer(address string) error {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return err
+	}
+	handler := fileserver.New(nav.FS, nav.Dir(), fileserver.Options{
+		Username: userConfig.Server.Username,
+		Password: userConfig.Server.Password,
+		Upload:   userConfig.Server.Upload,
+		Hidden:   listAll,
+		Log:      sendServerLog,
+	})
+	server = &http.Server{
+		Handler:           handler,
+		ReadHeaderTimeout: serverReadHeaderTimeout,
+		ReadTimeout:       serverReadTimeout,
+		IdleTimeout:       serverIdleTimeout,
+		ErrorLog:          log.New(serverErrorLog{}, "", 0),
+	}
+	serverDescription = "Sharing " + nav.Location() + " at " + serverURL(listener.Addr())
+	go func() {
+		defer recoverPanic()
+		server.Serve(listener)
+	}()
+	return nil
+}
+
+// sendServerLog sends a line describing a request which the server has answered to serverLog,
+// discarding it if the explorer is busy.
+func sendServerLog(line string) {
+	select {
+	case serverLog <- line:
+	default:
+	}
+}
+
+// serverErrorLog sends the first line of each error which the server logs, such as a panic while
+// answering a request, to serverLog rather than writing it over the screen.
+type serverErrorLog struct{}
+
+func (serverErrorLog) Write(p []byte) (int, error) {
+	line := strings.SplitN(strings.TrimSpace(string(p)), "\n", 2)[0]
+	sendServerLog(line)
+	return len(p), nil
+}
+
+// serverURL returns the URL at which others may reach a server listening at an address. If it
+// listens on every interface, the URL uses the address of the first interface which is not a
+// loopback interface, as others on the local network would reach it.
+func serverURL(addr net.Addr) string {
+	tcpAddr, ok := addr.(*net.TCPAddr)
+	if !ok {
+		return "http://" + addr.String() + "/"
+	}
+	host := tcpAddr.IP.String()
+	if tcpAddr.IP.IsUnspecified() {
+		host = "localhost"
+		if ip, err := localIP(); err == nil {
+			host = ip.String()
+		}
+	}
+	return "http://" + net.JoinHostPort(host, strconv.Itoa(tcpAddr.Port)) + "/"
+}
+
+// localIP returns the first IPv4 address of this machine which is not a loopback address.
+func localIP() (net.IP, error) {
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return nil, err
+	}
+	for _, addr := range addrs {
+		if ipNet, ok := addr.(*net.IPNet); ok && !ipNet.IP.IsLoopback() && ipNet.IP.To4() != nil {
+			return ipNet.IP, nil
+		}
+	}
+	return nil, errors.New("no network address was found")
+}
+
+// logRequest displays a line describing a request which the server has answered on the status
+// line, unless the user is typing into a prompt which it would hide.
+func logRequest(line string) {
+	if mode == NormalMode {
+		showMessage(textrenderer.Info, line)
+	}
+}
@@ -0,0 +1,87 @@
+// Copyright 2019 Max Godfrey
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 		http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"runtime/debug"
+
+	"github.com/maxgodfrey2004/go-file-manager/textrenderer"
+	"github.com/nsf/termbox-go"
+)
+
+// errNothingSelected is returned by commands which act upon the current selected entry when the
+// current directory lists nothing.
+var errNothingSelected = errors.New("nothing is selected")
+
+// panics receives a description of each panic which occurs in a goroutine other than the one
+// running the main loop.
+var panics = make(chan string)
+
+// showMessage displays a message on the status line at the bottom of the screen until the next key
+// is pressed.
+func showMessage(severity textrenderer.Severity, message string) {
+	screen.Message = message
+	screen.Severity = severity
+	screen.RenderMessage()
+}
+
+// report displays an error on the status line, from which the application recovers.
+func report(err error) {
+	showMessage(textrenderer.Error, err.Error())
+}
+
+// clearMessage removes any message displayed on the status line.
+func clearMessage() {
+	if screen.Message != "" {
+		screen.Message = ""
+		screen.Render(requestPreview())
+	}
+}
+
+// fatal restores the terminal and exits, describing an error from which the application cannot
+// recover.
+func fatal(err error) {
+	termbox.Close()
+	fmt.Fprintln(os.Stderr, "go-file-manager:", err)
+	os.Exit(1)
+}
+
+// recoverFatal recovers from a panic, restoring the terminal before describing the panic and
+// exiting, so that the terminal is not left in an unusable state. It must be deferred.
+func recoverFatal() {
+	if r := recover(); r != nil {
+		exitPanic(fmt.Sprintf("%v\n%s", r, debug.Stack()))
+	}
+}
+
+// recoverPanic recovers from a panic in a goroutine other than the one running the main loop,
+// sending a description of it to panics so that the main loop exits as recoverFatal does. It must
+// be deferred.
+func recoverPanic() {
+	if r := recover(); r != nil {
+		panics <- fmt.Sprintf("%v\n%s", r, debug.Stack())
+	}
+}
+
+// exitPanic restores the terminal and exits, describing a panic along with the stack trace of the
+// goroutine in which it occurred.
+func exitPanic(description string) {
+	termbox.Close()
+	fmt.Fprintf(os.Stderr, "go-file-manager: %s", description)
+	os.Exit(2)
+}
@@ -0,0 +1,121 @@
+// Copyright 2019 Max Godfrey
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 		http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"errors"
+	"path/filepath"
+
+	"github.com/maxgodfrey2004/go-file-manager/explorer"
+)
+
+// tab holds the state of a tab which is not being displayed, so that it may be restored when the
+// user switches back to it.
+type tab struct {
+	pane            // The pane which had focus.
+	other      pane // The pane without focus, in dual-pane mode.
+	activePane int  // The side of the screen on which the pane with focus was rendered.
+}
+
+var (
+	// tabs holds the state of every tab. The state of the current tab is only saved here when the
+	// user switches away from it, as until then it is held by nav, listAll and screen.
+	tabs = []tab{{}}
+
+	// currentTab is the index in tabs of the tab being displayed.
+	currentTab int
+)
+
+// saveTab saves the state of the current tab.
+func saveTab() {
+	tabs[currentTab] = tab{pane: currentPane(), other: otherPane, activePane: screen.ActivePane}
+}
+
+// loadTab displays the tab at an index in tabs, restoring its state.
+func loadTab(index int) {
+	currentTab = index
+	t := tabs[index]
+	otherPane = t.other
+	screen.ActivePane = t.activePane
+	err := showPane(t.pane)
+	if otherErr := updateOtherPane(); err == nil {
+		err = otherErr
+	}
+	updateTabLabels()
+	screen.Render(requestPreview())
+	if err != nil {
+		report(err)
+	}
+}
+
+// updateTabLabels labels each tab on the screen with the name of its directory.
+func updateTabLabels() {
+	label := func(e explorer.Explorer) string {
+		if e.Path == "" {
+			return explorer.PathSep
+		}
+		return filepath.Base(e.Path)
+	}
+	screen.Tabs = make([]string, len(tabs))
+	for i, t := range tabs {
+		screen.Tabs[i] = label(t.nav)
+	}
+	screen.Tabs[currentTab] = label(nav)
+	screen.CurrentTab = currentTab
+}
+
+// newTab opens a new tab after the current one, in the same directory.
+func newTab() {
+	saveTab()
+	tabs = append(tabs, tab{})
+	copy(tabs[currentTab+2:], tabs[currentTab+1:])
+	t := tabs[currentTab]
+	t.pane, t.other = t.pane.clone(), t.other.clone()
+	tabs[currentTab+1] = t
+	loadTab(currentTab + 1)
+}
+
+// closeTab closes the current tab, displaying the one before it. The last tab cannot be closed.
+func closeTab() error {
+	if len(tabs) == 1 {
+		return errors.New("cannot close the last tab")
+	}
+	tabs = append(tabs[:currentTab], tabs[currentTab+1:]...)
+	if currentTab > 0 {
+		currentTab--
+	}
+	loadTab(currentTab)
+	return nil
+}
+
+// switchTab displays the tab given by the count typed before the key, counting from 1, or
+// otherwise the next or previous tab, wrapping around at either end.
+func switchTab(ev keypress) {
+	index := currentTab + ev.Direction
+	if ev.Count > 0 {
+		index = ev.Count - 1
+	}
+	if index < 0 || index >= len(tabs) {
+		if ev.Count > 0 {
+			return
+		}
+		index = (index + len(tabs)) % len(tabs)
+	}
+	if index == currentTab {
+		return
+	}
+	saveTab()
+	loadTab(index)
+}
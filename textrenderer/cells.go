@@ -0,0 +1,191 @@
+// Copyright 2019 Max Godfrey
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 		http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package textrenderer
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/mattn/go-runewidth"
+	"github.com/nsf/termbox-go"
+)
+
+// TabWidth is the number of columns between each tab stop when tabs are expanded.
+const TabWidth = 8
+
+// glyph is a single rune which is to be drawn on the terminal, along with the number of columns
+// it occupies.
+type glyph struct {
+	ch    rune
+	width int
+}
+
+// glyphs converts a string into the glyphs which represent it on the terminal, given the column
+// at which the string starts. Tabs are expanded to the next tab stop, control characters are drawn
+// in caret notation (eg. ^[ for escape), and invalid UTF-8 is replaced with U+FFFD. Zero width
+// runes such as combining marks occupy no columns of their own. As a termbox cell may only hold a
+// single rune, a combining mark is composed with the rune before it where possible, and is
+// otherwise left undrawn.
+func glyphs(s string, col int) []glyph {
+	var result []glyph
+	add := func(ch rune, width int) {
+		result = append(result, glyph{ch: ch, width: width})
+		col += width
+	}
+
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		i += size
+
+		switch {
+		case r == utf8.RuneError && size <= 1:
+			add(utf8.RuneError, 1)
+		case r == '\t':
+			for n := TabWidth - col%TabWidth; n > 0; n-- {
+				add(' ', 1)
+			}
+		case r < 0x20 || r == 0x7f:
+			add('^', 1)
+			add(r^0x40, 1)
+		case r >= 0x80 && r < 0xa0:
+			add(utf8.RuneError, 1)
+		default:
+			if w := runewidth.RuneWidth(r); w > 0 {
+				add(r, w)
+			} else if len(result) > 0 {
+				last := &result[len(result)-1]
+				last.ch = compose(last.ch, r)
+			}
+		}
+	}
+	return result
+}
+
+// compositions holds, for each combining mark, pairs of a letter followed by the same letter
+// precomposed with the mark, for the letters of Latin-1 and Latin Extended-A. Some file systems,
+// such as those of macOS, store names with their marks decomposed.
+var compositions = map[rune]string{
+	// Grave accent
+	0x0300: "AÀEÈIÌOÒUÙaàeèiìoòuù",
+	// Acute accent
+	0x0301: "AÁEÉIÍOÓUÚYÝaáeéiíoóuúyýCĆcćLĹlĺNŃnńRŔrŕSŚsśZŹzź",
+	// Circumflex accent
+	0x0302: "AÂEÊIÎOÔUÛaâeêiîoôuûCĈcĉGĜgĝHĤhĥJĴjĵSŜsŝWŴwŵYŶyŷ",
+	// Tilde
+	0x0303: "AÃNÑOÕaãnñoõIĨiĩUŨuũ",
+	// Macron
+	0x0304: "AĀaāEĒeēIĪiīOŌoōUŪuū",
+	// Breve
+	0x0306: "AĂaăEĔeĕGĞgğIĬiĭOŎoŏUŬuŭ",
+	// Dot above
+	0x0307: "CĊcċEĖeėGĠgġIİZŻzż",
+	// Diaeresis
+	0x0308: "AÄEËIÏOÖUÜaäeëiïoöuüyÿYŸ",
+	// Ring above
+	0x030a: "AÅaåUŮuů",
+	// Double acute accent
+	0x030b: "OŐoőUŰuű",
+	// Caron
+	0x030c: "CČcčDĎdďEĚeěLĽlľNŇnňRŘrřSŠsšTŤtťZŽzž",
+	// Cedilla
+	0x0327: "CÇcçGĢgģKĶkķLĻlļNŅnņRŖrŗSŞsşTŢtţ",
+	// Ogonek
+	0x0328: "AĄaąEĘeęIĮiįUŲuų",
+}
+
+// compose returns the rune which a letter followed by a combining mark is composed into, or the
+// letter itself if there is no such rune.
+func compose(letter, mark rune) rune {
+	pairs := []rune(compositions[mark])
+	for i := 0; i+1 < len(pairs); i += 2 {
+		if pairs[i] == letter {
+			return pairs[i+1]
+		}
+	}
+	return letter
+}
+
+// StringWidth returns the number of terminal columns which a string occupies once rendered.
+func StringWidth(s string) int {
+	width := 0
+	for _, g := range glyphs(s, 0) {
+		width += g.width
+	}
+	return width
+}
+
+// Span is a run of text which is drawn with a single pair of colours.
+type Span struct {
+	Text string
+	Fg   termbox.Attribute
+	Bg   termbox.Attribute
+}
+
+// Line is a single line of styled text, made up of consecutive spans.
+type Line []Span
+
+// String returns the text of a line, without any styling.
+func (l Line) String() string {
+	var b strings.Builder
+	for _, span := range l {
+		b.WriteString(span.Text)
+	}
+	return b.String()
+}
+
+// Width returns the number of terminal columns which a line occupies once rendered.
+func (l Line) Width() int {
+	return StringWidth(l.String())
+}
+
+// PlainLine returns a line consisting of a single span drawn with the default colours.
+func PlainLine(s string) Line {
+	return Line{{Text: s}}
+}
+
+// PlainLines converts lines of unstyled text into lines drawn with the default colours.
+func PlainLines(lines []string) []Line {
+	if lines == nil {
+		return nil
+	}
+	result := make([]Line, len(lines))
+	for i, line := range lines {
+		result[i] = PlainLine(line)
+	}
+	return result
+}
+
+// drawString draws a string on the terminal starting at the cell (x, y). At most maxWidth columns
+// are drawn; a wide character which does not fit entirely within them is not drawn at all. The
+// number of columns drawn is returned.
+func drawString(x, y, maxWidth int, s string, fgColor, bgColor termbox.Attribute) int {
+	return drawLine(x, y, maxWidth, Line{{Text: s, Fg: fgColor, Bg: bgColor}})
+}
+
+// drawLine draws each span of a line on the terminal starting at the cell (x, y), in the same
+// manner as drawString.
+func drawLine(x, y, maxWidth int, line Line) int {
+	col := 0
+	for _, span := range line {
+		for _, g := range glyphs(span.Text, col) {
+			if col+g.width > maxWidth {
+				return col
+			}
+			setCell(x+col, y, g.ch, span.Fg, span.Bg)
+			col += g.width
+		}
+	}
+	return col
+}
@@ -0,0 +1,79 @@
+// Copyright 2019 Max Godfrey
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 		http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package textrenderer
+
+import (
+	"testing"
+)
+
+func TestStringWidth(t *testing.T) {
+	tests := []struct {
+		s     string
+		width int
+	}{
+		{"hello", 5},
+		{"héllo", 5},
+		{"e\u0301", 1},     // e followed by a combining acute accent
+		{"日本語", 6},         // wide characters
+		{"a\tb", 9},        // tab expanded to the next tab stop
+		{"\tb", 9},         // tab at the start of the string
+		{"abcdefgh\t", 16}, // tab exactly on a tab stop
+		{"\x1b[0m", 5},     // escape drawn as ^[
+		{"\xff", 1},        // invalid UTF-8
+	}
+	for _, test := range tests {
+		if width := StringWidth(test.s); width != test.width {
+			t.Errorf("StringWidth(%q) = %d, want %d", test.s, width, test.width)
+		}
+	}
+}
+
+func TestGlyphs(t *testing.T) {
+	tests := []struct {
+		s    string
+		col  int
+		want string
+	}{
+		{"plain", 0, "plain"},
+		{"bell\a", 0, "bell^G"},
+		{"del\x7f", 0, "del^?"},
+		{"e\u0301", 0, "é"},         // composed with a combining acute accent
+		{"Cafe\u0301s", 0, "Cafés"}, // composed within a word
+		{"q\u0301", 0, "q"},         // left undrawn without a precomposed rune
+		{"\u0301a", 0, "a"},         // left undrawn without a rune before it
+		{"\u0085", 0, "�"},
+		{"x\ty", 0, "x       y"},
+		{"x\ty", 3, "x    y"}, // tab stops are relative to the start of the line
+	}
+	for _, test := range tests {
+		var got []rune
+		for _, g := range glyphs(test.s, test.col) {
+			got = append(got, g.ch)
+		}
+		if string(got) != test.want {
+			t.Errorf("glyphs(%q, %d) = %q, want %q", test.s, test.col, string(got), test.want)
+		}
+	}
+}
+
+func TestLineWidth(t *testing.T) {
+	line := Line{{Text: "abc"}, {Text: "\tdef"}}
+	if width := line.Width(); width != 11 {
+		t.Errorf("Width() = %d, want 11", width)
+	}
+	if s := line.String(); s != "abc\tdef" {
+		t.Errorf("String() = %q, want %q", s, "abc\tdef")
+	}
+}
@@ -0,0 +1,217 @@
+// Copyright 2019 Max Godfrey
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 		http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package textrenderer
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/nsf/termbox-go"
+)
+
+// outputMode is the termbox output mode selected by InitColors.
+var outputMode = termbox.OutputNormal
+
+// namedColors holds the RGB values of the 16 colours which termbox names, indexed from
+// termbox.ColorBlack. They are used to draw named colours when the terminal is in truecolour mode.
+var namedColors = [16][3]uint8{
+	{0, 0, 0}, {205, 0, 0}, {0, 205, 0}, {205, 205, 0},
+	{0, 0, 238}, {205, 0, 205}, {0, 205, 205}, {229, 229, 229},
+	{127, 127, 127}, {255, 0, 0}, {0, 255, 0}, {255, 255, 0},
+	{92, 92, 255}, {255, 0, 255}, {0, 255, 255}, {255, 255, 255},
+}
+
+// colorNames maps the names of colours which may be parsed by ParseColor to termbox attributes.
+var colorNames = map[string]termbox.Attribute{
+	"default":       termbox.ColorDefault,
+	"black":         termbox.ColorBlack,
+	"red":           termbox.ColorRed,
+	"green":         termbox.ColorGreen,
+	"yellow":        termbox.ColorYellow,
+	"blue":          termbox.ColorBlue,
+	"magenta":       termbox.ColorMagenta,
+	"cyan":          termbox.ColorCyan,
+	"white":         termbox.ColorWhite,
+	"dark-gray":     termbox.ColorDarkGray,
+	"light-red":     termbox.ColorLightRed,
+	"light-green":   termbox.ColorLightGreen,
+	"light-yellow":  termbox.ColorLightYellow,
+	"light-blue":    termbox.ColorLightBlue,
+	"light-magenta": termbox.ColorLightMagenta,
+	"light-cyan":    termbox.ColorLightCyan,
+	"light-gray":    termbox.ColorLightGray,
+}
+
+// attributeNames maps the names of attributes which may be parsed by ParseColor to termbox
+// attributes.
+var attributeNames = map[string]termbox.Attribute{
+	"bold":      termbox.AttrBold,
+	"underline": termbox.AttrUnderline,
+	"reverse":   termbox.AttrReverse,
+	"dim":       termbox.AttrDim,
+	"italic":    termbox.AttrCursive,
+	"blink":     termbox.AttrBlink,
+}
+
+// cubeLevels are the intensities of each channel in the 6x6x6 colour cube of a 256 colour
+// terminal.
+var cubeLevels = [6]int{0, 95, 135, 175, 215, 255}
+
+// InitColors selects the richest output mode which the terminal advertises support for: truecolour
+// if $COLORTERM says so, 256 colours if $TERM does, and the 8 standard colours otherwise. It must
+// be called after termbox.Init.
+func InitColors() {
+	colorTerm := os.Getenv("COLORTERM")
+	switch {
+	case colorTerm == "truecolor" || colorTerm == "24bit":
+		outputMode = termbox.OutputRGB
+	case strings.Contains(os.Getenv("TERM"), "256color"):
+		outputMode = termbox.Output256
+	default:
+		outputMode = termbox.OutputNormal
+	}
+	termbox.SetOutputMode(outputMode)
+}
+
+// RGB returns the attribute which most closely represents a colour in the current output mode.
+func RGB(r, g, b uint8) termbox.Attribute {
+	switch outputMode {
+	case termbox.OutputRGB:
+		return termbox.RGBToAttribute(r, g, b)
+	case termbox.Output256:
+		return termbox.Attribute(nearest256(int(r), int(g), int(b)) + 1)
+	default:
+		color := termbox.ColorBlack
+		if r > 127 {
+			color++
+		}
+		if g > 127 {
+			color += 2
+		}
+		if b > 127 {
+			color += 4
+		}
+		return color
+	}
+}
+
+// Color256 returns the attribute which most closely represents a colour of a 256 colour palette in
+// the current output mode. The first 16 colours are termbox's named colours.
+func Color256(n uint8) termbox.Attribute {
+	switch {
+	case n < 16:
+		return termbox.ColorBlack + termbox.Attribute(n)
+	case outputMode == termbox.Output256:
+		return termbox.Attribute(n) + 1
+	case n >= 232:
+		grey := uint8(8 + 10*(int(n)-232))
+		return RGB(grey, grey, grey)
+	default:
+		n -= 16
+		return RGB(uint8(cubeLevels[n/36]), uint8(cubeLevels[n/6%6]), uint8(cubeLevels[n%6]))
+	}
+}
+
+// ParseColor parses a description of a colour, such as "blue", "bold light-red" or "#ff8800",
+// into a termbox attribute. A description consists of at most one colour, which is either a named
+// colour or a hexadecimal RGB triplet, along with any number of attributes. Hexadecimal colours are
+// converted for the current output mode, so InitColors should be called before parsing them.
+func ParseColor(description string) (termbox.Attribute, error) {
+	var attr termbox.Attribute
+	var color string
+	for _, word := range strings.Fields(strings.ToLower(description)) {
+		if flag, ok := attributeNames[word]; ok {
+			attr |= flag
+			continue
+		}
+		if color != "" {
+			return 0, fmt.Errorf("%q names more than one colour", description)
+		}
+		color = word
+
+		if named, ok := colorNames[word]; ok {
+			attr |= named
+		} else if len(word) == 7 && word[0] == '#' {
+			rgb, err := strconv.ParseUint(word[1:], 16, 32)
+			if err != nil {
+				return 0, fmt.Errorf("%q is not a valid hexadecimal colour", word)
+			}
+			attr |= RGB(uint8(rgb>>16), uint8(rgb>>8), uint8(rgb))
+		} else {
+			return 0, fmt.Errorf("unknown colour or attribute %q", word)
+		}
+	}
+	return attr, nil
+}
+
+// nearest256 returns the index of the colour in a 256 colour palette which is closest to the given
+// colour, considering both the colour cube and the greyscale ramp.
+func nearest256(r, g, b int) int {
+	level := func(v int) int {
+		switch {
+		case v < 48:
+			return 0
+		case v < 115:
+			return 1
+		default:
+			return (v - 35) / 40
+		}
+	}
+	distance := func(r2, g2, b2 int) int {
+		return (r-r2)*(r-r2) + (g-g2)*(g-g2) + (b-b2)*(b-b2)
+	}
+
+	cr, cg, cb := level(r), level(g), level(b)
+	cube := 16 + 36*cr + 6*cg + cb
+	cubeDistance := distance(cubeLevels[cr], cubeLevels[cg], cubeLevels[cb])
+
+	grey := (r + g + b) / 3
+	greyIndex := 0
+	if grey > 238 {
+		greyIndex = 23
+	} else if grey > 8 {
+		greyIndex = (grey - 8) / 10
+	}
+	greyLevel := 8 + 10*greyIndex
+	if distance(greyLevel, greyLevel, greyLevel) < cubeDistance {
+		return 232 + greyIndex
+	}
+	return cube
+}
+
+// translate converts an attribute holding one of termbox's named colours into the equivalent
+// attribute for the current output mode. Only truecolour mode requires translation, as there the
+// named colours would otherwise be interpreted as (near) black.
+func translate(attr termbox.Attribute) termbox.Attribute {
+	if outputMode != termbox.OutputRGB {
+		return attr
+	}
+	const colorMask = 0x1FF
+	const flagMask = 0xFE00
+	color := attr & colorMask
+	if attr>>16 != 0 || color < termbox.ColorBlack || color > termbox.ColorLightGray {
+		return attr
+	}
+	rgb := namedColors[color-termbox.ColorBlack]
+	return termbox.RGBToAttribute(rgb[0], rgb[1], rgb[2]) | attr&flagMask
+}
+
+// setCell sets a cell of the termbox back buffer, translating its colours for the current output
+// mode.
+func setCell(x, y int, ch rune, fgColor, bgColor termbox.Attribute) {
+	termbox.SetCell(x, y, ch, translate(fgColor), translate(bgColor))
+}
@@ -0,0 +1,129 @@
+// Copyright 2019 Max Godfrey
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 		http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package textrenderer
+
+import (
+	"testing"
+
+	"github.com/nsf/termbox-go"
+)
+
+func TestNearest256(t *testing.T) {
+	tests := []struct {
+		r, g, b int
+		index   int
+	}{
+		{0, 0, 0, 16},
+		{255, 255, 255, 231},
+		{255, 0, 0, 196},
+		{0, 0, 255, 21},
+		{128, 128, 128, 244},
+		{95, 135, 175, 67},
+	}
+	for _, test := range tests {
+		if index := nearest256(test.r, test.g, test.b); index != test.index {
+			t.Errorf("nearest256(%d, %d, %d) = %d, want %d", test.r, test.g, test.b, index, test.index)
+		}
+	}
+}
+
+func TestRGB(t *testing.T) {
+	defer func(mode termbox.OutputMode) { outputMode = mode }(outputMode)
+
+	outputMode = termbox.OutputNormal
+	if attr := RGB(250, 10, 10); attr != termbox.ColorRed {
+		t.Errorf("RGB in normal mode = %d, want red", attr)
+	}
+	outputMode = termbox.Output256
+	if attr := RGB(255, 0, 0); attr != 197 {
+		t.Errorf("RGB in 256 colour mode = %d, want 197", attr)
+	}
+	outputMode = termbox.OutputRGB
+	if attr := RGB(1, 2, 3); attr != termbox.RGBToAttribute(1, 2, 3) {
+		t.Errorf("RGB in truecolour mode = %d, want %d", attr, termbox.RGBToAttribute(1, 2, 3))
+	}
+}
+
+func TestColor256(t *testing.T) {
+	defer func(mode termbox.OutputMode) { outputMode = mode }(outputMode)
+
+	outputMode = termbox.Output256
+	if got := Color256(9); got != termbox.ColorLightRed {
+		t.Errorf("Color256(9) = %v, want ColorLightRed", got)
+	}
+	if got := Color256(208); got != 209 {
+		t.Errorf("Color256(208) in 256 colour mode = %v, want 209", got)
+	}
+	outputMode = termbox.OutputRGB
+	if got, want := Color256(208), termbox.RGBToAttribute(255, 135, 0); got != want {
+		t.Errorf("Color256(208) in truecolour mode = %v, want %v", got, want)
+	}
+	if got, want := Color256(244), termbox.RGBToAttribute(128, 128, 128); got != want {
+		t.Errorf("Color256(244) in truecolour mode = %v, want %v", got, want)
+	}
+}
+
+func TestTranslate(t *testing.T) {
+	defer func(mode termbox.OutputMode) { outputMode = mode }(outputMode)
+
+	outputMode = termbox.OutputNormal
+	bold := termbox.ColorBlue | termbox.AttrBold
+	if attr := translate(bold); attr != bold {
+		t.Errorf("translate changed a colour outside of truecolour mode")
+	}
+
+	outputMode = termbox.OutputRGB
+	want := termbox.RGBToAttribute(0, 0, 238) | termbox.AttrBold
+	if attr := translate(termbox.ColorBlue | termbox.AttrBold); attr != want {
+		t.Errorf("translate(blue | bold) = %d, want %d", attr, want)
+	}
+	if attr := translate(termbox.ColorDefault); attr != termbox.ColorDefault {
+		t.Errorf("translate changed the default colour")
+	}
+	rgb := termbox.RGBToAttribute(1, 2, 3)
+	if attr := translate(rgb); attr != rgb {
+		t.Errorf("translate changed an RGB colour")
+	}
+}
+
+func TestParseColor(t *testing.T) {
+	defer func(mode termbox.OutputMode) { outputMode = mode }(outputMode)
+	outputMode = termbox.OutputRGB
+
+	tests := []struct {
+		description string
+		attr        termbox.Attribute
+	}{
+		{"blue", termbox.ColorBlue},
+		{"Bold  light-red", termbox.ColorLightRed | termbox.AttrBold},
+		{"underline", termbox.AttrUnderline},
+		{"#ff8800", termbox.RGBToAttribute(0xff, 0x88, 0x00)},
+		{"", termbox.ColorDefault},
+	}
+	for _, test := range tests {
+		attr, err := ParseColor(test.description)
+		if err != nil {
+			t.Errorf("ParseColor(%q) returned an error: %v", test.description, err)
+		} else if attr != test.attr {
+			t.Errorf("ParseColor(%q) = %d, want %d", test.description, attr, test.attr)
+		}
+	}
+
+	for _, description := range []string{"bleu", "red blue", "#12345g", "#1234"} {
+		if _, err := ParseColor(description); err == nil {
+			t.Errorf("ParseColor(%q) did not return an error", description)
+		}
+	}
+}
@@ -15,6 +15,9 @@
 package textrenderer
 
 import (
+	"strconv"
+	"strings"
+
 	"github.com/maxgodfrey2004/go-file-manager/explorer"
 	"github.com/nsf/termbox-go"
 )
@@ -22,6 +25,7 @@ import (
 // X positions to render various elements of the explorer.
 const (
 	CaretRenderX       = 1
+	MarkRenderX        = 2
 	FilePreviewRenderY = 2
 	FileRenderX        = 3
 )
@@ -44,24 +48,106 @@ func min(a, b int) int {
 	return b
 }
 
+// Theme holds the colours with which the textrenderer draws each element of the explorer.
+type Theme struct {
+	Directory    termbox.Attribute // The foreground colour of directories.
+	KeyFunctions termbox.Attribute // The foreground colour of KeyFunctions.
+	Error        termbox.Attribute // The background colour of errors, and lines reporting an error.
+	Warning      termbox.Attribute // The background colour of warnings.
+	Caret        termbox.Attribute // The foreground colour of the caret.
+	Header       termbox.Attribute // The foreground colour of the header.
+	PreviewBox   termbox.Attribute // The foreground colour of the box around the preview.
+}
+
+// DefaultTheme returns the theme with which the textrenderer draws when none has been configured.
+func DefaultTheme() Theme {
+	return Theme{
+		Directory:    termbox.ColorBlue,
+		KeyFunctions: termbox.ColorCyan,
+		Error:        termbox.ColorRed,
+		Warning:      termbox.ColorYellow,
+		Caret:        termbox.ColorDefault,
+		Header:       termbox.ColorDefault,
+		PreviewBox:   termbox.ColorDefault,
+	}
+}
+
+// Severity describes how serious a message shown to the user is.
+type Severity int
+
+const (
+	// Info is the severity of a message which merely informs the user.
+	Info Severity = iota
+
+	// Warning is the severity of a message describing something which did not go as expected,
+	// but is not an error.
+	Warning
+
+	// Error is the severity of a message describing an error.
+	Error
+)
+
+// Style holds the colours with which a line of text is drawn.
+type Style struct {
+	Fg termbox.Attribute
+	Bg termbox.Attribute
+}
+
+// Pane is a listing of a directory which is rendered beside the textrenderer's own Text: in place
+// of the preview when the textrenderer is in dual-pane mode, or in a column before Text when it
+// lists an ancestor of the directory.
+type Pane struct {
+	Header        string   // The string to render above Text, in dual-pane mode.
+	Text          []string // The entries of the directory.
+	Styles        []Style  // The style of each line of Text, overriding the theme unless it is zero.
+	SelectedIndex int      // The selected index in Text.
+	StartIndex    int      // Start rendering text from this index in Text.
+	Highlight     bool     // Whether the selected entry is highlighted, as the pane lacks a caret.
+	Guides        []string // The tree guide drawn before each line of Text, if it is a tree.
+	Marked        []bool   // Whether each line of Text has been marked by the user.
+}
+
 type textrenderer struct {
 	Header        string   // The string to render above Text.
 	KeyFunctions  []string // The function of each command, rendered at the bottom of the terminal.
+	Message       string   // A message for the user, rendered in place of KeyFunctions.
+	Severity      Severity // The severity of Message.
+	Prompt        string   // A line being typed by the user, rendered in place of KeyFunctions.
+	Dialog        []Line   // The lines of a dialog, rendered in a box over the middle of the screen.
+	PreviewOffset int      // The number of lines of the preview scrolled past.
+	Styles        []Style  // The style of each line of Text, overriding the theme unless it is zero.
+	Guides        []string // The tree guide drawn before each line of Text, if it is a tree.
+	Marked        []bool   // Whether each line of Text has been marked by the user.
+	Tabs          []string // The label of each tab, rendered beside Header if there is more than one.
+	CurrentTab    int      // The index in Tabs of the tab being displayed.
+	DualPane      bool     // Whether OtherPane is rendered in place of the preview.
+	OtherPane     Pane     // The pane which does not have focus, in dual-pane mode.
+	ActivePane    int      // The side on which Text is rendered in dual-pane mode: 0 is the left.
+	ColumnWidths  []int    // The relative widths of the columns, outside of dual-pane mode.
+	Parents       []Pane   // The ancestors of the directory, rendered in the columns before Text.
+	ScrollOff     int      // The number of lines kept between the caret and the edges of the view.
 	SelectedIndex int      // The selected index in Text.
 	StartIndex    int      // Start rendering text from this index in Text.
+	StartLeft     int      // Start rendering text from this point.
 	StopRight     int      // Stop rendering text past this point.
 	Text          []string // The text which the renderer draws on the screen.
+	Theme         Theme    // The colours with which the text is drawn.
+
+	columnEdges []int // The position of the edge after each column, as found by RecalculateBounds.
 }
 
 // CurrentSelected returns the element of the textrenderer's Text attribute which is currently
-// selected.
+// selected, or "" if there is no text, as in an empty directory at the root of a file system.
 func (t *textrenderer) CurrentSelected() string {
+	if t.SelectedIndex < 0 || t.SelectedIndex >= len(t.Text) {
+		return ""
+	}
 	return t.Text[t.SelectedIndex]
 }
 
 // Display reassigns the lines which the textrenderer will be displaying, and their respective
 // header. It then renders them on the terminal screen.
-func (t *textrenderer) Display(header string, text []string, preview []string) {
+func (t *textrenderer) Display(header string, text []string, preview []Line) {
 	t.Header = header
 	t.Text = text
 	t.SelectedIndex = 0
@@ -74,52 +160,214 @@ func (t *textrenderer) Display(header string, text []string, preview []string) {
 func (t *textrenderer) Init(header string, text []string) {
 	t.Header = header
 	t.Text = text
+	t.Styles = nil
+	t.Guides = nil
+	t.Marked = nil
 	t.SelectedIndex = 0
 	t.StartIndex = 0
 }
 
-// RecalculateBounds recalculates the positions on the terminal at which textrenderer stops
-// rendering text.
+// RecalculateBounds recalculates the positions on the terminal at which textrenderer starts and
+// stops rendering text. The screen is divided into columns whose widths are in proportion to
+// ColumnWidths: the preview is rendered in the last column, Text in the one before it, and Parents
+// in those before that. In dual-pane mode, or without at least two columns, the screen is instead
+// split in half.
 func (t *textrenderer) RecalculateBounds() {
 	width, _ := termbox.Size()
-	t.StopRight = width / 2
+	widths := t.ColumnWidths
+	if t.DualPane || len(widths) < 2 {
+		widths = []int{1, 1}
+	}
+	t.columnEdges = columnEdges(width, widths)
+	t.StopRight = t.columnEdges[len(t.columnEdges)-1]
+	t.StartLeft = 0
+	if len(t.columnEdges) > 1 {
+		t.StartLeft = t.columnEdges[len(t.columnEdges)-2] + 1
+	}
+}
+
+// columnEdges returns the position of the edge after each column but the last, when a screen of a
+// given width is divided into columns whose widths are in proportion to widths. Each edge lies on
+// a column of its own, between the columns which it separates.
+func columnEdges(width int, widths []int) []int {
+	total := 0
+	for _, w := range widths {
+		total += w
+	}
+	edges := make([]int, len(widths)-1)
+	sum := 0
+	for i := range edges {
+		sum += widths[i]
+		edges[i] = width * sum / total
+	}
+	return edges
 }
 
 // Render displays the selected window of text and respective header on the terminal screen. The
 // selected file will be displayed with a caret, indicative of its selection. A preview of the
-// current selected item will also be displayed on the right hand side of the screen.
-func (t *textrenderer) Render(preview []string) {
+// current selected item will also be displayed on the right hand side of the screen, or in
+// dual-pane mode, the other pane.
+func (t *textrenderer) Render(preview []Line) {
 	t.RecalculateBounds()
-	_, termHeight := termbox.Size()
-	if err := termbox.Clear(termbox.ColorDefault, termbox.ColorDefault); err != nil {
-		panic(err)
+	termbox.Clear(termbox.ColorDefault, termbox.ColorDefault)
+
+	active := Pane{
+		Header:        t.Header,
+		Text:          t.Text,
+		Styles:        t.Styles,
+		SelectedIndex: t.SelectedIndex,
+		StartIndex:    t.StartIndex,
+		Guides:        t.Guides,
+		Marked:        t.Marked,
+	}
+	if t.DualPane {
+		panes := [2]Pane{active, active}
+		panes[1-t.ActivePane] = t.OtherPane
+		for side, pane := range panes {
+			left, right := t.paneBounds(side)
+			drawString(left, 0, right-left, pane.Header, t.Theme.Header, termbox.ColorDefault)
+			t.renderPane(left, right, pane, side == t.ActivePane)
+		}
+		t.RenderSeparator()
+	} else {
+		drawString(0, 0, t.StopRight, t.Header, t.Theme.Header, termbox.ColorDefault)
+		t.renderParents()
+		t.renderPane(t.StartLeft, t.StopRight, active, true)
 	}
+	t.RenderTabs()
 
-	headerEnd := min(t.StopRight, len(t.Header))
-	for i := 0; i < headerEnd; i++ {
-		termbox.SetCell(i, 0, rune(t.Header[i]), termbox.ColorDefault, termbox.ColorDefault)
+	t.RenderPreview(preview)
+	if t.Dialog != nil {
+		t.RenderDialog()
 	}
+	switch {
+	case t.Prompt != "":
+		t.RenderPrompt()
+	case t.Message != "":
+		t.RenderMessage()
+	default:
+		t.RenderKeyFunctions()
+	}
+	t.placeCursor()
+	termbox.Flush()
+}
 
-	endIndex := min(t.StartIndex+termHeight-1-textHeightModifier, len(t.Text))
-	for i := t.StartIndex; i < endIndex; i++ {
+// renderPane renders the visible entries of a pane between two columns of the screen, along with
+// the caret if the pane has focus. Marked entries are drawn in bold beside a '*'.
+func (t *textrenderer) renderPane(left, right int, pane Pane, focused bool) {
+	_, textHeight := t.TextViewSize()
+	endIndex := min(pane.StartIndex+textHeight, len(pane.Text))
+	for i := pane.StartIndex; i < endIndex; i++ {
 		bgColor := termbox.ColorDefault
-		yCoord := i - t.StartIndex + 1
-		if i == t.SelectedIndex {
-			termbox.SetCell(CaretRenderX, yCoord, rune('>'), termbox.ColorDefault, termbox.ColorDefault)
+		yCoord := i - pane.StartIndex + 1
+		if i == pane.SelectedIndex && focused {
+			setCell(left+CaretRenderX, yCoord, rune('>'), t.Theme.Caret, termbox.ColorDefault)
 		}
 		fgColor := termbox.ColorDefault
-		if t.Text[i][len(t.Text[i])-1] == explorer.PathSepChar {
-			fgColor = termbox.ColorBlue
+		if i < len(pane.Styles) && pane.Styles[i] != (Style{}) {
+			fgColor, bgColor = pane.Styles[i].Fg, pane.Styles[i].Bg
+		} else if pane.Text[i][len(pane.Text[i])-1] == explorer.PathSepChar {
+			fgColor = t.Theme.Directory
+		}
+		if i == pane.SelectedIndex && pane.Highlight {
+			fgColor |= termbox.AttrReverse
 		}
-		for j := 0; j < len(t.Text[i]); j++ {
-			termbox.SetCell(FileRenderX+j, yCoord, rune(t.Text[i][j]), fgColor, bgColor)
+		if i < len(pane.Marked) && pane.Marked[i] {
+			setCell(left+MarkRenderX, yCoord, '*', t.Theme.Caret, termbox.ColorDefault)
+			fgColor |= termbox.AttrBold
 		}
+		x, name := left+FileRenderX, pane.Text[i]
+		if i < len(pane.Guides) {
+			x += drawString(x, yCoord, right-x, pane.Guides[i], t.Theme.PreviewBox, termbox.ColorDefault)
+			name = baseName(name)
+		}
+		drawString(x, yCoord, right-x, name, fgColor, bgColor)
 	}
+}
 
-	t.RenderKeyFunctions()
-	t.RenderPreview(preview)
-	termbox.HideCursor()
-	termbox.Flush()
+// baseName returns the last element of a path within a tree, keeping the separator which follows
+// the name of a directory.
+func baseName(path string) string {
+	trimmed := strings.TrimSuffix(path, explorer.PathSep)
+	if i := strings.LastIndex(trimmed, explorer.PathSep); i >= 0 {
+		return path[i+1:]
+	}
+	return path
+}
+
+// renderParents renders each of Parents in its own column before Text, so far as there are columns
+// for them. The parent of the directory is rendered in the column immediately before Text, and each
+// is scrolled so that its selected entry is visible.
+func (t *textrenderer) renderParents() {
+	_, textHeight := t.TextViewSize()
+	columns := len(t.columnEdges) - 1
+	for i := 0; i < columns && i < len(t.Parents); i++ {
+		column := columns - 1 - i
+		left := 0
+		if column > 0 {
+			left = t.columnEdges[column-1] + 1
+		}
+		pane := t.Parents[len(t.Parents)-1-i]
+		pane.StartIndex = scrollStart(pane.SelectedIndex, 0, len(pane.Text), textHeight, t.ScrollOff)
+		t.renderPane(left, t.columnEdges[column], pane, false)
+	}
+}
+
+// RenderSeparator renders the line which divides the two panes in dual-pane mode.
+func (t *textrenderer) RenderSeparator() {
+	_, textHeight := t.TextViewSize()
+	for y := 0; y <= textHeight; y++ {
+		setCell(t.StopRight, y, rune('│'), t.Theme.PreviewBox, termbox.ColorDefault)
+	}
+}
+
+// paneBounds returns the columns between which a pane on a side of the screen is rendered. The
+// left pane always ends at StopRight, and in dual-pane mode the right pane begins after it.
+func (t *textrenderer) paneBounds(side int) (int, int) {
+	if side == 0 {
+		return 0, t.StopRight
+	}
+	width, _ := termbox.Size()
+	return t.StopRight + 1, width
+}
+
+// PaneAt returns the side of the screen of the pane rendered at a column in dual-pane mode.
+func (t *textrenderer) PaneAt(x int) int {
+	if x > t.StopRight {
+		return 1
+	}
+	return 0
+}
+
+// RenderTabs renders the labels of the textrenderer's attribute Tabs on the right hand side of the
+// header row, with the current tab highlighted. Nothing is rendered unless there are multiple tabs.
+func (t *textrenderer) RenderTabs() {
+	if len(t.Tabs) < 2 {
+		return
+	}
+	width, _ := termbox.Size()
+	labels := make([]string, len(t.Tabs))
+	total := 0
+	for i, tab := range t.Tabs {
+		labels[i] = " " + strconv.Itoa(i+1) + ":" + tab + " "
+		total += StringWidth(labels[i])
+	}
+
+	// The tab bar is aligned to the right, but never begins before the middle of the screen.
+	x := width - total
+	if x <= t.StopRight {
+		x = t.StopRight + 1
+	}
+	for i, label := range labels {
+		fgColor, bgColor := t.Theme.Header, termbox.ColorDefault
+		if i == t.CurrentTab {
+			fgColor |= termbox.AttrReverse
+		}
+		x += drawString(x, 0, width-x, label, fgColor, bgColor)
+		if x >= width {
+			break
+		}
+	}
 }
 
 // RenderBox renders a box on the terminal whose upper left corner, width and height are specified.
@@ -128,58 +376,170 @@ func (t *textrenderer) RenderBox(topLeftX, topLeftY, width, height int) {
 		return
 	}
 
-	fgColor := termbox.ColorDefault
+	fgColor := t.Theme.PreviewBox
 	bgColor := termbox.ColorDefault
-	termbox.SetCell(topLeftX, topLeftY, rune('┌'), fgColor, bgColor)
-	termbox.SetCell(topLeftX+width, topLeftY, rune('┐'), fgColor, bgColor)
-	termbox.SetCell(topLeftX, topLeftY+height, rune('└'), fgColor, bgColor)
-	termbox.SetCell(topLeftX+width, topLeftY+height, rune('┘'), fgColor, bgColor)
+	setCell(topLeftX, topLeftY, rune('┌'), fgColor, bgColor)
+	setCell(topLeftX+width, topLeftY, rune('┐'), fgColor, bgColor)
+	setCell(topLeftX, topLeftY+height, rune('└'), fgColor, bgColor)
+	setCell(topLeftX+width, topLeftY+height, rune('┘'), fgColor, bgColor)
 
 	for x := 1; x < width; x++ {
-		termbox.SetCell(topLeftX+x, topLeftY, rune('─'), fgColor, bgColor)
-		termbox.SetCell(topLeftX+x, topLeftY+height, rune('─'), fgColor, bgColor)
+		setCell(topLeftX+x, topLeftY, rune('─'), fgColor, bgColor)
+		setCell(topLeftX+x, topLeftY+height, rune('─'), fgColor, bgColor)
 	}
 	for y := 1; y < height; y++ {
-		termbox.SetCell(topLeftX, topLeftY+y, rune('│'), fgColor, bgColor)
-		termbox.SetCell(topLeftX+width, topLeftY+y, rune('│'), fgColor, bgColor)
+		setCell(topLeftX, topLeftY+y, rune('│'), fgColor, bgColor)
+		setCell(topLeftX+width, topLeftY+y, rune('│'), fgColor, bgColor)
 	}
-	termbox.HideCursor()
+	t.placeCursor()
 	termbox.Flush()
 }
 
+// RenderDialog renders the textrenderer's attribute Dialog in a box over the middle of the screen,
+// clearing whatever lies beneath it. The box is as wide as the widest line of the dialog, as far as
+// it fits on the screen.
+func (t *textrenderer) RenderDialog() {
+	width, height := termbox.Size()
+	boxWidth := 0
+	for _, line := range t.Dialog {
+		if lineWidth := line.Width(); lineWidth > boxWidth {
+			boxWidth = lineWidth
+		}
+	}
+	boxWidth = min(boxWidth+3, width-1)
+	boxHeight := min(len(t.Dialog)+1, height-2)
+	left, top := (width-boxWidth)/2, (height-boxHeight)/2
+
+	for y := top; y <= top+boxHeight; y++ {
+		for x := left; x <= left+boxWidth; x++ {
+			setCell(x, y, ' ', termbox.ColorDefault, termbox.ColorDefault)
+		}
+	}
+	for i := 0; i < len(t.Dialog) && i < boxHeight-1; i++ {
+		drawLine(left+2, top+1+i, boxWidth-3, t.Dialog[i])
+	}
+	t.RenderBox(left, top, boxWidth, boxHeight)
+}
+
 // RenderKeyFunctions renders the textrenderer's attribute KeyFunctions on the bottom line of the
 // terminal screen.
 func (t *textrenderer) RenderKeyFunctions() {
-	width, height := termbox.Size()
-	x := 1
+	_, height := termbox.Size()
 	y := height - 1
-	renderedFirst := 0
 
-	fgColor := termbox.ColorCyan
+	fgColor := t.Theme.KeyFunctions
 	bgColor := termbox.ColorDefault
-	for _, token := range t.KeyFunctions {
-		if renderedFirst == 1 {
-			termbox.SetCell(x-2, y, rune(','), fgColor, bgColor)
+	for i, x := range t.keyFunctionPositions() {
+		if i > 0 {
+			setCell(x-2, y, rune(','), fgColor, bgColor)
 		}
-		if x+len(token)+renderedFirst < width {
-			for i := 0; i < len(token); i++ {
-				termbox.SetCell(x+i, y, rune(token[i]), fgColor, bgColor)
-			}
-			x += len(token)
-		} else {
+		drawString(x, y, StringWidth(t.KeyFunctions[i]), t.KeyFunctions[i], fgColor, bgColor)
+	}
+	t.placeCursor()
+	termbox.Flush()
+}
+
+// keyFunctionPositions returns the x position at which each of KeyFunctions is rendered, for as
+// many of them as fit on the screen.
+func (t *textrenderer) keyFunctionPositions() []int {
+	width, _ := termbox.Size()
+	var positions []int
+	x := 1
+	renderedFirst := 0
+	for _, token := range t.KeyFunctions {
+		tokenWidth := StringWidth(token)
+		if x+tokenWidth+renderedFirst >= width {
 			break
 		}
+		positions = append(positions, x)
 		renderedFirst = 1
-		x += 2
+		x += tokenWidth + 2
 	}
-	termbox.HideCursor()
+	return positions
+}
+
+// KeyFunctionAt returns the index in KeyFunctions of the key function rendered at a position on
+// the screen, or -1 if there is none.
+func (t *textrenderer) KeyFunctionAt(x, y int) int {
+	if _, height := termbox.Size(); y != height-1 || t.Prompt != "" || t.Message != "" {
+		return -1
+	}
+	for i, start := range t.keyFunctionPositions() {
+		if x >= start && x < start+StringWidth(t.KeyFunctions[i]) {
+			return i
+		}
+	}
+	return -1
+}
+
+// EntryAt returns the index in Text of the line rendered at a position on the screen, or -1 if
+// there is none.
+func (t *textrenderer) EntryAt(x, y int) int {
+	_, height := t.TextViewSize()
+	index := t.StartIndex + y - 1
+	left, right := t.StartLeft, t.StopRight
+	if t.DualPane {
+		left, right = t.paneBounds(t.ActivePane)
+	}
+	if x < left || x >= right || y < 1 || y > height || index >= len(t.Text) {
+		return -1
+	}
+	return index
+}
+
+// InPreview reports whether a position on the screen lies within the box in which the preview is
+// rendered.
+func (t *textrenderer) InPreview(x, y int) bool {
+	return !t.DualPane && x > t.StopRight && y >= FilePreviewRenderY-1 &&
+		y <= FilePreviewRenderY+t.PreviewHeight()
+}
+
+// RenderMessage renders the textrenderer's attribute Message on the bottom line of the terminal
+// screen, coloured according to its severity.
+func (t *textrenderer) RenderMessage() {
+	width, height := termbox.Size()
+	bgColor := termbox.ColorDefault
+	switch t.Severity {
+	case Warning:
+		bgColor = t.Theme.Warning
+	case Error:
+		bgColor = t.Theme.Error
+	}
+	for x := 0; x < width; x++ {
+		setCell(x, height-1, ' ', termbox.ColorDefault, termbox.ColorDefault)
+	}
+	drawString(0, height-1, width, t.Message, termbox.ColorDefault, bgColor)
+	t.placeCursor()
 	termbox.Flush()
 }
 
+// RenderPrompt renders the textrenderer's attribute Prompt on the bottom line of the terminal
+// screen, followed by the cursor.
+func (t *textrenderer) RenderPrompt() {
+	width, height := termbox.Size()
+	drawString(0, height-1, width, t.Prompt, termbox.ColorDefault, termbox.ColorDefault)
+	t.placeCursor()
+	termbox.Flush()
+}
+
+// placeCursor shows the cursor at the end of the prompt while the user is typing one, and hides it
+// otherwise.
+func (t *textrenderer) placeCursor() {
+	if t.Prompt == "" {
+		termbox.HideCursor()
+		return
+	}
+	width, height := termbox.Size()
+	termbox.SetCursor(min(StringWidth(t.Prompt), width-1), height-1)
+}
+
 // RenderPreview renders a preview of the current selected file (not a directory) on the right hand
-// half of the terminal screen.
-func (t *textrenderer) RenderPreview(preview []string) {
+// half of the terminal screen, unless the textrenderer is in dual-pane mode.
+func (t *textrenderer) RenderPreview(preview []Line) {
 	t.RecalculateBounds()
+	if t.DualPane {
+		return
+	}
 	previewX := t.StopRight + 2
 	width, _ := termbox.Size()
 	boxWidth := width - previewX - filePreviewWidthModifier
@@ -189,37 +549,89 @@ func (t *textrenderer) RenderPreview(preview []string) {
 	if preview == nil {
 		return
 	}
-	for i := 0; i < len(preview); i++ {
+	// The preview cannot be scrolled past its last line.
+	offset := min(t.PreviewOffset, len(preview)-t.PreviewHeight())
+	if offset > 0 {
+		preview = preview[offset:]
+	}
+	for i := 0; i < len(preview) && i < t.PreviewHeight(); i++ {
 		y := i + FilePreviewRenderY
-		fgColor := termbox.ColorDefault
-		bgColor := termbox.ColorDefault
-		if preview[i] == "PERMISSION DENIED" {
-			bgColor = termbox.ColorRed
-		}
-		for j := 0; j < min(boxWidth, len(preview[i])); j++ {
-			termbox.SetCell(previewX+j, y, rune(preview[i][j]), fgColor, bgColor)
+		line := preview[i]
+		if line.String() == "PERMISSION DENIED" {
+			line = Line{{Text: line.String(), Fg: termbox.ColorDefault, Bg: t.Theme.Error}}
 		}
+		drawLine(previewX, y, boxWidth-1, line)
 	}
 
-	termbox.HideCursor()
+	t.placeCursor()
 	termbox.Flush()
 }
 
-// PreviewSize returns the dimensions of the box in which the file preview is rendered.
+// PreviewHeight returns the number of lines which fit inside the box in which the file preview is
+// rendered.
 func (t *textrenderer) PreviewHeight() int {
 	_, height := termbox.Size()
 	return height - filePreviewHeightModifier - FilePreviewRenderY - 1
 }
 
-// TextViewSize returns the dimensions of the box in which textrenderer.Text is stored.
+// PreviewWidth returns the number of columns which fit inside the box in which the file preview is
+// rendered.
+func (t *textrenderer) PreviewWidth() int {
+	t.RecalculateBounds()
+	width, _ := termbox.Size()
+	return width - t.StopRight - 2 - filePreviewWidthModifier - 1
+}
+
+// TextViewSize returns the dimensions of the box in which textrenderer.Text is stored, which lies
+// between the header and the key functions.
 func (t *textrenderer) TextViewSize() (int, int) {
 	width, height := termbox.Size()
-	return width - textWidthModifier, height - textHeightModifier
+	return width - textWidthModifier, height - 1 - textHeightModifier
+}
+
+// Select moves the caret to an index in Text, which is limited to the indices of Text, and scrolls
+// the view so that the caret is visible and at least ScrollOff lines from its top and bottom edges.
+func (t *textrenderer) Select(index int) {
+	if index >= len(t.Text) {
+		index = len(t.Text) - 1
+	}
+	if index < 0 {
+		index = 0
+	}
+	_, height := t.TextViewSize()
+	t.SelectedIndex = index
+	t.StartIndex = scrollStart(index, t.StartIndex, len(t.Text), height, t.ScrollOff)
+}
+
+// scrollStart returns the index from which text of a given length is rendered in a view of a given
+// height, so that the caret at index is visible and at least scrollOff lines from the edges of the
+// view, unless the view is already at the beginning or end of the text. Scrolling is kept to a
+// minimum, so the view only moves from start when it must.
+func scrollStart(index, start, length, height, scrollOff int) int {
+	if height <= 0 {
+		return index
+	}
+	// The margin may be at most half of the view, or the caret would never be able to move.
+	margin := min(scrollOff, (height-1)/2)
+	if index-margin < start {
+		start = index - margin
+	}
+	if index+margin >= start+height {
+		start = index + margin - height + 1
+	}
+	if start > length-height {
+		start = length - height
+	}
+	if start < 0 {
+		start = 0
+	}
+	return start
 }
 
 // New returns a new instance of the textrenderer type.
 func New() (t textrenderer) {
 	t.SelectedIndex = 0
 	t.StartIndex = 0
+	t.Theme = DefaultTheme()
 	return
 }
@@ -15,8 +15,11 @@
 package textrenderer
 
 import (
+	"reflect"
 	"strconv"
 	"testing"
+
+	"github.com/maxgodfrey2004/go-file-manager/explorer"
 )
 
 func TestRender(t *testing.T) {
@@ -29,7 +32,7 @@ func TestRender(t *testing.T) {
 	tr.SelectedIndex = 2
 	tr.StartIndex = 1
 
-	tr.Render([]string{"no preview here..."})
+	tr.Render(PlainLines([]string{"no preview here..."}))
 }
 
 func TestNew(t *testing.T) {
@@ -38,3 +41,66 @@ func TestNew(t *testing.T) {
 	t.Log(tr.SelectedIndex)
 	t.Log(tr.StartIndex)
 }
+
+func TestScrollStart(t *testing.T) {
+	tests := []struct {
+		index, start, length, height, scrollOff int
+		want                                    int
+	}{
+		{index: 5, start: 0, length: 100, height: 20, scrollOff: 0, want: 0},
+		{index: 19, start: 0, length: 100, height: 20, scrollOff: 0, want: 0},
+		{index: 20, start: 0, length: 100, height: 20, scrollOff: 0, want: 1},
+		{index: 60, start: 0, length: 100, height: 20, scrollOff: 0, want: 41},
+		{index: 3, start: 41, length: 100, height: 20, scrollOff: 0, want: 3},
+		{index: 17, start: 0, length: 100, height: 20, scrollOff: 3, want: 1},
+		{index: 44, start: 41, length: 100, height: 20, scrollOff: 3, want: 41},
+		{index: 43, start: 41, length: 100, height: 20, scrollOff: 3, want: 40},
+		{index: 99, start: 0, length: 100, height: 20, scrollOff: 3, want: 80},
+		{index: 1, start: 50, length: 100, height: 20, scrollOff: 3, want: 0},
+		{index: 10, start: 5, length: 12, height: 20, scrollOff: 3, want: 0},
+		{index: 9, start: 0, length: 100, height: 10, scrollOff: 50, want: 4},
+		{index: 7, start: 0, length: 100, height: 0, scrollOff: 0, want: 7},
+	}
+	for _, test := range tests {
+		got := scrollStart(test.index, test.start, test.length, test.height, test.scrollOff)
+		if got != test.want {
+			t.Errorf("scrollStart(%d, %d, %d, %d, %d) = %d, want %d", test.index, test.start,
+				test.length, test.height, test.scrollOff, got, test.want)
+		}
+	}
+}
+
+func TestColumnEdges(t *testing.T) {
+	tests := []struct {
+		width  int
+		widths []int
+		want   []int
+	}{
+		{width: 100, widths: []int{1, 1}, want: []int{50}},
+		{width: 101, widths: []int{1, 1}, want: []int{50}},
+		{width: 80, widths: []int{1, 3, 4}, want: []int{10, 40}},
+		{width: 90, widths: []int{1, 1, 2, 2}, want: []int{15, 30, 60}},
+	}
+	for _, test := range tests {
+		got := columnEdges(test.width, test.widths)
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("columnEdges(%d, %v) = %v, want %v", test.width, test.widths, got, test.want)
+		}
+	}
+}
+
+func TestBaseName(t *testing.T) {
+	sep := explorer.PathSep
+	tests := map[string]string{
+		"file.txt":                     "file.txt",
+		"dir" + sep:                    "dir" + sep,
+		"dir" + sep + "file.txt":       "file.txt",
+		"dir" + sep + "sub" + sep:      "sub" + sep,
+		"a" + sep + "b" + sep + "c.go": "c.go",
+	}
+	for path, want := range tests {
+		if got := baseName(path); got != want {
+			t.Errorf("baseName(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+// Copyright 2019 Max Godfrey
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 		http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package textrenderer
+
+import (
+	"sort"
+
+	"github.com/nsf/termbox-go"
+)
+
+// themes maps the name of each built-in theme to a function returning it. The themes are built when
+// they are requested, as hexadecimal colours depend upon the output mode selected by InitColors.
+var themes = map[string]func() Theme{
+	"default": DefaultTheme,
+	"monochrome": func() Theme {
+		return Theme{
+			Directory:    termbox.ColorDefault | termbox.AttrBold,
+			KeyFunctions: termbox.ColorDefault | termbox.AttrUnderline,
+			Error:        termbox.ColorDefault | termbox.AttrReverse,
+			Warning:      termbox.ColorDefault | termbox.AttrReverse,
+			Caret:        termbox.ColorDefault | termbox.AttrBold,
+			Header:       termbox.ColorDefault | termbox.AttrBold,
+			PreviewBox:   termbox.ColorDefault,
+		}
+	},
+	"solarized": func() Theme {
+		return Theme{
+			Directory:    RGB(0x26, 0x8b, 0xd2),
+			KeyFunctions: RGB(0x2a, 0xa1, 0x98),
+			Error:        RGB(0xdc, 0x32, 0x2f),
+			Warning:      RGB(0xb5, 0x89, 0x00),
+			Caret:        RGB(0xb5, 0x89, 0x00) | termbox.AttrBold,
+			Header:       RGB(0x93, 0xa1, 0xa1) | termbox.AttrBold,
+			PreviewBox:   RGB(0x58, 0x6e, 0x75),
+		}
+	},
+	"gruvbox": func() Theme {
+		return Theme{
+			Directory:    RGB(0x83, 0xa5, 0x98),
+			KeyFunctions: RGB(0x8e, 0xc0, 0x7c),
+			Error:        RGB(0xcc, 0x24, 0x1d),
+			Warning:      RGB(0xd7, 0x99, 0x21),
+			Caret:        RGB(0xfe, 0x80, 0x19) | termbox.AttrBold,
+			Header:       RGB(0xfa, 0xbd, 0x2f) | termbox.AttrBold,
+			PreviewBox:   RGB(0x66, 0x5c, 0x54),
+		}
+	},
+}
+
+// NamedTheme returns the built-in theme with the given name, and whether there is one. InitColors
+// should be called first, so that the theme's colours suit the current output mode.
+func NamedTheme(name string) (Theme, bool) {
+	theme, ok := themes[name]
+	if !ok {
+		return Theme{}, false
+	}
+	return theme(), true
+}
+
+// ThemeNames returns the names of the built-in themes, in alphabetical order.
+func ThemeNames() []string {
+	var names []string
+	for name := range themes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
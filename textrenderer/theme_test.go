@@ -0,0 +1,33 @@
+// Copyright 2019 Max Godfrey
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 		http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package textrenderer
+
+import (
+	"testing"
+)
+
+func TestNamedTheme(t *testing.T) {
+	if theme, ok := NamedTheme("default"); !ok || theme != DefaultTheme() {
+		t.Errorf("NamedTheme(\"default\") = %+v, %t, want the default theme", theme, ok)
+	}
+	if _, ok := NamedTheme("neon"); ok {
+		t.Error("NamedTheme(\"neon\") reported a theme which does not exist")
+	}
+	for _, name := range ThemeNames() {
+		if _, ok := NamedTheme(name); !ok {
+			t.Errorf("ThemeNames() lists %q, which NamedTheme does not know", name)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+// Copyright 2019 Max Godfrey
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 		http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"os"
+	"strings"
+
+	"github.com/maxgodfrey2004/go-file-manager/explorer"
+)
+
+var (
+	// treeMode is whether directories may be expanded in place within the pane with focus, to list
+	// their contents beneath them. The tree mode of other panes and tabs is held by their pane.
+	treeMode = false
+
+	// expanded holds the path of each directory which has been expanded in tree mode within the
+	// pane with focus.
+	expanded = make(map[string]bool)
+)
+
+// listEntries returns the contents of the directory which nav, the explorer, is in, along with the
+// tree guide drawn before each of them and information about each of them, as listPane does.
+func listEntries() ([]string, []string, []os.FileInfo, error) {
+	return listPane(nav, listAll, treeMode, expanded)
+}
+
+// listPane returns the contents of the directory which an explorer is in, along with the tree
+// guide drawn before each of them and information about each of them. Outside of tree mode there
+// are no guides. In tree mode, the contents of each expanded directory follow it, named relative
+// to the explorer's directory.
+func listPane(e explorer.Explorer, listAll, treeMode bool, expanded map[string]bool) ([]string,
+	[]string, []os.FileInfo, error) {
+	if !treeMode {
+		dirContents, infos, err := e.ListInfo(listAll)
+		return dirContents, nil, infos, err
+	}
+	return e.ListTree(listAll, expanded)
+}
+
+// toggleTree switches between listing the current directory alone, and listing it as a tree in
+// which directories may be expanded.
+func toggleTree() {
+	treeMode = !treeMode
+	if err := reloadDirectory(); err != nil {
+		treeMode = !treeMode
+		report(err)
+	}
+}
+
+// toggleExpand expands the current selected directory in tree mode, listing its contents beneath
+// it, or collapses it if it is already expanded. If a file is selected, the directory containing
+// it is collapsed instead.
+func toggleExpand() {
+	if !treeMode {
+		return
+	}
+	selected := screen.CurrentSelected()
+	if selected == "" {
+		return
+	}
+	path := nav.EntryPath(selected)
+	if !explorer.IsExpandable(selected) || expanded[path] {
+		collapse(selected)
+		return
+	}
+
+	e := nav
+	e.Path = path
+	if _, err := e.List(listAll); err != nil {
+		report(err)
+		return
+	}
+	expanded[path] = true
+	if err := reloadDirectory(); err != nil {
+		report(err)
+	}
+}
+
+// collapse collapses an expanded directory, or otherwise the directory containing an entry,
+// selecting the directory which was collapsed.
+func collapse(name string) {
+	dir := strings.TrimSuffix(name, explorer.PathSep)
+	if !expanded[nav.EntryPath(dir)] {
+		i := strings.LastIndex(dir, explorer.PathSep)
+		if i < 0 {
+			return
+		}
+		dir = dir[:i]
+	}
+	delete(expanded, nav.EntryPath(dir))
+	if err := listDirectory(); err != nil {
+		report(err)
+		return
+	}
+	selectName(dir + explorer.PathSep)
+}
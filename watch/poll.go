@@ -0,0 +1,104 @@
+// Copyright 2019 Max Godfrey
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 		http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package watch
+
+import (
+	"os"
+	"sync"
+	"time"
+)
+
+// fileState is what is known of a watched file or directory when it was last polled.
+type fileState struct {
+	exists  bool
+	modTime time.Time
+	size    int64
+}
+
+// poller watches files and directories by polling their modification times. The modification time
+// of a directory changes whenever a file is created, removed or renamed within it.
+type poller struct {
+	mu       sync.Mutex
+	states   map[string]fileState
+	notify   func()
+	interval time.Duration
+	done     chan struct{}
+}
+
+// newPoller returns a poller which polls every interval once it is run, calling notify when
+// anything changes.
+func newPoller(notify func(), interval time.Duration) *poller {
+	return &poller{
+		states:   make(map[string]fileState),
+		notify:   notify,
+		interval: interval,
+		done:     make(chan struct{}),
+	}
+}
+
+// stat returns the state of a file or directory.
+func stat(path string) fileState {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fileState{}
+	}
+	return fileState{exists: true, modTime: info.ModTime(), size: info.Size()}
+}
+
+func (p *poller) watch(paths []string) error {
+	states := make(map[string]fileState, len(paths))
+	for _, path := range paths {
+		states[path] = stat(path)
+	}
+	p.mu.Lock()
+	p.states = states
+	p.mu.Unlock()
+	return nil
+}
+
+func (p *poller) close() error {
+	close(p.done)
+	return nil
+}
+
+// run polls every interval until the poller is closed.
+func (p *poller) run() {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			p.poll()
+		case <-p.done:
+			return
+		}
+	}
+}
+
+// poll checks each watched file and directory for changes since it was last polled.
+func (p *poller) poll() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	changed := false
+	for path, previous := range p.states {
+		if current := stat(path); current != previous {
+			p.states[path] = current
+			changed = true
+		}
+	}
+	if changed {
+		p.notify()
+	}
+}
@@ -0,0 +1,162 @@
+// Copyright 2019 Max Godfrey
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 		http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package watch reports changes to files and directories on disk, so that what is displayed of
+// them may be kept up to date. On Linux, changes are reported by inotify. Elsewhere, or if inotify
+// is unavailable, the files and directories are polled for changes.
+package watch
+
+import (
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+// pollInterval is how often files and directories are polled for changes when they cannot be
+// watched by the operating system.
+const pollInterval = time.Second
+
+// maxDelays is how many times the delay changes may wait to be reported while others keep
+// occurring, so that files which change constantly are still reported.
+const maxDelays = 10
+
+// backend is the means by which a Watcher learns of changes.
+type backend interface {
+	// run notices changes until the backend is closed. It is run in a goroutine of its own.
+	run()
+
+	// watch replaces the watched files and directories.
+	watch(paths []string) error
+
+	// close stops watching for changes.
+	close() error
+}
+
+// Watcher reports when any of a set of watched files and directories changes: when a file is
+// modified, or when a file or directory is created, removed or renamed within a watched directory.
+// Changes which occur in quick succession are reported once, when none have occurred for a delay,
+// or once the first of them has waited for maxDelays times the delay.
+type Watcher struct {
+	backend backend
+	delay   time.Duration
+	raw     chan struct{} // Receives each change as soon as it is noticed.
+	changes chan struct{} // Receives changes once they have settled.
+	panics  chan Panic    // Receives a panic which occurred in one of the goroutines.
+	done    chan struct{}
+	once    sync.Once
+}
+
+// Panic describes a panic which occurred in one of a Watcher's goroutines.
+type Panic struct {
+	Value interface{} // The value with which the goroutine panicked.
+	Stack []byte      // The stack trace of the goroutine when it panicked.
+}
+
+// New returns a Watcher which reports changes once none have occurred for delay. It watches
+// nothing until Watch is called.
+func New(delay time.Duration) (*Watcher, error) {
+	w := &Watcher{
+		delay:   delay,
+		raw:     make(chan struct{}, 1),
+		changes: make(chan struct{}, 1),
+		panics:  make(chan Panic, 1),
+		done:    make(chan struct{}),
+	}
+	b, err := newBackend(w.notify)
+	if err != nil {
+		return nil, err
+	}
+	w.backend = b
+	w.start(b.run)
+	w.start(w.debounce)
+	return w, nil
+}
+
+// start runs a function in a goroutine of its own. If the function panics, the panic is sent to
+// Panics rather than ending the program.
+func (w *Watcher) start(f func()) {
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				select {
+				case w.panics <- Panic{Value: r, Stack: debug.Stack()}:
+				default:
+				}
+			}
+		}()
+		f()
+	}()
+}
+
+// Watch replaces the files and directories which are watched. Paths which do not exist are ignored
+// rather than failing, as they may be created later, but changes to them are only certain to be
+// reported once they exist and have been passed to Watch again.
+func (w *Watcher) Watch(paths ...string) error {
+	return w.backend.watch(paths)
+}
+
+// Changes returns a channel which receives a value whenever the watched files and directories have
+// changed. Changes which have not been received are merged into one.
+func (w *Watcher) Changes() <-chan struct{} {
+	return w.changes
+}
+
+// Panics returns a channel which receives a panic which occurred while watching for changes, so
+// that the program may handle it. Changes may no longer be reported once there has been one.
+func (w *Watcher) Panics() <-chan Panic {
+	return w.panics
+}
+
+// Close stops watching for changes.
+func (w *Watcher) Close() error {
+	var err error
+	w.once.Do(func() {
+		close(w.done)
+		err = w.backend.close()
+	})
+	return err
+}
+
+// notify records that a change has been noticed by the backend.
+func (w *Watcher) notify() {
+	select {
+	case w.raw <- struct{}{}:
+	default:
+	}
+}
+
+// debounce reports changes noticed by the backend once none have been noticed for the delay, or
+// once maxDelays times the delay has passed since the first of them was noticed.
+func (w *Watcher) debounce() {
+	var settled, deadline <-chan time.Time
+	for {
+		select {
+		case <-w.raw:
+			settled = time.After(w.delay)
+			if deadline == nil {
+				deadline = time.After(maxDelays * w.delay)
+			}
+			continue
+		case <-settled:
+		case <-deadline:
+		case <-w.done:
+			return
+		}
+		settled, deadline = nil, nil
+		select {
+		case w.changes <- struct{}{}:
+		default:
+		}
+	}
+}
@@ -0,0 +1,102 @@
+// Copyright 2019 Max Godfrey
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 		http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package watch
+
+import (
+	"os"
+	"sync"
+	"syscall"
+	"unsafe"
+)
+
+// inotifyMask selects the events of which inotify informs a watcher.
+const inotifyMask = syscall.IN_CREATE | syscall.IN_DELETE | syscall.IN_MODIFY | syscall.IN_ATTRIB |
+	syscall.IN_MOVED_FROM | syscall.IN_MOVED_TO | syscall.IN_DELETE_SELF | syscall.IN_MOVE_SELF
+
+// inotifyEventSize is the size of an inotify event, excluding the name which may follow it.
+const inotifyEventSize = syscall.SizeofInotifyEvent
+
+// inotify watches files and directories with Linux's inotify.
+type inotify struct {
+	mu      sync.Mutex
+	fd      int
+	file    *os.File       // The inotify instance, read through the runtime's poller.
+	watches map[int32]bool // The watch descriptor of each watched file or directory.
+	notify  func()
+}
+
+// newBackend returns a backend which uses inotify, or which polls for changes if inotify is
+// unavailable, such as when the limit on inotify instances has been reached.
+func newBackend(notify func()) (backend, error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return newPoller(notify, pollInterval), nil
+	}
+	in := &inotify{
+		fd:      fd,
+		file:    os.NewFile(uintptr(fd), "inotify"),
+		watches: make(map[int32]bool),
+		notify:  notify,
+	}
+	return in, nil
+}
+
+func (in *inotify) watch(paths []string) error {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	for wd := range in.watches {
+		syscall.InotifyRmWatch(in.fd, uint32(wd))
+	}
+	in.watches = make(map[int32]bool)
+	for _, path := range paths {
+		wd, err := syscall.InotifyAddWatch(in.fd, path, inotifyMask)
+		if err == syscall.ENOENT || err == syscall.ENOTDIR {
+			continue
+		} else if err != nil {
+			return &os.PathError{Op: "watch", Path: path, Err: err}
+		}
+		in.watches[int32(wd)] = true
+	}
+	return nil
+}
+
+func (in *inotify) close() error {
+	return in.file.Close()
+}
+
+// run reads events from inotify until it is closed, notifying the watcher of any which concern a
+// file or directory that is still being watched.
+func (in *inotify) run() {
+	buf := make([]byte, 64*(inotifyEventSize+syscall.NAME_MAX+1))
+	for {
+		n, err := in.file.Read(buf)
+		if err != nil {
+			return
+		}
+		changed := false
+		in.mu.Lock()
+		for offset := 0; offset+inotifyEventSize <= n; {
+			event := (*syscall.InotifyEvent)(unsafe.Pointer(&buf[offset]))
+			if in.watches[event.Wd] && event.Mask&syscall.IN_IGNORED == 0 {
+				changed = true
+			}
+			offset += inotifyEventSize + int(event.Len)
+		}
+		in.mu.Unlock()
+		if changed {
+			in.notify()
+		}
+	}
+}
@@ -0,0 +1,163 @@
+// Copyright 2019 Max Godfrey
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 		http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package watch
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// timeout is the longest that a test waits for a change to be reported.
+const timeout = 5 * time.Second
+
+// tempDir creates a temporary directory, returning its path and a function which removes it.
+func tempDir(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "watch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir, func() { os.RemoveAll(dir) }
+}
+
+// expectChange fails the test unless a change is received from a channel within the timeout.
+func expectChange(t *testing.T, changes <-chan struct{}, what string) {
+	select {
+	case <-changes:
+	case <-time.After(timeout):
+		t.Fatalf("no change was reported after %s", what)
+	}
+}
+
+// expectNoChange fails the test if a change is received from a channel within a duration.
+func expectNoChange(t *testing.T, changes <-chan struct{}, d time.Duration, what string) {
+	select {
+	case <-changes:
+		t.Fatalf("a change was reported after %s", what)
+	case <-time.After(d):
+	}
+}
+
+func TestWatcher(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+	w, err := New(50 * time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	if err := w.Watch(dir, filepath.Join(dir, "missing")); err != nil {
+		t.Fatal(err)
+	}
+
+	// Changes in quick succession are reported once.
+	for _, name := range []string{"a", "b", "c"} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expectChange(t, w.Changes(), "creating files")
+	expectNoChange(t, w.Changes(), 300*time.Millisecond, "the changes settled")
+
+	if err := os.Remove(filepath.Join(dir, "a")); err != nil {
+		t.Fatal(err)
+	}
+	expectChange(t, w.Changes(), "removing a file")
+
+	// Directories which are no longer watched are not reported.
+	other, cleanupOther := tempDir(t)
+	defer cleanupOther()
+	if err := w.Watch(other); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "d"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	expectNoChange(t, w.Changes(), 300*time.Millisecond, "changing an unwatched directory")
+}
+
+func TestDebounceDeadline(t *testing.T) {
+	w := &Watcher{
+		delay:   50 * time.Millisecond,
+		raw:     make(chan struct{}, 1),
+		changes: make(chan struct{}, 1),
+		done:    make(chan struct{}),
+	}
+	go w.debounce()
+	defer close(w.done)
+
+	// Changes which never settle are still reported, once the first has waited long enough.
+	start := time.Now()
+	ticker := time.NewTicker(5 * time.Millisecond)
+	defer ticker.Stop()
+	expired := time.After(timeout)
+	for {
+		select {
+		case <-w.changes:
+			if elapsed := time.Since(start); elapsed < maxDelays*w.delay {
+				t.Errorf("changes were reported after %v, before they settled", elapsed)
+			}
+			return
+		case <-ticker.C:
+			w.notify()
+		case <-expired:
+			t.Fatal("changes which never settled were not reported")
+		}
+	}
+}
+
+func TestWatcherPanic(t *testing.T) {
+	w := &Watcher{panics: make(chan Panic, 1)}
+	w.start(func() { panic("failed") })
+	select {
+	case p := <-w.Panics():
+		if p.Value != "failed" || len(p.Stack) == 0 {
+			t.Errorf("Panics received %v with a stack of %d bytes", p.Value, len(p.Stack))
+		}
+	case <-time.After(timeout):
+		t.Fatal("a panic in a goroutine was not received")
+	}
+}
+
+func TestPoller(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+	changes := make(chan struct{}, 1)
+	p := newPoller(func() {
+		select {
+		case changes <- struct{}{}:
+		default:
+		}
+	}, 10*time.Millisecond)
+	go p.run()
+	defer p.close()
+
+	path := filepath.Join(dir, "file")
+	if err := p.watch([]string{path}); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(path, []byte("created"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	expectChange(t, changes, "creating a watched file")
+	expectNoChange(t, changes, 100*time.Millisecond, "nothing changed")
+
+	if err := ioutil.WriteFile(path, []byte("modified"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	expectChange(t, changes, "modifying a watched file")
+}